@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"strings"
+)
+
+// Assignment 是 "x = 3.5" 形式的赋值语句，或 "f(x,y) = x^2 + y" 形式的函数定义
+type Assignment struct {
+	Name   string   // 变量名或函数名
+	Params []string // 函数定义的参数名，变量赋值时为 nil
+	Value  string   // 等号右边的算式（函数定义时为函数体）
+	Start  int      // Value 在原算式中的起始位置（rune 偏移），用于换算出错位置
+}
+
+// IsFunction 判断是否为函数定义
+func (a *Assignment) IsFunction() bool {
+	return a.Params != nil
+}
+
+// Signature 返回函数定义的写法，如 f(x,y)；变量赋值时返回变量名
+func (a *Assignment) Signature() string {
+	if !a.IsFunction() {
+		return a.Name
+	}
+	return a.Name + "(" + strings.Join(a.Params, ",") + ")"
+}
+
+// ParseAssignment 判断算式是否为赋值语句 "名称 = 算式" 或函数定义 "名称(参数, …) = 算式"
+// 算式中没有等号时返回 nil, nil；等号左边不是合法的变量名或函数头时返回 *Error
+func ParseAssignment(expr string, opts Options) (*Assignment, error) {
+	runes := []rune(expr)
+	eq := strings.IndexRune(expr, '=')
+	if eq < 0 {
+		return nil, nil
+	}
+	eq = len([]rune(expr[:eq]))
+
+	tokens, err := lex(string(runes[:eq]))
+	if err != nil {
+		return nil, err
+	}
+	if tokens[0].kind != tokIdent {
+		return nil, errorAt(KindSyntax, Span{0, eq}, "等号左边必须是变量名")
+	}
+	name := tokens[0]
+	if opts.isConstant(name.text) {
+		return nil, errorAt(KindSyntax, Span{name.pos, name.end}, "不能给常量 %s 赋值", name.text)
+	}
+	a := &Assignment{Name: name.text, Value: string(runes[eq+1:]), Start: eq + 1}
+
+	fn, isFunc := opts.functions()[name.text]
+	switch {
+	case len(tokens) == 2:
+		if isFunc {
+			return nil, errorAt(KindSyntax, Span{name.pos, name.end}, "%s 是函数名，不能用作变量", name.text)
+		}
+	case tokens[1].kind == tokLParen:
+		if isFunc && fn.Body == "" {
+			return nil, errorAt(KindSyntax, Span{name.pos, name.end}, "%s 是内置函数，不能重新定义", name.text)
+		}
+		params, err := parseParams(tokens[1:], opts)
+		if err != nil {
+			return nil, err
+		}
+		a.Params = params
+	default:
+		return nil, errorAt(KindSyntax, Span{0, eq}, "等号左边必须是变量名")
+	}
+
+	if strings.TrimSpace(a.Value) == "" {
+		return nil, errorAt(KindSyntax, Span{eq, eq + 1}, "等号右边缺少算式")
+	}
+	return a, nil
+}
+
+// 解析函数头中的参数列表 "(x, y)"，tokens 从 "(" 开始
+func parseParams(tokens []token, opts Options) ([]string, error) {
+	params := []string{}
+	i := 1
+	if tokens[i].kind == tokRParen {
+		i++
+	} else {
+		for ; ; i += 2 {
+			tok := tokens[i]
+			if tok.kind != tokIdent {
+				return nil, errorAt(KindSyntax, Span{tok.pos, tok.end}, "参数必须是名称")
+			}
+			if opts.isConstant(tok.text) {
+				return nil, errorAt(KindSyntax, Span{tok.pos, tok.end}, "常量 %s 不能用作参数", tok.text)
+			}
+			for _, p := range params {
+				if p == tok.text {
+					return nil, errorAt(KindSyntax, Span{tok.pos, tok.end}, "参数 %s 重复", tok.text)
+				}
+			}
+			params = append(params, tok.text)
+
+			if sep := tokens[i+1]; sep.kind == tokRParen {
+				i += 2
+				break
+			} else if sep.kind != tokComma {
+				return nil, errorAt(KindUnbalanced, Span{sep.pos, sep.end}, "函数头缺少右括号")
+			}
+		}
+	}
+	if tok := tokens[i]; tok.kind != tokEOF {
+		return nil, errorAt(KindSyntax, Span{tok.pos, tok.end}, "此处不能出现 %s", tok.text)
+	}
+	return params, nil
+}
+
+// Shift 把在 Value 中的出错位置换算为在整个赋值语句中的位置
+func (a *Assignment) Shift(err *Error) *Error {
+	if err == nil || err.Span.Start == err.Span.End {
+		return err
+	}
+	shifted := *err
+	shifted.Span = Span{err.Span.Start + a.Start, err.Span.End + a.Start}
+	return &shifted
+}
@@ -0,0 +1,55 @@
+package engine
+
+// Span 是语法节点或错误在原始算式中的位置，单位为 rune，区间为 [Start, End)
+type Span struct {
+	Start int
+	End   int
+}
+
+// Node 是语法树中的一个节点
+type Node interface {
+	span() Span
+}
+
+func (s Span) span() Span { return s }
+
+// Number 是数字字面量
+type Number struct {
+	Span
+	Text  string  // 原始写法
+	Value float64 // 数值
+}
+
+// Ident 是常量或变量名，如 π、e
+type Ident struct {
+	Span
+	Name string
+}
+
+// Unary 是前缀运算：-x、+x
+type Unary struct {
+	Span
+	Op string
+	X  Node
+}
+
+// Binary 是二元运算：+ - × ÷ ^，隐式乘法（如 2π）也表示为 ×
+type Binary struct {
+	Span
+	Op   string
+	X, Y Node
+}
+
+// Postfix 是后缀运算：百分号 % 与阶乘 !
+type Postfix struct {
+	Span
+	Op string
+	X  Node
+}
+
+// Call 是函数调用，如 sin(30)、pow(2,3)
+type Call struct {
+	Span
+	Name string
+	Args []Node
+}
@@ -0,0 +1,203 @@
+package engine
+
+import (
+	"math/big"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 缓存最多保存的项数，超过时整体清空
+const maxCachedTerms = 4096
+
+// Cache 在连续多次求值之间复用已经算过的部分，适合实时预览：
+// 算式按顶层的加减号拆成若干项，每一项按原文缓存解析结果，只由数字、常量和内置函数组成的项还缓存计算结果。
+// 在长算式末尾输入时，只有最后一项需要重新解析和计算。
+// 变量或函数增删后应调用 Reset，因为已知的名称会影响连写名称的拆分。可以并发使用
+type Cache struct {
+	mu    sync.Mutex
+	terms map[string]*cachedTerm
+}
+
+// 一个加减项的解析结果，以及按求值参数缓存的计算结果
+type cachedTerm struct {
+	node    Node
+	pure    bool // 只由数字、常量和内置函数组成，结果只取决于求值参数
+	results map[resultKey]Result
+}
+
+// 影响一项计算结果的求值参数
+type resultKey struct {
+	angle AngleMode
+	exact bool
+}
+
+// NewCache 创建一个空的缓存
+func NewCache() *Cache {
+	return &Cache{terms: make(map[string]*cachedTerm)}
+}
+
+// Reset 清空缓存，在变量或函数增删后调用
+func (c *Cache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.terms = make(map[string]*cachedTerm)
+}
+
+// Evaluate 与包级的 Evaluate 结果相同，但复用之前算过的项
+// 出错时按整个算式重新求值，得到与 Evaluate 相同的错误和出错位置
+func (c *Cache) Evaluate(expr string, opts Options) (Result, error) {
+	if expr == "" || expr == "0" {
+		return Result{Value: 0, Text: "0"}, nil
+	}
+	if opts.Complex {
+		return Evaluate(expr, opts) // 复数模式的计算量不大，不做缓存
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.terms) > maxCachedTerms {
+		c.terms = make(map[string]*cachedTerm)
+	}
+
+	var sum float64
+	var exact *big.Rat
+	first := true
+	ok := eachTerm(expr, func(sign byte, text string) bool {
+		res, ok := c.term(text, opts)
+		if !ok {
+			return false
+		}
+		if first {
+			// 第一项直接作为初值，与逐个节点计算的结果完全一致（包括 -0）
+			first = false
+			sum = res.Value
+			if res.Exact != nil {
+				exact = new(big.Rat).Set(res.Exact)
+			}
+			return true
+		}
+		if sign == '-' {
+			sum -= res.Value
+			if exact != nil {
+				exact.Sub(exact, res.Exact)
+			}
+		} else {
+			sum += res.Value
+			if exact != nil {
+				exact.Add(exact, res.Exact)
+			}
+		}
+		return true
+	})
+	if !ok {
+		return Evaluate(expr, opts)
+	}
+
+	if exact != nil {
+		sum, _ = exact.Float64()
+	}
+	if checkFinite(sum, Span{}) != nil {
+		return Evaluate(expr, opts)
+	}
+	if exact != nil {
+		return Result{Value: sum, Text: FormatExact(exact, opts.places()), Exact: exact}, nil
+	}
+	return Result{Value: sum, Text: opts.Format(sum)}, nil
+}
+
+// 计算一项，出错时返回 false
+func (c *Cache) term(text string, opts Options) (Result, bool) {
+	t, found := c.terms[text]
+	if !found {
+		node, err := Parse(text, opts)
+		if err != nil {
+			return Result{}, false
+		}
+		t = &cachedTerm{node: node, pure: isPure(node, opts.functions())}
+		c.terms[text] = t
+	}
+
+	key := resultKey{angle: opts.Angle, exact: opts.Exact}
+	if res, hit := t.results[key]; hit {
+		return res, true
+	}
+	res, err := Eval(t.node, opts)
+	if err != nil {
+		return Result{}, false
+	}
+	if t.pure {
+		if t.results == nil {
+			t.results = make(map[resultKey]Result)
+		}
+		t.results[key] = res
+	}
+	return res, true
+}
+
+// 把算式按顶层（不在括号内）的二元加减号拆成若干项，依次调用 yield，sign 为项前的 '+' 或 '-'
+// 括号不匹配时返回 false；yield 返回 false 时停止并返回 false
+func eachTerm(expr string, yield func(sign byte, text string) bool) bool {
+	depth := 0
+	start := 0
+	sign := byte('+')
+	operand := false // 前一个有效字符是否结束了一个操作数，此时的 + - 是二元运算符
+	for i, r := range expr {
+		switch {
+		case unicode.IsSpace(r):
+			continue
+		case r == '(':
+			depth++
+			operand = false
+			continue
+		case r == ')':
+			if depth--; depth < 0 {
+				return false
+			}
+			operand = true
+			continue
+		}
+
+		op := opAliases[r]
+		if depth == 0 && operand && (op == "+" || op == "-") {
+			if !yield(sign, expr[start:i]) {
+				return false
+			}
+			sign = op[0]
+			start = i + utf8.RuneLen(r)
+			operand = false
+			continue
+		}
+		// 运算符和逗号之后是新的操作数，% 和 ! 是后缀运算符，之后仍是操作数的结尾
+		operand = r == '%' || r == '!' || (op == "" && r != ',')
+	}
+	return yield(sign, expr[start:])
+}
+
+// 判断语法树是否只由数字、常量和内置函数组成，这样的算式的值只取决于原文和求值参数
+func isPure(n Node, functions map[string]Function) bool {
+	switch n := n.(type) {
+	case *Number:
+		return true
+	case *Ident:
+		_, ok := constants[n.Name]
+		return ok
+	case *Unary:
+		return isPure(n.X, functions)
+	case *Postfix:
+		return isPure(n.X, functions)
+	case *Binary:
+		return isPure(n.X, functions) && isPure(n.Y, functions)
+	case *Call:
+		if fn, ok := functions[n.Name]; !ok || fn.Body != "" {
+			return false
+		}
+		for _, arg := range n.Args {
+			if !isPure(arg, functions) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,290 @@
+package engine
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// 复数模式下的虚数单位
+const imaginaryUnit = "i"
+
+// 判断名称是否为常量：π、e，复数模式下还有 i
+func (o Options) isConstant(name string) bool {
+	_, ok := constants[name]
+	return ok || (o.Complex && name == imaginaryUnit)
+}
+
+// 复数模式：所有数值按 complex128 计算，i 是虚数单位
+// 有复数实现的函数（见 complexFunctions）接受复数参数，其余函数只在参数都是实数时可用
+type complexEvaluator struct {
+	*evaluator
+}
+
+func (ev complexEvaluator) eval(n Node) (complex128, error) {
+	switch n := n.(type) {
+	case *Number:
+		return complex(n.Value, 0), nil
+
+	case *Ident:
+		if n.Name == imaginaryUnit {
+			return 1i, nil
+		}
+		if v, ok := constants[n.Name]; ok {
+			return complex(v, 0), nil
+		}
+		if ev.opts.complexArgs != nil {
+			if z, ok := ev.opts.complexArgs(n.Name); ok {
+				return z, nil
+			}
+		}
+		if v, ok := ev.opts.variable(n.Name); ok {
+			return complex(v, 0), nil
+		}
+		return 0, errorAt(KindUnknownName, n.Span, "未知的名称 %s", n.Name)
+
+	case *Unary:
+		x, err := ev.eval(n.X)
+		if err != nil {
+			return 0, err
+		}
+		if n.Op == "-" {
+			return 0 - x, nil // 不产生 -0 虚部，避免 sqrt(-1) 落到分支切割的另一侧
+		}
+		return x, nil
+
+	case *Postfix:
+		x, err := ev.eval(n.X)
+		if err != nil {
+			return 0, err
+		}
+		if n.Op == "%" {
+			return x * 0.01, nil
+		}
+		if imag(x) != 0 {
+			return 0, errorAt(KindDomain, n.Span, "阶乘只对非负整数有定义")
+		}
+		res, err := factorial(real(x))
+		if err != nil {
+			return 0, locate(err, n.Span)
+		}
+		return complex(res, 0), nil
+
+	case *Binary:
+		x, err := ev.eval(n.X)
+		if err != nil {
+			return 0, err
+		}
+		y, err := ev.eval(n.Y)
+		if err != nil {
+			return 0, err
+		}
+		switch n.Op {
+		case "+":
+			return x + y, nil
+		case "-":
+			return x - y, nil
+		case "×":
+			return x * y, nil
+		case "÷":
+			if y == 0 {
+				return 0, errorAt(KindDivByZero, n.Y.span(), "除数不能为零")
+			}
+			return x / y, nil
+		case "^":
+			res := complexPow(x, y)
+			return res, checkFiniteComplex(res, n.Span)
+		}
+		return 0, errorAt(KindSyntax, n.Span, "未知的运算符 %s", n.Op)
+
+	case *Call:
+		fn, ok := ev.functions[n.Name]
+		if !ok {
+			return 0, errorAt(KindUnknownName, Span{n.Start, n.Start + len([]rune(n.Name))}, "未知的函数 %s", n.Name)
+		}
+		if fn.Arity >= 0 && len(n.Args) != fn.Arity {
+			return 0, errorAt(KindArity, n.Span, "%s 需要 %d 个参数，实际为 %d 个", n.Name, fn.Arity, len(n.Args))
+		}
+		args := make([]complex128, len(n.Args))
+		allReal := true
+		for i, a := range n.Args {
+			z, err := ev.eval(a)
+			if err != nil {
+				return 0, err
+			}
+			args[i] = z
+			allReal = allReal && imag(z) == 0
+		}
+
+		var res complex128
+		var err error
+		switch {
+		case fn.CallComplex != nil:
+			res, err = fn.CallComplex(ev.opts, args)
+		case allReal:
+			floats := make([]float64, len(args))
+			for i, z := range args {
+				floats[i] = real(z)
+			}
+			var f float64
+			f, err = fn.Call(ev.opts, floats)
+			res = complex(f, 0)
+		default:
+			return 0, errorAt(KindDomain, n.Span, "%s 不支持复数参数", n.Name)
+		}
+		if err != nil {
+			return 0, locate(err, n.Span)
+		}
+		return res, checkFiniteComplex(res, n.Span)
+	}
+	return 0, errorAt(KindSyntax, n.span(), "无法计算的表达式")
+}
+
+// 复数的幂，实数的整数次幂直接用 math.Pow，避免 (-2)^2 这类结果带上微小的虚部；
+// 复数的较小整数次幂用连乘计算，使 i^2 恰好等于 -1
+func complexPow(x, y complex128) complex128 {
+	if imag(x) == 0 && imag(y) == 0 && (real(x) >= 0 || real(y) == math.Trunc(real(y))) {
+		return complex(math.Pow(real(x), real(y)), 0)
+	}
+	if n := real(y); imag(y) == 0 && n == math.Trunc(n) && math.Abs(n) <= 64 && x != 0 {
+		res := complex(1, 0)
+		for k := 0; k < int(math.Abs(n)); k++ {
+			res *= x
+		}
+		if n < 0 {
+			return 1 / res
+		}
+		return res
+	}
+	if x == 0 && real(y) > 0 {
+		return 0
+	}
+	return cmplx.Pow(x, y)
+}
+
+// 复数结果的实部或虚部不是有限值时报错
+func checkFiniteComplex(z complex128, span Span) error {
+	if err := checkFinite(real(z), span); err != nil {
+		return err
+	}
+	return checkFinite(imag(z), span)
+}
+
+// 角度模式下把复数参数转换为弧度
+func (o *Options) toRadiansComplex(z complex128) complex128 {
+	if o.Angle != Radian {
+		return z * complex(math.Pi/180, 0)
+	}
+	return z
+}
+
+// 角度模式下把反三角函数的复数结果转换为角度
+func (o *Options) fromRadiansComplex(z complex128) complex128 {
+	if o.Angle != Radian {
+		return z * complex(180/math.Pi, 0)
+	}
+	return z
+}
+
+// 复数的对数，0 没有对数
+func complexLog(z complex128, log func(complex128) complex128) (complex128, error) {
+	if z == 0 {
+		return 0, NewError(KindDomain, "0 没有对数")
+	}
+	return log(z), nil
+}
+
+// 有复数实现的内置函数
+var complexFunctions = map[string]func(o *Options, args []complex128) (complex128, error){
+	"sin": func(o *Options, args []complex128) (complex128, error) {
+		return cmplx.Sin(o.toRadiansComplex(args[0])), nil
+	},
+	"cos": func(o *Options, args []complex128) (complex128, error) {
+		return cmplx.Cos(o.toRadiansComplex(args[0])), nil
+	},
+	"tan": func(o *Options, args []complex128) (complex128, error) {
+		return cmplx.Tan(o.toRadiansComplex(args[0])), nil
+	},
+	"asin": func(o *Options, args []complex128) (complex128, error) {
+		return o.fromRadiansComplex(cmplx.Asin(args[0])), nil
+	},
+	"acos": func(o *Options, args []complex128) (complex128, error) {
+		return o.fromRadiansComplex(cmplx.Acos(args[0])), nil
+	},
+	"atan": func(o *Options, args []complex128) (complex128, error) {
+		if args[0] == 1i || args[0] == -1i {
+			return 0, NewError(KindDomain, "atan 在 ±i 处没有定义")
+		}
+		return o.fromRadiansComplex(cmplx.Atan(args[0])), nil
+	},
+	"sqrt": func(_ *Options, args []complex128) (complex128, error) {
+		return cmplx.Sqrt(args[0]), nil
+	},
+	"lg": func(_ *Options, args []complex128) (complex128, error) {
+		return complexLog(args[0], cmplx.Log10)
+	},
+	"ln": func(_ *Options, args []complex128) (complex128, error) {
+		return complexLog(args[0], cmplx.Log)
+	},
+	"exp": func(_ *Options, args []complex128) (complex128, error) {
+		return cmplx.Exp(args[0]), nil
+	},
+	"pow10": func(_ *Options, args []complex128) (complex128, error) {
+		return complexPow(10, args[0]), nil
+	},
+	"sqr": func(_ *Options, args []complex128) (complex128, error) {
+		return args[0] * args[0], nil
+	},
+	"pow": func(_ *Options, args []complex128) (complex128, error) {
+		return complexPow(args[0], args[1]), nil
+	},
+	"inv": func(_ *Options, args []complex128) (complex128, error) {
+		if args[0] == 0 {
+			return 0, NewError(KindDivByZero, "0 没有倒数")
+		}
+		return 1 / args[0], nil
+	},
+}
+
+// 复数的显示：直角坐标 a+bi，或极坐标 r∠θ（角度单位跟随角度模式）
+// 相对很小的实部或虚部视为舍入误差，显示为 0，如 e^(iπ) 显示为 -1
+func (o Options) FormatComplex(z complex128) string {
+	re, im := real(z), imag(z)
+	scale := max(math.Abs(re), math.Abs(im))
+	if math.Abs(re) < scale*1e-12 {
+		re = 0
+	}
+	if math.Abs(im) < scale*1e-12 {
+		im = 0
+	}
+
+	if o.Polar {
+		theta := math.Atan2(im, re)
+		unit := ""
+		if o.Angle != Radian {
+			theta *= 180 / math.Pi
+			unit = "°"
+		}
+		return o.Format(math.Hypot(re, im)) + "∠" + o.Format(theta) + unit
+	}
+
+	switch {
+	case im == 0:
+		return o.Format(re)
+	case re == 0:
+		return o.formatImag(im)
+	case im < 0:
+		return o.Format(re) + "-" + o.formatImag(-im)
+	}
+	return o.Format(re) + "+" + o.formatImag(im)
+}
+
+// 虚部的写法：1i 写作 i，-1i 写作 -i
+func (o Options) formatImag(im float64) string {
+	switch im {
+	case 1:
+		return imaginaryUnit
+	case -1:
+		return "-" + imaginaryUnit
+	}
+	return o.Format(im) + imaginaryUnit
+}
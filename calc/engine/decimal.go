@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"math/big"
+	"strings"
+)
+
+// 精确模式下整数幂的指数上限，超过时退回浮点计算，避免分子分母无限膨胀
+const maxExactExponent = 4096
+
+// 精确模式下阶乘的上限
+const maxExactFactorial = 1000
+
+// 精确模式：数字按十进制原样解析为有理数，+ - × ÷ % 以及整数次幂没有二进制舍入误差
+// 函数和非整数次幂仍然使用 float64 计算，再转换回有理数
+type ratEvaluator struct {
+	*evaluator
+}
+
+func (ev ratEvaluator) eval(n Node) (*big.Rat, error) {
+	switch n := n.(type) {
+	case *Number:
+		r, ok := new(big.Rat).SetString(n.Text)
+		if !ok {
+			return nil, errorAt(KindSyntax, n.Span, "无效的数字 %s", n.Text)
+		}
+		return r, nil
+
+	case *Unary:
+		x, err := ev.eval(n.X)
+		if err != nil {
+			return nil, err
+		}
+		if n.Op == "-" {
+			x.Neg(x)
+		}
+		return x, nil
+
+	case *Postfix:
+		x, err := ev.eval(n.X)
+		if err != nil {
+			return nil, err
+		}
+		if n.Op == "%" {
+			return x.Quo(x, big.NewRat(100, 1)), nil
+		}
+		if x.IsInt() && x.Sign() >= 0 && x.Num().IsInt64() && x.Num().Int64() <= maxExactFactorial {
+			f := new(big.Int).MulRange(1, x.Num().Int64())
+			return new(big.Rat).SetInt(f), nil
+		}
+		return ev.viaFloat(n)
+
+	case *Binary:
+		x, err := ev.eval(n.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := ev.eval(n.Y)
+		if err != nil {
+			return nil, err
+		}
+		switch n.Op {
+		case "+":
+			return x.Add(x, y), nil
+		case "-":
+			return x.Sub(x, y), nil
+		case "×":
+			return x.Mul(x, y), nil
+		case "÷":
+			if y.Sign() == 0 {
+				return nil, errorAt(KindDivByZero, n.Y.span(), "除数不能为零")
+			}
+			return x.Quo(x, y), nil
+		case "^":
+			if r, ok := ratPow(x, y); ok {
+				return r, nil
+			}
+			return ev.viaFloat(n)
+		}
+	}
+
+	// 常量、函数调用等交给浮点求值
+	return ev.viaFloat(n)
+}
+
+// 用浮点求值器计算节点，再转换为有理数
+func (ev ratEvaluator) viaFloat(n Node) (*big.Rat, error) {
+	f, err := ev.evaluator.eval(n)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFinite(f, n.span()); err != nil {
+		return nil, err
+	}
+	return new(big.Rat).SetFloat64(f), nil
+}
+
+// 整数次幂的精确计算，指数不是整数或过大时返回 false
+func ratPow(x, y *big.Rat) (*big.Rat, bool) {
+	if !y.IsInt() || !y.Num().IsInt64() {
+		return nil, false
+	}
+	exp := y.Num().Int64()
+	if exp > maxExactExponent || exp < -maxExactExponent {
+		return nil, false
+	}
+	if exp < 0 {
+		if x.Sign() == 0 {
+			return nil, false // 0 的负数次幂交给浮点计算报错
+		}
+		x = new(big.Rat).Inv(x)
+		exp = -exp
+	}
+	e := big.NewInt(exp)
+	num := new(big.Int).Exp(x.Num(), e, nil)
+	den := new(big.Int).Exp(x.Denom(), e, nil)
+	return new(big.Rat).SetFrac(num, den), true
+}
+
+// FormatExact 把有理数四舍五入到 places 位小数，并去掉多余的 0
+func FormatExact(r *big.Rat, places int) string {
+	s := r.FloatString(places)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(s, "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
@@ -0,0 +1,97 @@
+// Package engine 是计算器的求值引擎，不依赖 Fyne，可被 GUI、命令行或服务端直接复用
+package engine
+
+import (
+	"math/big"
+	"strconv"
+)
+
+// AngleMode 表示三角函数使用的角度单位
+type AngleMode int
+
+const (
+	Degree AngleMode = iota // 角度模式（默认）
+	Radian                  // 弧度模式
+)
+
+// Options 是一次求值使用的全部参数，零值即为默认配置
+type Options struct {
+	Angle     AngleMode           // 三角函数的角度单位
+	Precision int                 // 结果保留的有效数字位数，0 表示使用最短表示 (%g)
+	Functions map[string]Function // 可用的函数表，nil 时使用 DefaultFunctions()
+
+	// Variables 查找算式中的变量（如上一次的结果 ans），找不到时返回 false；nil 表示没有变量
+	Variables func(name string) (float64, bool)
+
+	Exact  bool // 精确小数模式：+ - × ÷ % 按十进制精确计算，不产生二进制舍入误差
+	Places int  // 精确模式下结果保留的小数位数，0 表示使用 DefaultPlaces
+
+	Complex bool // 复数模式：i 是虚数单位，函数接受和返回复数；优先于精确模式
+	Polar   bool // 复数模式下结果按极坐标 r∠θ 显示，否则按直角坐标 a+bi 显示
+
+	depth       int                                  // 当前自定义函数的嵌套调用层数
+	complexArgs func(name string) (complex128, bool) // 复数模式下自定义函数的参数
+}
+
+// DefaultPlaces 是精确模式下默认保留的小数位数
+const DefaultPlaces = 10
+
+// Result 是一次求值的结果
+type Result struct {
+	Value   float64    // 数值结果（精确模式下为近似值，复数模式下为实部）
+	Text    string     // 按 Precision 或 Places 格式化后的文本
+	Exact   *big.Rat   // 精确模式下的有理数结果，浮点模式下为 nil
+	Complex complex128 // 复数模式下的结果
+}
+
+// IsReal 判断结果是否为实数（复数模式下虚部为 0）
+func (r Result) IsReal() bool {
+	return imag(r.Complex) == 0
+}
+
+// Evaluate 计算一个使用显示符号（× ÷ % π e ^ 等）书写的算式
+// 失败时返回的错误均为 *Error，包含错误类别和出错位置
+func Evaluate(expr string, opts Options) (Result, error) {
+	if expr == "" || expr == "0" {
+		return Result{Value: 0, Text: "0"}, nil
+	}
+
+	node, err := Parse(expr, opts)
+	if err != nil {
+		return Result{}, err
+	}
+	return Eval(node, opts)
+}
+
+// 返回本次求值使用的函数表
+func (o Options) functions() map[string]Function {
+	if o.Functions == nil {
+		return builtins
+	}
+	return o.Functions
+}
+
+// Format 按 Precision 格式化数值，如果是整数则不带小数点
+func (o Options) Format(f float64) string {
+	prec := -1
+	if o.Precision > 0 {
+		prec = o.Precision
+	}
+	return strconv.FormatFloat(f, 'g', prec, 64)
+}
+
+// 查找变量
+func (o Options) variable(name string) (float64, bool) {
+	if o.Variables == nil {
+		return 0, false
+	}
+	return o.Variables(name)
+}
+
+// 精确模式下保留的小数位数
+func (o Options) places() int {
+	if o.Places > 0 {
+		return o.Places
+	}
+	return DefaultPlaces
+}
@@ -0,0 +1,421 @@
+package engine
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     Options
+		expected float64
+	}{
+		// --- 基础四则运算 ---
+		{"Addition", "1+1", Options{}, 2},
+		{"Multiplication", "2×3", Options{}, 6},
+		{"Division", "4÷2", Options{}, 2},
+		{"Percentage", "50%+50%", Options{}, 1},
+
+		// --- 角度模式 ---
+		{"Sin DEG", "sin(30)", Options{Angle: Degree}, 0.5},
+		{"Sin RAD", "sin(π÷6)", Options{Angle: Radian}, 0.5},
+		{"Atan DEG", "atan(1)", Options{Angle: Degree}, 45},
+
+		// --- 自动补全括号 ---
+		{"Unclosed Paren", "sqrt(9", Options{}, 3},
+
+		// --- 优先级与结合性 ---
+		{"Nested Paren Power", "(1+(2))^2", Options{}, 9},
+		{"Right Assoc Power", "2^3^2", Options{}, 512},
+		{"Negative Power", "-2^2", Options{}, -4},
+		{"Power Of Negative", "2^-1", Options{}, 0.5},
+		{"Percentage Priority", "5÷2%", Options{}, 0.025},
+		{"Factorial", "3!+1", Options{}, 7},
+
+		// --- 键盘符号 ---
+		{"Exp Next To E", "exp(1)-e", Options{}, 0},
+		{"Implicit Multiply", "2π÷π", Options{}, 2},
+		{"Split Names", "ee÷e", Options{}, math.E},
+		{"Reciprocal", "21/x(4)", Options{}, 0.5},
+		{"Auto Wrap Newline", "1+\n2", Options{}, 3},
+		{"ASCII Operators", "6*2/4", Options{}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Evaluate(tt.input, tt.opts)
+			if err != nil {
+				t.Fatalf("Input: %s, unexpected error: %v", tt.input, err)
+			}
+			if math.Abs(got.Value-tt.expected) > 1e-6 {
+				t.Errorf("Input: %s, Expected: %g, Got: %g", tt.input, tt.expected, got.Value)
+			}
+		})
+	}
+}
+
+func TestEvaluateOptions(t *testing.T) {
+	// 有效数字位数
+	got, err := Evaluate("1÷3", Options{Precision: 4})
+	if err != nil || got.Text != "0.3333" {
+		t.Errorf("Precision 4: got %q, %v", got.Text, err)
+	}
+
+	// 自定义函数表
+	funcs := map[string]Function{
+		"double": unary(func(x float64) float64 { return 2 * x }),
+	}
+	got, err = Evaluate("double(21)", Options{Functions: funcs})
+	if err != nil || got.Value != 42 {
+		t.Errorf("Custom function: got %v, %v", got.Value, err)
+	}
+	if _, err := Evaluate("sin(1)", Options{Functions: funcs}); err == nil {
+		t.Errorf("sin should be unavailable with a custom function table")
+	}
+
+	// 变量：可以和数字、常量连写，找不到时报未知名称
+	vars := func(name string) (float64, bool) {
+		v, ok := map[string]float64{"ans": 5, "ans2": 3}[name]
+		return v, ok
+	}
+	for expr, want := range map[string]float64{"ans+1": 6, "2ans": 10, "ans2ans": 15, "ans^2÷ans2": 25.0 / 3} {
+		got, err := Evaluate(expr, Options{Variables: vars})
+		if err != nil || math.Abs(got.Value-want) > 1e-12 {
+			t.Errorf("Variables %s: expected %v, got %v, %v", expr, want, got.Value, err)
+		}
+	}
+	if got, err := Evaluate("ans×0.1", Options{Variables: vars, Exact: true}); err != nil || got.Text != "0.5" {
+		t.Errorf("Variables in exact mode: got %q, %v", got.Text, err)
+	}
+	if _, err := Evaluate("ans3", Options{Variables: vars}); AsError(err) == nil || AsError(err).Kind != KindUnknownName {
+		t.Errorf("Unknown variable: got %v", err)
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{"Division by Zero", "1÷0", ErrMath},
+		{"Domain Error", "asin(2)", ErrMath},
+		{"Syntax", "1+)", ErrSyntax},
+		{"Trailing Operator", "1+", ErrSyntax},
+		{"Bad Number", "1.2.3", ErrSyntax},
+		{"Unknown Function", "foo(1)", ErrSyntax},
+		{"Arity", "pow(2)", ErrMath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Evaluate(tt.input, Options{})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Input: %s, Expected: %v, Got: %v", tt.input, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestErrorKindAndSpan(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		kind  ErrorKind
+		span  Span
+	}{
+		{"Division by Zero", "1+2÷0", KindDivByZero, Span{4, 5}},
+		{"Domain", "1+asin(2)", KindDomain, Span{2, 9}},
+		{"Sqrt Negative", "sqrt(-1)", KindDomain, Span{0, 8}},
+		{"Extra Paren", "(1+2))", KindUnbalanced, Span{5, 6}},
+		{"Missing Paren", "(1,2)", KindUnbalanced, Span{2, 3}},
+		{"Incomplete", "2×", KindSyntax, Span{1, 2}},
+		{"Unknown Function", "foo(1)", KindUnknownName, Span{0, 3}},
+		{"Arity", "pow(2)", KindArity, Span{0, 6}},
+		{"Overflow", "10^400", KindOverflow, Span{0, 6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Evaluate(tt.input, Options{})
+			e := AsError(err)
+			if e == nil || e.Kind != tt.kind || e.Span != tt.span {
+				t.Errorf("Input: %s, Expected: kind %d at %v, Got: %+v", tt.input, tt.kind, tt.span, e)
+			}
+		})
+	}
+}
+
+func TestExactMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		places   int
+		expected string
+	}{
+		{"No Binary Rounding", "0.1+0.2", 0, "0.3"},
+		{"Money Sum", "19.99×3-0.97", 0, "59"},
+		{"Large Integer", "2^53+1", 0, "9007199254740993"},
+		{"Percentage", "12.5%", 0, "0.125"},
+		{"Rounded Places", "2÷3", 2, "0.67"},
+		{"Negative Power", "2^-2", 0, "0.25"},
+		{"Factorial", "25!", 0, "15511210043330985984000000"},
+		{"Float Fallback", "sqrt(4)+0.1", 0, "2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Evaluate(tt.input, Options{Exact: true, Places: tt.places})
+			if err != nil {
+				t.Fatalf("Input: %s, unexpected error: %v", tt.input, err)
+			}
+			if got.Text != tt.expected || got.Exact == nil {
+				t.Errorf("Input: %s, Expected: %s, Got: %s", tt.input, tt.expected, got.Text)
+			}
+		})
+	}
+
+	if _, err := Evaluate("1÷(0.1-0.1)", Options{Exact: true}); err == nil || AsError(err).Kind != KindDivByZero {
+		t.Errorf("Exact division by zero: got %v", err)
+	}
+}
+
+func TestParseAssignment(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  *Assignment
+		span  Span // 出错时的位置，want 为 nil 且 span 为空表示不是赋值
+	}{
+		{"Not Assignment", "1+2", nil, Span{}},
+		{"Simple", "x=3.5", &Assignment{Name: "x", Value: "3.5", Start: 2}, Span{}},
+		{"Spaces", "rate = 2×3", &Assignment{Name: "rate", Value: " 2×3", Start: 6}, Span{}},
+		{"Constant", "π=3", nil, Span{0, 1}},
+		{"Function Name", "sin=1", nil, Span{0, 3}},
+		{"Not A Name", "2x=3", nil, Span{0, 2}},
+		{"Missing Value", "x=", nil, Span{1, 2}},
+		{"Function", "f(x, y)=x^2+y", &Assignment{Name: "f", Params: []string{"x", "y"}, Value: "x^2+y", Start: 8}, Span{}},
+		{"No Params", "g()=2", &Assignment{Name: "g", Params: []string{}, Value: "2", Start: 4}, Span{}},
+		{"Builtin", "sin(x)=x", nil, Span{0, 3}},
+		{"Bad Param", "f(2)=1", nil, Span{2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAssignment(tt.input, Options{})
+			if e := AsError(err); e != nil {
+				if e.Span != tt.span || tt.want != nil {
+					t.Errorf("Input: %s, unexpected error %+v", tt.input, e)
+				}
+				return
+			}
+			if tt.span != (Span{}) || (got == nil) != (tt.want == nil) || (got != nil && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("Input: %s, Expected: %+v, Got: %+v", tt.input, tt.want, got)
+			}
+		})
+	}
+
+	// 右边算式的出错位置换算到整个语句中
+	a, _ := ParseAssignment("y=1÷0", Options{})
+	_, err := Evaluate(a.Value, Options{})
+	if e := a.Shift(AsError(err)); e.Span != (Span{4, 5}) {
+		t.Errorf("Shift: got %v", e.Span)
+	}
+}
+
+func TestDefine(t *testing.T) {
+	functions := DefaultFunctions()
+	define := func(src string) error {
+		a, err := ParseAssignment(src, Options{Functions: functions})
+		if err != nil {
+			return err
+		}
+		fn, err := Define(a, Options{Functions: functions})
+		if err == nil {
+			functions[a.Name] = fn
+		}
+		return err
+	}
+	for _, src := range []string{"f(x,y)=x^2+y", "g(x)=2f(x,1)", "h()=g(k)", "area(r)=πr^2", "loop(x)=loop(x)+1"} {
+		if err := define(src); err != nil {
+			t.Fatalf("Define(%q): %v", src, err)
+		}
+	}
+
+	k := func(name string) (float64, bool) { return 3, name == "k" }
+	tests := []struct {
+		input string
+		want  float64
+		kind  ErrorKind // want 为 NaN 时期望的错误类别
+	}{
+		{"f(3,1)", 10, 0},
+		{"g(2)+1", 11, 0},
+		{"h()", 20, 0}, // 函数体中的变量在调用时查找
+		{"area(1)", math.Pi, 0},
+		{"sin(f(3,-9)×30)", 0, 0},
+		{"f(1)", math.NaN(), KindArity},
+		{"loop(1)", math.NaN(), KindRecursion},
+	}
+	for _, tt := range tests {
+		got, err := Evaluate(tt.input, Options{Functions: functions, Variables: k})
+		if math.IsNaN(tt.want) {
+			if e := AsError(err); e == nil || e.Kind != tt.kind {
+				t.Errorf("Input: %s, Expected error kind %d, Got: %v", tt.input, tt.kind, err)
+			}
+			continue
+		}
+		if err != nil || math.Abs(got.Value-tt.want) > 1e-12 {
+			t.Errorf("Input: %s, Expected: %v, Got: %v, %v", tt.input, tt.want, got.Value, err)
+		}
+	}
+
+	// 定义时的错误
+	for _, src := range []string{"sin(x)=x", "f(x,x)=x", "f(π)=1", "f(x=1", "f(x)=x+", "f(x)=pow(x)"} {
+		if err := define(src); err == nil {
+			t.Errorf("Define(%q) should fail", src)
+		}
+	}
+	// 参数个数错误标出调用位置
+	_, err := Evaluate("1+f(2)", Options{Functions: functions})
+	if e := AsError(err); e == nil || e.Span != (Span{2, 6}) || e.Msg != "f 需要 2 个参数，实际为 1 个" {
+		t.Errorf("Arity error: got %+v", e)
+	}
+}
+
+func TestCache(t *testing.T) {
+	c := NewCache()
+	x := 2.0
+	vars := func(name string) (float64, bool) { return x, name == "x" }
+
+	// 逐字输入算式，每一步的结果（包括错误）都应与不使用缓存时相同
+	exprs := []string{
+		"sin(30)+sqrt(2)×3^2-1÷(4+x)+ln(e^2)×2πx",
+		"-2^2-(-3)+2^-1-5%-3!+0.1+0.2",
+		"1-1÷0+(2+3",
+		"10^300×10^10-1",
+	}
+	for _, opts := range []Options{
+		{Angle: Degree, Variables: vars},
+		{Angle: Radian, Variables: vars},
+		{Exact: true, Places: 4, Variables: vars},
+	} {
+		for _, expr := range exprs {
+			runes := []rune(expr)
+			for i := 1; i <= len(runes); i++ {
+				prefix := string(runes[:i])
+				want, wantErr := Evaluate(prefix, opts)
+				got, err := c.Evaluate(prefix, opts)
+				if got.Text != want.Text || !reflect.DeepEqual(err, wantErr) {
+					t.Fatalf("Input: %s (%+v), Expected: %q %v, Got: %q %v", prefix, opts, want.Text, wantErr, got.Text, err)
+				}
+			}
+		}
+	}
+
+	// 变量的值改变后，含变量的部分重新计算
+	x = 3
+	if got, _ := c.Evaluate("1+2x", Options{Variables: vars}); got.Text != "7" {
+		t.Errorf("Variable change: got %s", got.Text)
+	}
+	// 新增名称后 Reset，连写的名称按新的名称拆分
+	if _, err := c.Evaluate("2xy", Options{Variables: vars}); err == nil {
+		t.Errorf("Unknown name xy should fail")
+	}
+	vars = func(name string) (float64, bool) { return 3, name == "x" || name == "y" }
+	c.Reset()
+	if got, err := c.Evaluate("2xy", Options{Variables: vars}); err != nil || got.Text != "18" {
+		t.Errorf("After Reset: got %s, %v", got.Text, err)
+	}
+}
+
+// 200 多个字符的算式，模拟实时预览中较长的输入
+var benchExpr = strings.Repeat("sin(30)+sqrt(2)×3^2-1÷(4+5)+ln(e^2)×2π+", 6) + "1"
+
+func BenchmarkEvaluate(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := Evaluate(benchExpr, Options{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// 每次按键的开销：在长算式末尾输入数字后重新计算
+func BenchmarkKeystroke(b *testing.B) {
+	b.Run("Evaluate", func(b *testing.B) {
+		b.ReportAllocs()
+		i := 0
+		for b.Loop() {
+			Evaluate(benchExpr+strconv.Itoa(i), Options{})
+			i++
+		}
+	})
+	b.Run("Cache", func(b *testing.B) {
+		c := NewCache()
+		b.ReportAllocs()
+		i := 0
+		for b.Loop() {
+			c.Evaluate(benchExpr+strconv.Itoa(i), Options{})
+			i++
+		}
+	})
+}
+
+func TestComplexMode(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  Options
+		want  string
+	}{
+		{"Sqrt Negative", "sqrt(-1)", Options{Complex: true}, "i"},
+		{"Rectangular", "(3+4i)×(1-2i)", Options{Complex: true}, "11-2i"},
+		{"Division", "(1+i)÷(1-i)", Options{Complex: true}, "i"},
+		{"Ln Negative", "ln(-1)", Options{Complex: true, Precision: 6}, "3.14159i"},
+		{"Euler", "e^(iπ)", Options{Complex: true}, "-1"},
+		{"Power", "i^2", Options{Complex: true}, "-1"},
+		{"Integer power", "(1+i)^4", Options{Complex: true}, "-4"},
+		{"Asin Outside Domain", "asin(2)", Options{Complex: true, Angle: Radian, Precision: 5}, "1.5708+1.317i"},
+		{"Real Stays Real", "sin(30)+2^10", Options{Complex: true}, "1024.5"},
+		{"Real Only Function", "fact(5)+i", Options{Complex: true}, "120+i"},
+		{"Polar Degrees", "3+4i", Options{Complex: true, Polar: true, Precision: 4}, "5∠53.13°"},
+		{"Polar Radians", "-2", Options{Complex: true, Polar: true, Angle: Radian, Precision: 4}, "2∠3.142"},
+		{"Implicit Multiplication", "2i+3πi", Options{Complex: true, Precision: 4}, "11.42i"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Evaluate(tt.input, tt.opts)
+			if err != nil || got.Text != tt.want {
+				t.Errorf("Input: %s, Expected: %s, Got: %s, %v", tt.input, tt.want, got.Text, err)
+			}
+		})
+	}
+
+	// 实数模式下 i 不是常量；复数模式下仍有的错误
+	if _, err := Evaluate("2i", Options{}); AsError(err) == nil || AsError(err).Kind != KindUnknownName {
+		t.Errorf("i in real mode: got %v", err)
+	}
+	for input, kind := range map[string]ErrorKind{"1÷(i-i)": KindDivByZero, "ln(0)": KindDomain, "i!": KindDomain, "fact(i)": KindDomain} {
+		if _, err := Evaluate(input, Options{Complex: true}); AsError(err) == nil || AsError(err).Kind != kind {
+			t.Errorf("Input: %s, Expected error kind %d, Got: %v", input, kind, err)
+		}
+	}
+
+	// 自定义函数接受复数参数
+	functions := DefaultFunctions()
+	a, _ := ParseAssignment("z(r,x)=r+xi", Options{Complex: true})
+	fn, err := Define(a, Options{Complex: true, Functions: functions})
+	if err != nil {
+		t.Fatal(err)
+	}
+	functions["z"] = fn
+	if got, err := Evaluate("z(1,2)×i", Options{Complex: true, Functions: functions}); err != nil || got.Text != "-2+i" {
+		t.Errorf("User function: got %s, %v", got.Text, err)
+	}
+}
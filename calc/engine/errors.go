@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrSyntax 表示算式无法解析（例如输入到一半的算式），可用 errors.Is 判断
+var ErrSyntax = errors.New("syntax error")
+
+// ErrMath 表示算式可以解析，但计算失败（除零、定义域错误等），可用 errors.Is 判断
+var ErrMath = errors.New("math error")
+
+// ErrorKind 是求值错误的类别
+type ErrorKind int
+
+const (
+	KindSyntax      ErrorKind = iota // 语法错误，如多余的运算符
+	KindUnbalanced                   // 括号不匹配
+	KindUnknownName                  // 未知的函数或常量
+	KindArity                        // 函数参数个数不对
+	KindDivByZero                    // 除数为零
+	KindDomain                       // 超出定义域，如 sqrt(-1)、asin(2)
+	KindOverflow                     // 结果溢出
+	KindRecursion                    // 自定义函数嵌套调用过深
+)
+
+// IsSyntax 判断错误是否属于"算式还没写完整"一类，实时预览时通常不提示这类错误
+func (k ErrorKind) IsSyntax() bool {
+	return k == KindSyntax || k == KindUnbalanced || k == KindUnknownName
+}
+
+// Error 是求值过程中产生的结构化错误
+type Error struct {
+	Kind ErrorKind // 错误类别
+	Msg  string    // 面向用户的说明
+	Span Span      // 出错部分在算式中的位置，Start == End 表示无法定位
+}
+
+// NewError 创建一个未定位的错误，自定义函数可以返回它，引擎会补上调用位置
+func NewError(kind ErrorKind, format string, args ...any) *Error {
+	return &Error{Kind: kind, Msg: fmt.Sprintf(format, args...)}
+}
+
+// 创建一个带位置的错误
+func errorAt(kind ErrorKind, span Span, format string, args ...any) *Error {
+	return &Error{Kind: kind, Msg: fmt.Sprintf(format, args...), Span: span}
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+// Is 让 errors.Is(err, ErrSyntax) 和 errors.Is(err, ErrMath) 按类别匹配
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrSyntax:
+		return e.Kind.IsSyntax()
+	case ErrMath:
+		return !e.Kind.IsSyntax()
+	}
+	return false
+}
+
+// AsError 把任意错误转换为 *Error，非引擎错误归为定义域错误
+func AsError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return &Error{Kind: KindDomain, Msg: err.Error()}
+}
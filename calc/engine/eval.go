@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"math"
+)
+
+// 内置常量
+var constants = map[string]float64{
+	"π": math.Pi,
+	"e": math.E,
+}
+
+// Eval 计算一棵已经解析好的语法树
+func Eval(n Node, opts Options) (Result, error) {
+	ev := &evaluator{opts: &opts, functions: opts.functions()}
+	if opts.Complex {
+		z, err := complexEvaluator{ev}.eval(n)
+		if err != nil {
+			return Result{}, err
+		}
+		if err := checkFiniteComplex(z, n.span()); err != nil {
+			return Result{}, err
+		}
+		return Result{Value: real(z), Text: opts.FormatComplex(z), Complex: z}, nil
+	}
+	if opts.Exact {
+		r, err := ratEvaluator{ev}.eval(n)
+		if err != nil {
+			return Result{}, err
+		}
+		f, _ := r.Float64()
+		if err := checkFinite(f, n.span()); err != nil {
+			return Result{}, err
+		}
+		return Result{Value: f, Text: FormatExact(r, opts.places()), Exact: r}, nil
+	}
+
+	f, err := ev.eval(n)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := checkFinite(f, n.span()); err != nil {
+		return Result{}, err
+	}
+	return Result{Value: f, Text: opts.Format(f)}, nil
+}
+
+type evaluator struct {
+	opts      *Options
+	functions map[string]Function
+}
+
+func (ev *evaluator) eval(n Node) (float64, error) {
+	switch n := n.(type) {
+	case *Number:
+		return n.Value, nil
+
+	case *Ident:
+		if v, ok := constants[n.Name]; ok {
+			return v, nil
+		}
+		if v, ok := ev.opts.variable(n.Name); ok {
+			return v, nil
+		}
+		return 0, errorAt(KindUnknownName, n.Span, "未知的名称 %s", n.Name)
+
+	case *Unary:
+		x, err := ev.eval(n.X)
+		if err != nil {
+			return 0, err
+		}
+		if n.Op == "-" {
+			return -x, nil
+		}
+		return x, nil
+
+	case *Postfix:
+		x, err := ev.eval(n.X)
+		if err != nil {
+			return 0, err
+		}
+		if n.Op == "%" {
+			return x * 0.01, nil
+		}
+		res, err := factorial(x)
+		if err != nil {
+			return 0, locate(err, n.Span)
+		}
+		return res, nil
+
+	case *Binary:
+		x, err := ev.eval(n.X)
+		if err != nil {
+			return 0, err
+		}
+		y, err := ev.eval(n.Y)
+		if err != nil {
+			return 0, err
+		}
+		switch n.Op {
+		case "+":
+			return x + y, nil
+		case "-":
+			return x - y, nil
+		case "×":
+			return x * y, nil
+		case "÷":
+			if y == 0 {
+				return 0, errorAt(KindDivByZero, n.Y.span(), "除数不能为零")
+			}
+			return x / y, nil
+		case "^":
+			res := math.Pow(x, y)
+			return res, checkFinite(res, n.Span)
+		}
+		return 0, errorAt(KindSyntax, n.Span, "未知的运算符 %s", n.Op)
+
+	case *Call:
+		fn, ok := ev.functions[n.Name]
+		if !ok {
+			return 0, errorAt(KindUnknownName, Span{n.Start, n.Start + len([]rune(n.Name))}, "未知的函数 %s", n.Name)
+		}
+		if fn.Arity >= 0 && len(n.Args) != fn.Arity {
+			return 0, errorAt(KindArity, n.Span, "%s 需要 %d 个参数，实际为 %d 个", n.Name, fn.Arity, len(n.Args))
+		}
+		args := make([]float64, len(n.Args))
+		for i, a := range n.Args {
+			v, err := ev.eval(a)
+			if err != nil {
+				return 0, err
+			}
+			args[i] = v
+		}
+		res, err := fn.Call(ev.opts, args)
+		if err != nil {
+			return 0, locate(err, n.Span)
+		}
+		return res, checkFinite(res, n.Span)
+	}
+	return 0, errorAt(KindSyntax, n.span(), "无法计算的表达式")
+}
+
+// 非有限的中间结果：NaN 视为超出定义域，无穷大视为溢出
+func checkFinite(f float64, span Span) error {
+	switch {
+	case math.IsNaN(f):
+		return errorAt(KindDomain, span, "超出定义域")
+	case math.IsInf(f, 0):
+		return errorAt(KindOverflow, span, "结果溢出")
+	}
+	return nil
+}
+
+// 给函数返回的错误补上位置信息
+func locate(err error, span Span) *Error {
+	e := *AsError(err)
+	if e.Span == (Span{}) {
+		e.Span = span
+	}
+	return &e
+}
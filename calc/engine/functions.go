@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"maps"
+	"math"
+)
+
+// Function 描述一个可在算式中调用的函数
+type Function struct {
+	Arity int                                                  // 参数个数，-1 表示不限
+	Call  func(opts *Options, args []float64) (float64, error) // 函数实现，opts 提供角度模式等上下文
+
+	// 复数模式下的实现，nil 表示只接受实数参数（参数都是实数时调用 Call）
+	CallComplex func(opts *Options, args []complex128) (complex128, error)
+
+	Params []string // 自定义函数的参数名，内置函数为 nil
+	Body   string   // 自定义函数的函数体，内置函数为空
+}
+
+// 角度模式下把输入转换为弧度
+func (o *Options) toRadians(val float64) float64 {
+	if o.Angle != Radian { // 如果不是弧度模式，进行转换
+		return val * math.Pi / 180
+	}
+	return val
+}
+
+// 角度模式下把反三角函数的结果转换为角度
+func (o *Options) fromRadians(val float64) float64 {
+	if o.Angle != Radian {
+		return val * 180 / math.Pi
+	}
+	return val
+}
+
+// 只有一个参数、与角度模式无关的函数
+func unary(f func(float64) float64) Function {
+	return Function{Arity: 1, Call: func(_ *Options, args []float64) (float64, error) {
+		return f(args[0]), nil
+	}}
+}
+
+// 内置函数表只在启动时创建一次，Options.Functions 为 nil 时直接使用，不会被修改
+var builtins = newBuiltins()
+
+// DefaultFunctions 返回计算器键盘上所有函数的实现
+// 返回的是内置函数表的副本，调用方可以在上面增加自定义函数
+func DefaultFunctions() map[string]Function {
+	return maps.Clone(builtins)
+}
+
+func newBuiltins() map[string]Function {
+	fns := map[string]Function{
+		"sin": {Arity: 1, Call: func(o *Options, args []float64) (float64, error) {
+			return math.Sin(o.toRadians(args[0])), nil
+		}},
+		"cos": {Arity: 1, Call: func(o *Options, args []float64) (float64, error) {
+			return math.Cos(o.toRadians(args[0])), nil
+		}},
+		"tan": {Arity: 1, Call: func(o *Options, args []float64) (float64, error) {
+			return math.Tan(o.toRadians(args[0])), nil
+		}},
+		"asin": {Arity: 1, Call: func(o *Options, args []float64) (float64, error) {
+			if args[0] < -1 || args[0] > 1 {
+				return 0, NewError(KindDomain, "asin 的参数必须在 -1 到 1 之间")
+			}
+			return o.fromRadians(math.Asin(args[0])), nil
+		}},
+		"acos": {Arity: 1, Call: func(o *Options, args []float64) (float64, error) {
+			if args[0] < -1 || args[0] > 1 {
+				return 0, NewError(KindDomain, "acos 的参数必须在 -1 到 1 之间")
+			}
+			return o.fromRadians(math.Acos(args[0])), nil
+		}},
+		"atan": {Arity: 1, Call: func(o *Options, args []float64) (float64, error) {
+			return o.fromRadians(math.Atan(args[0])), nil
+		}},
+		"sqrt": {Arity: 1, Call: func(_ *Options, args []float64) (float64, error) {
+			if args[0] < 0 {
+				return 0, NewError(KindDomain, "不能对负数开平方")
+			}
+			return math.Sqrt(args[0]), nil
+		}},
+		"lg": {Arity: 1, Call: func(_ *Options, args []float64) (float64, error) {
+			if args[0] <= 0 {
+				return 0, NewError(KindDomain, "对数的参数必须大于 0")
+			}
+			return math.Log10(args[0]), nil
+		}},
+		"ln": {Arity: 1, Call: func(_ *Options, args []float64) (float64, error) {
+			if args[0] <= 0 {
+				return 0, NewError(KindDomain, "对数的参数必须大于 0")
+			}
+			return math.Log(args[0]), nil
+		}},
+		"exp":   unary(math.Exp),
+		"pow10": unary(func(x float64) float64 { return math.Pow(10, x) }),
+		"sqr":   unary(func(x float64) float64 { return x * x }),
+		"pow": {Arity: 2, Call: func(_ *Options, args []float64) (float64, error) {
+			return math.Pow(args[0], args[1]), nil
+		}},
+		"fact": {Arity: 1, Call: func(_ *Options, args []float64) (float64, error) {
+			return factorial(args[0])
+		}},
+		"inv": {Arity: 1, Call: func(_ *Options, args []float64) (float64, error) {
+			if args[0] == 0 {
+				return 0, NewError(KindDivByZero, "0 没有倒数")
+			}
+			return 1.0 / args[0], nil
+		}},
+	}
+	for name, call := range complexFunctions {
+		fn := fns[name]
+		fn.CallComplex = call
+		fns[name] = fn
+	}
+	return fns
+}
+
+// 阶乘，仅对非负整数有定义
+func factorial(n float64) (float64, error) {
+	if n < 0 || n != math.Trunc(n) {
+		return 0, NewError(KindDomain, "阶乘只对非负整数有定义")
+	}
+	res := 1.0
+	for i := 2.0; i <= n && !math.IsInf(res, 0); i++ {
+		res *= i
+	}
+	return res, nil
+}
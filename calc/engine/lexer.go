@@ -0,0 +1,183 @@
+package engine
+
+import (
+	"unicode"
+)
+
+// 词法单元的类型
+type tokenKind int
+
+const (
+	tokEOF    tokenKind = iota
+	tokNumber           // 数字，如 3.14
+	tokIdent            // 名称：函数名、常量或变量
+	tokOp               // 运算符：+ - × ÷ ^ % !
+	tokLParen           // (
+	tokRParen           // )
+	tokComma            // , 函数参数分隔符
+)
+
+// token 是一个词法单元，pos/end 是它在原始算式中的 rune 偏移 [pos, end)
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+	end  int
+}
+
+// 键盘或粘贴中可能出现的运算符写法，统一规范为显示符号
+var opAliases = map[rune]string{
+	'+': "+", '-': "-", '−': "-",
+	'×': "×", '*': "×",
+	'÷': "÷", '/': "÷",
+	'^': "^", '%': "%", '!': "!",
+}
+
+// 倒数键在显示中写作 "1/x("，词法分析时把它识别为函数 inv
+const invSymbol = "1/x("
+
+// lex 把算式拆分为词法单元，空白和自动换行插入的 \n 会被忽略
+func lex(expr string) ([]token, error) {
+	runes := []rune(expr)
+	// 每个 rune 的字节偏移，名称和数字的文字直接截取自 expr，不再分配新的字符串
+	offsets := make([]int, 0, len(runes)+1)
+	for i := range expr {
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(expr))
+	tokens := make([]token, 0, len(runes)+1) // 每个词法单元至少一个字符，一次分配足够的空间
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case isInvAt(runes, i):
+			// "1/x(" -> inv (
+			tokens = append(tokens,
+				token{kind: tokIdent, text: "inv", pos: i, end: i + 3},
+				token{kind: tokLParen, text: "(", pos: i + 3, end: i + 4})
+			i += 4
+
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			dots := 0
+			// 数字后紧跟倒数键时（如 "21/x("），在 "1/x(" 之前截断
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') && !isInvAt(runes, i) {
+				if runes[i] == '.' {
+					dots++
+				}
+				i++
+			}
+			text := expr[offsets[start]:offsets[i]]
+			if dots > 1 || text == "." {
+				return nil, errorAt(KindSyntax, Span{start, i}, "无效的数字 %s", text)
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: text, pos: start, end: i})
+
+		case r == 'π':
+			// π 总是独立的常量，即使紧挨着字母（如 2πr）
+			tokens = append(tokens, token{kind: tokIdent, text: "π", pos: i, end: i + 1})
+			i++
+
+		case isIdentStart(r):
+			start := i
+			for i < len(runes) && (isIdentStart(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: expr[offsets[start]:offsets[i]], pos: start, end: i})
+
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i, end: i + 1})
+			i++
+
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i, end: i + 1})
+			i++
+
+		case r == ',':
+			tokens = append(tokens, token{kind: tokComma, text: ",", pos: i, end: i + 1})
+			i++
+
+		default:
+			op, ok := opAliases[r]
+			if !ok {
+				return nil, errorAt(KindSyntax, Span{i, i + 1}, "无法识别的字符 %c", r)
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i, end: i + 1})
+			i++
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokEOF, pos: len(runes), end: len(runes)})
+	return tokens, nil
+}
+
+// 判断第 i 个字符起是否为倒数键的 "1/x("
+func isInvAt(runes []rune, i int) bool {
+	if len(runes)-i < len(invRunes) {
+		return false
+	}
+	for j, r := range invRunes {
+		if runes[i+j] != r {
+			return false
+		}
+	}
+	return true
+}
+
+var invRunes = []rune(invSymbol)
+
+// 名称的首字符：字母或下划线（π 单独处理）
+func isIdentStart(r rune) bool {
+	return r == '_' || (unicode.IsLetter(r) && r != 'π')
+}
+
+// splitNames 把未知的名称拆成若干已知名称，例如键盘连续输入的 "ee" 或 "esin"
+// 按最长前缀贪心匹配，无法完全拆分时原样返回
+func splitNames(tokens []token, known func(string) bool) []token {
+	var out []token // 没有需要拆分的名称时直接返回 tokens，不复制
+	for i, tok := range tokens {
+		var parts []string
+		split := false
+		if tok.kind == tokIdent && !known(tok.text) {
+			parts, split = splitName([]rune(tok.text), known)
+		}
+		if !split {
+			if out != nil {
+				out = append(out, tok)
+			}
+			continue
+		}
+		if out == nil {
+			out = append(make([]token, 0, len(tokens)+len(parts)), tokens[:i]...)
+		}
+		pos := tok.pos
+		for _, p := range parts {
+			n := len([]rune(p))
+			out = append(out, token{kind: tokIdent, text: p, pos: pos, end: pos + n})
+			pos += n
+		}
+	}
+	if out == nil {
+		return tokens
+	}
+	return out
+}
+
+func splitName(name []rune, known func(string) bool) ([]string, bool) {
+	if len(name) == 0 {
+		return nil, true
+	}
+	for n := len(name); n > 0; n-- {
+		head := string(name[:n])
+		if !known(head) {
+			continue
+		}
+		if rest, ok := splitName(name[n:], known); ok {
+			return append([]string{head}, rest...), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,223 @@
+package engine
+
+import (
+	"strconv"
+)
+
+// 运算符的绑定强度（Pratt 解析），数值越大结合越紧
+const (
+	bpNone    = 0
+	bpAdd     = 10 // + -
+	bpMul     = 20 // × ÷ %，以及隐式乘法
+	bpPrefix  = 30 // 前缀负号：-2^2 = -(2^2)
+	bpPow     = 40 // ^，右结合：2^3^2 = 2^(3^2)
+	bpPostfix = 50 // 阶乘 !
+)
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+// Parse 把算式解析为语法树
+// 算式末尾未闭合的括号会被自动补全；opts 用于识别函数名、常量和变量，以便拆分 "ee"、"esin(" 这类连写
+func Parse(expr string, opts Options) (Node, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	functions := opts.functions()
+	tokens = splitNames(tokens, func(name string) bool {
+		_, isFunc := functions[name]
+		_, isVar := opts.variable(name)
+		return opts.isConstant(name) || isFunc || isVar
+	})
+
+	p := &parser{tokens: tokens}
+	node, err := p.parseExpr(bpNone)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, unexpected(tok)
+	}
+	return node, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.i]
+	if tok.kind != tokEOF {
+		p.i++
+	}
+	return tok
+}
+
+// 解析一个表达式，直到遇到绑定强度不大于 rbp 的运算符
+func (p *parser) parseExpr(rbp int) (Node, error) {
+	left, err := p.nud()
+	if err != nil {
+		return nil, err
+	}
+	for p.lbp(p.peek()) > rbp {
+		if left, err = p.led(left); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+// 运算符在左侧已有操作数时的绑定强度
+func (p *parser) lbp(tok token) int {
+	switch tok.kind {
+	case tokOp:
+		switch tok.text {
+		case "+", "-":
+			return bpAdd
+		case "×", "÷", "%":
+			return bpMul
+		case "^":
+			return bpPow
+		case "!":
+			return bpPostfix
+		}
+	case tokNumber, tokIdent, tokLParen:
+		// 操作数后面紧跟操作数，视为隐式乘法，如 2π、3(4+5)
+		return bpMul
+	}
+	return bpNone
+}
+
+// 前缀位置：数字、名称、函数调用、括号和前缀正负号
+func (p *parser) nud() (Node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, errorAt(KindSyntax, Span{tok.pos, tok.end}, "无效的数字 %s", tok.text)
+		}
+		return &Number{Span: Span{tok.pos, tok.end}, Text: tok.text, Value: v}, nil
+
+	case tokIdent:
+		if p.peek().kind == tokLParen {
+			return p.call(tok)
+		}
+		return &Ident{Span: Span{tok.pos, tok.end}, Name: tok.text}, nil
+
+	case tokLParen:
+		inner, err := p.parseExpr(bpNone)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.closeParen(); err != nil {
+			return nil, err
+		}
+		return inner, nil
+
+	case tokOp:
+		if tok.text == "-" || tok.text == "+" {
+			x, err := p.parseExpr(bpPrefix)
+			if err != nil {
+				return nil, err
+			}
+			return &Unary{Span: Span{tok.pos, x.span().End}, Op: tok.text, X: x}, nil
+		}
+	}
+
+	if tok.kind == tokEOF {
+		// 算式以运算符结尾，标出最后一个运算符
+		span := Span{tok.pos, tok.end}
+		if p.i > 0 {
+			prev := p.tokens[p.i-1]
+			span = Span{prev.pos, prev.end}
+		}
+		return nil, errorAt(KindSyntax, span, "算式不完整")
+	}
+	return nil, unexpected(tok)
+}
+
+// 出现在错误位置的词法单元
+func unexpected(tok token) *Error {
+	span := Span{tok.pos, tok.end}
+	if tok.kind == tokRParen {
+		return errorAt(KindUnbalanced, span, "多余的右括号")
+	}
+	return errorAt(KindSyntax, span, "此处不能出现 %s", tok.text)
+}
+
+// 中缀或后缀位置：左侧已经有一个操作数
+func (p *parser) led(left Node) (Node, error) {
+	tok := p.peek()
+	start := left.span().Start
+
+	if tok.kind != tokOp {
+		// 隐式乘法，不消耗词法单元
+		right, err := p.parseExpr(bpMul)
+		if err != nil {
+			return nil, err
+		}
+		return &Binary{Span: Span{start, right.span().End}, Op: "×", X: left, Y: right}, nil
+	}
+
+	p.next()
+	switch tok.text {
+	case "%", "!":
+		return &Postfix{Span: Span{start, tok.end}, Op: tok.text, X: left}, nil
+	case "^":
+		right, err := p.parseExpr(bpPow - 1) // 右结合
+		if err != nil {
+			return nil, err
+		}
+		return &Binary{Span: Span{start, right.span().End}, Op: "^", X: left, Y: right}, nil
+	default:
+		right, err := p.parseExpr(p.lbp(tok))
+		if err != nil {
+			return nil, err
+		}
+		return &Binary{Span: Span{start, right.span().End}, Op: tok.text, X: left, Y: right}, nil
+	}
+}
+
+// 解析函数调用的参数列表，name 为函数名词法单元，当前位于 "(" 之前
+func (p *parser) call(name token) (Node, error) {
+	p.next() // (
+	node := &Call{Span: Span{name.pos, name.end}, Name: name.text}
+
+	if p.peek().kind == tokRParen {
+		node.End = p.next().end
+		return node, nil
+	}
+	for {
+		arg, err := p.parseExpr(bpNone)
+		if err != nil {
+			return nil, err
+		}
+		node.Args = append(node.Args, arg)
+		node.End = arg.span().End
+		if p.peek().kind != tokComma {
+			break
+		}
+		p.next()
+	}
+	if end := p.peek().end; p.peek().kind == tokRParen {
+		node.End = end
+	}
+	return node, p.closeParen()
+}
+
+// 消耗一个 ")"，算式结尾处缺失的右括号视为自动补全
+func (p *parser) closeParen() error {
+	tok := p.peek()
+	switch tok.kind {
+	case tokRParen:
+		p.next()
+		return nil
+	case tokEOF:
+		return nil
+	}
+	return errorAt(KindUnbalanced, Span{tok.pos, tok.end}, "缺少右括号")
+}
@@ -0,0 +1,121 @@
+package engine
+
+// MaxCallDepth 是自定义函数嵌套调用的最大层数，超过时报错（防止递归定义无限调用）
+const MaxCallDepth = 32
+
+// Define 把函数定义（如 f(x,y) = x^2 + y）编译为可以放入函数表的 Function
+// 函数体只在定义时解析一次；opts 中的函数表用于检查函数体中调用的参数个数。
+// 函数体中的其他名称在调用时才查找，因此可以引用之后才定义的变量或函数
+func Define(a *Assignment, opts Options) (Function, error) {
+	if !a.IsFunction() {
+		return Function{}, NewError(KindSyntax, "%s 不是函数定义", a.Name)
+	}
+	params := a.Params
+	fn := Function{Arity: len(params), Params: params, Body: a.Value}
+
+	// 解析时把参数当作已知的变量，把函数自身加入函数表，以便拆分连写的名称和检查递归调用
+	functions := make(map[string]Function, len(opts.functions())+1)
+	for name, f := range opts.functions() {
+		functions[name] = f
+	}
+	functions[a.Name] = fn
+	parseOpts := opts
+	parseOpts.Functions = functions
+	parseOpts.Variables = func(name string) (float64, bool) {
+		for _, p := range params {
+			if p == name {
+				return 0, true
+			}
+		}
+		return opts.variable(name)
+	}
+	body, err := Parse(a.Value, parseOpts)
+	if err != nil {
+		return Function{}, a.Shift(AsError(err))
+	}
+	if err := checkArity(body, functions); err != nil {
+		return Function{}, a.Shift(err)
+	}
+
+	name := a.Name
+	fn.Call = func(o *Options, args []float64) (float64, error) {
+		if o.depth >= MaxCallDepth {
+			return 0, NewError(KindRecursion, "%s 的调用层数超过 %d 层，请检查是否递归调用", name, MaxCallDepth)
+		}
+		inner := *o
+		inner.depth++
+		inner.Exact = false // 函数的返回值是 float64，函数体按浮点计算
+		outer := o.Variables
+		inner.Variables = func(n string) (float64, bool) {
+			for i, p := range params {
+				if p == n {
+					return args[i], true
+				}
+			}
+			if outer == nil {
+				return 0, false
+			}
+			return outer(n)
+		}
+		res, err := Eval(body, inner)
+		if err != nil {
+			// 函数体中的出错位置对调用处没有意义，改为标出整个调用
+			e := *AsError(err)
+			e.Span = Span{}
+			return 0, &e
+		}
+		return res.Value, nil
+	}
+	fn.CallComplex = func(o *Options, args []complex128) (complex128, error) {
+		if o.depth >= MaxCallDepth {
+			return 0, NewError(KindRecursion, "%s 的调用层数超过 %d 层，请检查是否递归调用", name, MaxCallDepth)
+		}
+		inner := *o
+		inner.depth++
+		outer := o.complexArgs
+		inner.complexArgs = func(n string) (complex128, bool) {
+			for i, p := range params {
+				if p == n {
+					return args[i], true
+				}
+			}
+			if outer == nil {
+				return 0, false
+			}
+			return outer(n)
+		}
+		z, err := complexEvaluator{&evaluator{opts: &inner, functions: inner.functions()}}.eval(body)
+		if err != nil {
+			e := *AsError(err)
+			e.Span = Span{}
+			return 0, &e
+		}
+		return z, nil
+	}
+	return fn, nil
+}
+
+// 检查语法树中对已知函数的调用，参数个数不对时返回错误
+func checkArity(n Node, functions map[string]Function) *Error {
+	switch n := n.(type) {
+	case *Unary:
+		return checkArity(n.X, functions)
+	case *Postfix:
+		return checkArity(n.X, functions)
+	case *Binary:
+		if err := checkArity(n.X, functions); err != nil {
+			return err
+		}
+		return checkArity(n.Y, functions)
+	case *Call:
+		if fn, ok := functions[n.Name]; ok && fn.Arity >= 0 && len(n.Args) != fn.Arity {
+			return errorAt(KindArity, n.Span, "%s 需要 %d 个参数，实际为 %d 个", n.Name, fn.Arity, len(n.Args))
+		}
+		for _, arg := range n.Args {
+			if err := checkArity(arg, functions); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package history
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Filter 是全部历史窗口中的搜索条件，零值匹配所有记录
+type Filter struct {
+	Text     string    // 算式或结果中包含的文字，不区分大小写
+	Min, Max *float64  // 结果的数值范围（含），nil 表示不限；结果不是数值的记录不匹配
+	From, To time.Time // 日期范围（含，按本地日期比较），零值表示不限
+}
+
+// IsZero 判断是否没有任何条件
+func (f Filter) IsZero() bool {
+	return strings.TrimSpace(f.Text) == "" && f.Min == nil && f.Max == nil && f.From.IsZero() && f.To.IsZero()
+}
+
+// Match 判断记录是否满足全部条件
+func (f Filter) Match(r Record) bool {
+	if q := normalizeQuery(f.Text); q != "" {
+		if len(Highlight(r.Expression, q)) == 0 && len(Highlight(r.Result, q)) == 0 {
+			return false
+		}
+	}
+
+	if f.Min != nil || f.Max != nil {
+		v, ok := r.Value()
+		if !ok {
+			return false
+		}
+		if (f.Min != nil && v < *f.Min) || (f.Max != nil && v > *f.Max) {
+			return false
+		}
+	}
+
+	day := r.Date()
+	if !f.From.IsZero() && day < f.From.Format("2006-01-02") {
+		return false
+	}
+	if !f.To.IsZero() && day > f.To.Format("2006-01-02") {
+		return false
+	}
+	return true
+}
+
+// Apply 返回满足条件的记录，顺序不变
+func (f Filter) Apply(records []Record) []Record {
+	if f.IsZero() {
+		return records
+	}
+	var out []Record
+	for _, r := range records {
+		if f.Match(r) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// 搜索文字按键盘上的写法输入时，换成算式中使用的符号
+func normalizeQuery(q string) string {
+	return strings.NewReplacer("*", "×", "/", "÷").Replace(strings.TrimSpace(q))
+}
+
+// Highlight 返回 query 在 text 中每次出现的位置 [起, 止)，以 rune 为单位，不区分大小写
+func Highlight(text, query string) [][2]int {
+	q := []rune(strings.ToLower(normalizeQuery(query)))
+	if len(q) == 0 {
+		return nil
+	}
+	t := []rune(text)
+	for i, r := range t {
+		t[i] = unicode.ToLower(r)
+	}
+
+	var spans [][2]int
+	for i := 0; i+len(q) <= len(t); {
+		if string(t[i:i+len(q)]) == string(q) {
+			spans = append(spans, [2]int{i, i + len(q)})
+			i += len(q)
+		} else {
+			i++
+		}
+	}
+	return spans
+}
+
+// Section 是同一天的记录
+type Section struct {
+	Date    string // 如 "2026-03-31"
+	Records []Record
+}
+
+// Group 把按时间排列的记录按日期分组
+func Group(records []Record) []Section {
+	var sections []Section
+	for _, r := range records {
+		date := r.Date()
+		if n := len(sections); n == 0 || sections[n-1].Date != date {
+			sections = append(sections, Section{Date: date})
+		}
+		last := &sections[len(sections)-1]
+		last.Records = append(last.Records, r)
+	}
+	return sections
+}
+
+// ParseDate 解析 "2026-03-31" 形式的本地日期
+func ParseDate(s string) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", strings.TrimSpace(s), time.Local)
+}
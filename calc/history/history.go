@@ -0,0 +1,184 @@
+// Package history 定义计算历史的记录格式，以 JSON Lines 保存，每行一条记录并带有格式版本号
+package history
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchemaVersion 是当前写入的记录格式版本
+const SchemaVersion = 1
+
+// 记录的角度模式
+const (
+	AngleDegree = "deg"
+	AngleRadian = "rad"
+)
+
+// 记录的标签
+const (
+	TagCleared = "cleared" // 按 C 键时归档的算式（而不是按 = 得到的结果）
+	TagSplit   = "split"   // 平摊的结果，明细在 Record.Split 中
+)
+
+// Record 是一条计算历史
+type Record struct {
+	Version    int       `json:"v"`
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	Expression string    `json:"expr"`
+	Result     string    `json:"result"`
+	Angle      string    `json:"angle,omitempty"`
+	Tags       []string  `json:"tags,omitempty"`
+	Split      *Split    `json:"split,omitempty"` // 只有平摊记录才有
+}
+
+// Split 是一次平摊的明细，可以据此重新打开平摊界面
+type Split struct {
+	Amount   float64      `json:"amount"`             // 账单金额
+	Tip      float64      `json:"tip,omitempty"`      // 小费，账单金额的百分比
+	Tax      float64      `json:"tax,omitempty"`      // 税，账单金额的百分比
+	Service  float64      `json:"service,omitempty"`  // 服务费，账单金额的百分比
+	Rounding int          `json:"rounding,omitempty"` // 取整方式，即 split.Rounding 的值
+	Total    int64        `json:"total"`              // 合计，以分为单位
+	Shares   []SplitShare `json:"shares"`             // 每人应付的金额
+}
+
+// SplitShare 是平摊记录中一个人的份数和应付金额
+type SplitShare struct {
+	Name   string  `json:"name,omitempty"`
+	Weight float64 `json:"weight"`
+	Amount int64   `json:"amount"` // 以分为单位
+}
+
+// NewRecord 创建一条当前版本的记录，算式中自动换行插入的 \n 会被去掉
+func NewRecord(t time.Time, expression, result, angle string, tags ...string) Record {
+	return Record{
+		Version:    SchemaVersion,
+		ID:         NewID(),
+		Time:       t,
+		Expression: strings.ReplaceAll(expression, "\n", ""),
+		Result:     result,
+		Angle:      angle,
+		Tags:       tags,
+	}
+}
+
+// NewID 生成一个随机的记录 ID
+func NewID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// HasTag 判断记录是否带有某个标签
+func (r Record) HasTag(tag string) bool {
+	for _, t := range r.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Date 返回记录所在的日期（本地时间），如 "2026-03-31"
+func (r Record) Date() string {
+	return r.Time.Local().Format("2006-01-02")
+}
+
+// Value 把结果解析为数值，结果是 "Error" 等非数值时 ok 为 false
+func (r Record) Value() (v float64, ok bool) {
+	v, err := strconv.ParseFloat(r.Result, 64)
+	return v, err == nil
+}
+
+// String 返回 "算式 = 结果" 形式的文本
+func (r Record) String() string {
+	return r.Expression + " = " + r.Result
+}
+
+// Encode 把记录逐行写为 JSON Lines
+func Encode(w io.Writer, records []Record) error {
+	enc := json.NewEncoder(w) // Encoder 会在每条记录后追加换行
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Decode 读取 JSON Lines 格式的记录
+// 无法解析的行（例如写到一半的最后一行）和更新版本写入的记录会被跳过，skipped 返回跳过的行数
+func Decode(r io.Reader) (records []Record, skipped int, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var rec Record
+		if json.Unmarshal([]byte(line), &rec) != nil || rec.Version > SchemaVersion {
+			skipped++
+			continue
+		}
+		records = append(records, upgrade(rec))
+	}
+	return records, skipped, scanner.Err()
+}
+
+// 把旧版本的记录升级为当前版本
+func upgrade(rec Record) Record {
+	if rec.Version == 0 {
+		rec.Version = SchemaVersion
+	}
+	if rec.ID == "" {
+		rec.ID = NewID()
+	}
+	return rec
+}
+
+// MigrateText 导入旧版 history.txt：
+// 以 "--- 2026-03-31 ---" 为日期标题，其余每行为 "算式 = 结果"，算式被自动换行拆开时会跨越多行
+func MigrateText(r io.Reader) ([]Record, error) {
+	var records []Record
+	date := time.Time{}
+	pending := "" // 被自动换行拆开、还没遇到 " = " 的算式片段
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "---") && strings.HasSuffix(line, "---") {
+			day := strings.TrimSpace(strings.Trim(line, "-"))
+			if t, err := time.ParseInLocation("2006-01-02", day, time.Local); err == nil {
+				date = t
+			}
+			pending = ""
+			continue
+		}
+
+		idx := strings.LastIndex(line, " = ")
+		if idx == -1 {
+			pending += line
+			continue
+		}
+		rec := NewRecord(date, pending+line[:idx], line[idx+3:], "")
+		records = append(records, rec)
+		pending = ""
+	}
+	return records, scanner.Err()
+}
@@ -0,0 +1,177 @@
+package history
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	now := time.Date(2026, 3, 31, 10, 0, 0, 0, time.UTC)
+	records := []Record{
+		NewRecord(now, "1+\n2", "3", AngleDegree),
+		NewRecord(now, "a = b", "x\ny", AngleRadian, TagCleared),
+		NewRecord(now, "平摊 100（2 人）", "张三 60, 第 2 人 40", "", TagSplit),
+	}
+	records[2].Split = &Split{Amount: 100, Tip: 10, Rounding: 2, Total: 11000, Shares: []SplitShare{
+		{Name: "张三", Weight: 1.5, Amount: 6600},
+		{Weight: 1, Amount: 4400},
+	}}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, records); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 3 {
+		t.Fatalf("Expected 3 lines, got %d", lines)
+	}
+
+	// 模拟写到一半的最后一行和更新版本写入的记录
+	buf.WriteString(`{"v":1,"id":"broken","ex` + "\n")
+	buf.WriteString(`{"v":99,"id":"future","expr":"1","result":"1"}` + "\n")
+
+	got, skipped, err := Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if skipped != 2 || len(got) != 3 {
+		t.Fatalf("Expected 3 records and 2 skipped, got %d and %d", len(got), skipped)
+	}
+	if got[0].Expression != "1+2" {
+		t.Errorf("Newline should be stripped, got %q", got[0].Expression)
+	}
+	if got[1].Expression != "a = b" || got[1].Result != "x\ny" || !got[1].HasTag(TagCleared) {
+		t.Errorf("Round trip mismatch: %+v", got[1])
+	}
+	if !got[0].Time.Equal(now) || got[1].Angle != AngleRadian {
+		t.Errorf("Round trip mismatch: %+v", got)
+	}
+	if got[0].Split != nil || !got[2].HasTag(TagSplit) || !reflect.DeepEqual(got[2].Split, records[2].Split) {
+		t.Errorf("Split round trip mismatch: %+v", got[2].Split)
+	}
+}
+
+func TestMigrateText(t *testing.T) {
+	legacy := `
+--- 2026-03-30 ---
+1+1 = 2
+123456789+
+987654321 = 1111111110
+
+--- 2026-03-31 ---
+sin(30) = 0.5
+`
+	got, err := MigrateText(strings.NewReader(legacy))
+	if err != nil {
+		t.Fatalf("MigrateText: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 records, got %d: %+v", len(got), got)
+	}
+	if got[1].Expression != "123456789+987654321" || got[1].Result != "1111111110" {
+		t.Errorf("Wrapped line: got %+v", got[1])
+	}
+	if got[0].Date() != "2026-03-30" || got[2].Date() != "2026-03-31" {
+		t.Errorf("Dates: got %s and %s", got[0].Date(), got[2].Date())
+	}
+	if got[2].Version != SchemaVersion || got[2].ID == "" {
+		t.Errorf("Migrated record should be current version with an id: %+v", got[2])
+	}
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store := NewStore(path)
+	store.MaxRecords = 3
+	store.CompactEvery = 2
+
+	now := time.Now()
+	if err := store.Append(NewRecord(now, "1+1", "2", AngleDegree)); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	// 模拟崩溃：文件末尾留下写到一半的一行
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString(`{"v":1,"id":"torn","expr":"9`)
+	f.Close()
+
+	if err := store.Append(NewRecord(now, "2+2", "4", AngleDegree)); err != nil {
+		t.Fatalf("Append after torn write: %v", err)
+	}
+	got, skipped, err := store.Load()
+	if err != nil || len(got) != 2 || skipped != 1 {
+		t.Fatalf("Load: expected 2 records and 1 skipped, got %d, %d, %v", len(got), skipped, err)
+	}
+	if !store.NeedsCompaction() {
+		t.Errorf("Expected compaction after %d appends", store.CompactEvery)
+	}
+
+	// 压缩只保留最近的 MaxRecords 条，并去掉损坏的行
+	all := append(got, NewRecord(now, "3+3", "6", AngleDegree), NewRecord(now, "4+4", "8", AngleDegree))
+	kept, err := store.Compact(all)
+	if err != nil || len(kept) != 3 || kept[0].Expression != "2+2" {
+		t.Fatalf("Compact: got %+v, %v", kept, err)
+	}
+	got, skipped, _ = store.Load()
+	if len(got) != 3 || skipped != 0 || store.NeedsCompaction() {
+		t.Errorf("After compaction: got %d records, %d skipped", len(got), skipped)
+	}
+	if matches, _ := filepath.Glob(path + ".tmp-*"); len(matches) != 0 {
+		t.Errorf("Temporary files left behind: %v", matches)
+	}
+
+	if err := store.Remove(); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if got, _, err := store.Load(); err != nil || len(got) != 0 {
+		t.Errorf("Load after remove: got %d records, %v", len(got), err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 12, 0, 0, 0, time.Local) }
+	records := []Record{
+		NewRecord(day(30), "1+1", "2", AngleDegree),
+		NewRecord(day(30), "sin(30)", "0.5", AngleDegree),
+		NewRecord(day(31), "2×3", "6", AngleDegree),
+		NewRecord(day(31), "1÷0", "Error", AngleDegree),
+	}
+	ptr := func(f float64) *float64 { return &f }
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string // 匹配记录的算式
+	}{
+		{"Empty", Filter{}, []string{"1+1", "sin(30)", "2×3", "1÷0"}},
+		{"Expression", Filter{Text: "SIN"}, []string{"sin(30)"}},
+		{"Result", Filter{Text: "error"}, []string{"1÷0"}},
+		{"Keyboard symbols", Filter{Text: "2*3"}, []string{"2×3"}},
+		{"Numeric range", Filter{Min: ptr(1), Max: ptr(6)}, []string{"1+1", "2×3"}},
+		{"Date range", Filter{From: day(31)}, []string{"2×3", "1÷0"}},
+		{"Combined", Filter{Text: "1", To: day(30), Max: ptr(1)}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, r := range tt.filter.Apply(records) {
+				got = append(got, r.Expression)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if got := Highlight("Sin(1)+sin(2)", "sin"); len(got) != 2 || got[1] != [2]int{7, 10} {
+		t.Errorf("Highlight: got %v", got)
+	}
+	sections := Group(records)
+	if len(sections) != 2 || sections[1].Date != "2026-03-31" || len(sections[1].Records) != 2 {
+		t.Errorf("Group: got %+v", sections)
+	}
+}
@@ -0,0 +1,157 @@
+package history
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store 把记录保存在一个 JSON Lines 文件中
+// 每条新记录立即追加并 fsync，崩溃最多丢失正在写入的那一行；
+// 整个文件只在压缩时重写，重写先写临时文件再 rename，任何时刻文件都是完整的
+type Store struct {
+	Path         string // 文件路径
+	MaxRecords   int    // 压缩时保留的最大条数，超出时丢弃最早的记录
+	CompactEvery int    // 自上次压缩后追加多少条记录时需要再次压缩
+
+	mu       sync.Mutex
+	appended int // 自上次压缩后追加的条数
+}
+
+// NewStore 创建一个使用默认压缩策略的 Store
+func NewStore(path string) *Store {
+	return &Store{Path: path, MaxRecords: 5000, CompactEvery: 500}
+}
+
+// Load 读取文件中的全部记录，文件不存在时返回空列表
+// skipped 为无法解析而被跳过的行数（通常是崩溃时写到一半的最后一行）
+func (s *Store) Load() (records []Record, skipped int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	return Decode(f)
+}
+
+// Append 把记录追加到文件末尾并同步到磁盘
+func (s *Store) Append(records ...Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, records); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+
+	f, err := os.OpenFile(s.Path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	// 上次写入中断时文件末尾会留下半行，先补一个换行，避免新记录和它粘在一起
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			data = append([]byte{'\n'}, data...)
+		}
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	s.appended += len(records)
+	return nil
+}
+
+// NeedsCompaction 判断追加的记录是否已经多到需要压缩
+func (s *Store) NeedsCompaction() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.CompactEvery > 0 && s.appended >= s.CompactEvery
+}
+
+// Compact 用 all 中最近的 MaxRecords 条记录原子地重写文件，返回实际保留的记录
+func (s *Store) Compact(all []Record) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.MaxRecords > 0 && len(all) > s.MaxRecords {
+		all = all[len(all)-s.MaxRecords:]
+	}
+	err := WriteFileAtomic(s.Path, func(w io.Writer) error {
+		return Encode(w, all)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.appended = 0
+	return all, nil
+}
+
+// Remove 删除历史文件，文件不存在时不报错
+func (s *Store) Remove() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.appended = 0
+	if err := os.Remove(s.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// WriteFileAtomic 先把内容写入同目录下的临时文件并 fsync，再 rename 覆盖目标文件
+// 写入过程中崩溃时，目标文件保持原样
+func WriteFileAtomic(path string, write func(io.Writer) error) (err error) {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = write(tmp); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// 同步目录，确保 rename 本身落盘；部分平台（如 Windows）不支持，忽略错误
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+	return nil
+}
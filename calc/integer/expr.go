@@ -0,0 +1,190 @@
+package integer
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// 二元运算符的优先级，数字越大越先计算
+var precedence = map[string]int{
+	"OR":  1,
+	"XOR": 2,
+	"AND": 3,
+	"<<":  4, ">>": 4, "ROL": 4, "ROR": 4,
+	"+": 5, "-": 5,
+	"×": 6, "÷": 6, "MOD": 6,
+}
+
+// 运算符的其他写法，方便在键盘上直接输入
+var opAliases = map[string]string{
+	"&": "AND", "|": "OR", "^": "XOR", "~": "NOT",
+	"*": "×", "/": "÷", "%": "MOD",
+}
+
+// 用单词书写的运算符
+var wordOps = map[string]bool{"AND": true, "OR": true, "XOR": true, "NOT": true, "MOD": true, "ROL": true, "ROR": true}
+
+type tokenKind int
+
+const (
+	tokNumber tokenKind = iota
+	tokOp
+	tokLParen
+	tokRParen
+	tokEOF
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value uint64
+}
+
+// 把算式拆分为数字、运算符和括号
+func (w Word) lex(expr string, base int) ([]token, error) {
+	var tokens []token
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")"})
+			i++
+		case (r == '<' || r == '>') && i+1 < len(runes) && runes[i+1] == r:
+			tokens = append(tokens, token{kind: tokOp, text: string(runes[i : i+2])})
+			i += 2
+		case strings.ContainsRune("+-×÷", r):
+			tokens = append(tokens, token{kind: tokOp, text: string(r)})
+			i++
+		case opAliases[string(r)] != "":
+			tokens = append(tokens, token{kind: tokOp, text: opAliases[string(r)]})
+			i++
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			// 连续的字母和数字：运算符单词或者一个数字
+			j := i
+			for j < len(runes) && runes[j] < unicode.MaxASCII && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+				j++
+			}
+			word := strings.ToUpper(string(runes[i:j]))
+			if wordOps[word] {
+				tokens = append(tokens, token{kind: tokOp, text: word})
+			} else {
+				v, err := w.Parse(word, base)
+				if err != nil {
+					return nil, err
+				}
+				tokens = append(tokens, token{kind: tokNumber, text: word, value: v})
+			}
+			i = j
+		default:
+			return nil, fmt.Errorf("无法识别的字符 %c", r)
+		}
+	}
+	return append(tokens, token{kind: tokEOF}), nil
+}
+
+// Evaluate 计算一个整数算式，算式中的数字按 base 进制书写，结果按位宽回绕
+// 支持 + - × ÷ MOD AND OR XOR NOT << >> ROL ROR 和括号，也可以写作 & | ^ ~ * / %
+// 空算式的值为 0；算式不完整时返回 ErrIncomplete
+func (w Word) Evaluate(expr string, base int) (uint64, error) {
+	tokens, err := w.lex(expr, base)
+	if err != nil {
+		return 0, err
+	}
+	if len(tokens) == 1 {
+		return 0, nil
+	}
+	p := &parser{w: w, tokens: tokens}
+	v, err := p.binary(1)
+	if err != nil {
+		return 0, err
+	}
+	switch tok := p.tokens[p.pos]; tok.kind {
+	case tokEOF:
+		return v, nil
+	case tokRParen:
+		return 0, fmt.Errorf("多余的右括号")
+	default:
+		return 0, fmt.Errorf("此处不能出现 %s", tok.text)
+	}
+}
+
+// 按优先级爬升的递归下降解析，边解析边计算
+type parser struct {
+	w      Word
+	tokens []token
+	pos    int
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// 解析优先级不低于 minPrec 的二元运算
+func (p *parser) binary(minPrec int) (uint64, error) {
+	x, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		tok := p.tokens[p.pos]
+		prec, ok := precedence[tok.text]
+		if tok.kind != tokOp || !ok || prec < minPrec {
+			return x, nil
+		}
+		p.pos++
+		y, err := p.binary(prec + 1)
+		if err != nil {
+			return 0, err
+		}
+		if x, err = p.w.Apply(tok.text, x, y); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// 解析数字、括号和一元运算符（- + NOT）
+func (p *parser) unary() (uint64, error) {
+	tok := p.next()
+	switch {
+	case tok.kind == tokEOF:
+		return 0, ErrIncomplete
+	case tok.kind == tokNumber:
+		return tok.value, nil
+	case tok.kind == tokLParen:
+		v, err := p.binary(1)
+		if err != nil {
+			return 0, err
+		}
+		switch p.next().kind {
+		case tokRParen:
+			return v, nil
+		case tokEOF:
+			return 0, ErrIncomplete
+		}
+		return 0, fmt.Errorf("缺少右括号")
+	case tok.text == "-", tok.text == "+", tok.text == "NOT":
+		v, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		switch tok.text {
+		case "-":
+			return p.w.Neg(v), nil
+		case "NOT":
+			return p.w.Not(v), nil
+		}
+		return v, nil
+	}
+	return 0, fmt.Errorf("此处不能出现 %s", tok.text)
+}
@@ -0,0 +1,155 @@
+// Package integer 提供程序员模式使用的定长整数运算：8/16/32/64 位，有符号或无符号。
+// 运算结果按位宽回绕，与 C、Go 等语言中定长整数溢出时的行为一致
+package integer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Sizes 是支持的位宽
+var Sizes = []int{8, 16, 32, 64}
+
+// Word 是定长整数的类型。数值统一以 uint64 保存位模式，只有低 Bits 位有效
+type Word struct {
+	Bits   int  // 位宽：8、16、32 或 64
+	Signed bool // 是否按补码解释为有符号数
+}
+
+// ErrIncomplete 表示算式还没有写完整，如以运算符结尾或缺少右括号
+var ErrIncomplete = errors.New("算式不完整")
+
+// 低 Bits 位全为 1 的掩码
+func (w Word) mask() uint64 {
+	if w.Bits >= 64 {
+		return ^uint64(0)
+	}
+	return 1<<w.Bits - 1
+}
+
+// Wrap 截断到位宽，超出的高位丢弃
+func (w Word) Wrap(v uint64) uint64 {
+	return v & w.mask()
+}
+
+// Int 把位模式按补码解释为有符号数（符号位扩展到 64 位）
+func (w Word) Int(v uint64) int64 {
+	shift := 64 - w.Bits
+	return int64(v<<shift) >> shift
+}
+
+// Format 按进制显示数值，十六进制使用大写字母
+// 有符号数的十进制显示带负号，其他进制总是显示位模式
+func (w Word) Format(v uint64, base int) string {
+	v = w.Wrap(v)
+	if base == 10 && w.Signed {
+		return strconv.FormatInt(w.Int(v), 10)
+	}
+	return strings.ToUpper(strconv.FormatUint(v, base))
+}
+
+// Parse 解析一个不带符号的数字，数字是位模式，超出位宽时报错
+func (w Word) Parse(s string, base int) (uint64, error) {
+	v, err := strconv.ParseUint(s, base, 64)
+	if errors.Is(err, strconv.ErrSyntax) {
+		return 0, fmt.Errorf("%s 不是有效的 %d 进制数", s, base)
+	}
+	if err != nil || v > w.mask() {
+		return 0, fmt.Errorf("%s 超出 %d 位的范围", s, w.Bits)
+	}
+	return v, nil
+}
+
+// Group 从右向左每 n 位插入一个空格，便于阅读长的二进制和十六进制数
+func Group(s string, n int) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	var b strings.Builder
+	for i, r := range s {
+		if i > 0 && (len(s)-i)%n == 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(r)
+	}
+	return sign + b.String()
+}
+
+// Not 按位取反
+func (w Word) Not(x uint64) uint64 {
+	return w.Wrap(^x)
+}
+
+// Neg 取负（补码），无符号数同样按位宽回绕
+func (w Word) Neg(x uint64) uint64 {
+	return w.Wrap(-x)
+}
+
+// Apply 计算二元运算 x op y，op 为 + - × ÷ MOD AND OR XOR << >> ROL ROR 之一
+// 有符号数的除法、取余和右移按有符号数计算，右移时高位补符号位
+func (w Word) Apply(op string, x, y uint64) (uint64, error) {
+	x, y = w.Wrap(x), w.Wrap(y)
+	switch op {
+	case "+":
+		return w.Wrap(x + y), nil
+	case "-":
+		return w.Wrap(x - y), nil
+	case "×":
+		return w.Wrap(x * y), nil
+	case "÷", "MOD":
+		if y == 0 {
+			return 0, errors.New("除数不能为零")
+		}
+		if w.Signed {
+			// 最小值 ÷ -1 溢出，Go 中结果等于被除数，截断后与回绕的结果一致
+			if op == "÷" {
+				return w.Wrap(uint64(w.Int(x) / w.Int(y))), nil
+			}
+			return w.Wrap(uint64(w.Int(x) % w.Int(y))), nil
+		}
+		if op == "÷" {
+			return x / y, nil
+		}
+		return x % y, nil
+	case "AND":
+		return x & y, nil
+	case "OR":
+		return x | y, nil
+	case "XOR":
+		return x ^ y, nil
+	}
+
+	// 移位和循环移位，y 是位数
+	if w.Signed && w.Int(y) < 0 {
+		return 0, errors.New("移位的位数不能为负数")
+	}
+	bits := uint64(w.Bits)
+	switch op {
+	case "<<":
+		if y >= bits {
+			return 0, nil
+		}
+		return w.Wrap(x << y), nil
+	case ">>":
+		if w.Signed {
+			return w.Wrap(uint64(w.Int(x) >> min(y, 63))), nil
+		}
+		if y >= bits {
+			return 0, nil
+		}
+		return x >> y, nil
+	case "ROL", "ROR":
+		n := y % bits
+		if op == "ROR" {
+			n = (bits - n) % bits
+		}
+		if n == 0 {
+			return x, nil
+		}
+		return w.Wrap(x<<n | x>>(bits-n)), nil
+	}
+	return 0, fmt.Errorf("未知的运算符 %s", op)
+}
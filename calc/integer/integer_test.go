@@ -0,0 +1,125 @@
+package integer
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	u8 := Word{Bits: 8}
+	i8 := Word{Bits: 8, Signed: true}
+	u16 := Word{Bits: 16}
+	i32 := Word{Bits: 32, Signed: true}
+	u64 := Word{Bits: 64}
+	i64 := Word{Bits: 64, Signed: true}
+
+	tests := []struct {
+		name     string
+		word     Word
+		base     int
+		expr     string
+		expected string // 按 base 进制显示的结果
+	}{
+		{"Hex Digits", u16, 16, "ff + a", "109"},
+		{"And Or", u8, 16, "F0 OR 0F AND 3C", "FC"}, // AND 先于 OR
+		{"Xor", u8, 2, "1100 XOR 1010", "110"},
+		{"Not Unsigned", u8, 10, "NOT 0", "255"},
+		{"Not Signed", i8, 10, "NOT 0", "-1"},
+		{"Symbols", u8, 16, "~F0 & 3C | 1", "D"},
+		{"Add Wraps", u8, 10, "250 + 10", "4"},
+		{"Signed Add Wraps", i8, 10, "127 + 1", "-128"},
+		{"Mul Wraps", u16, 16, "FFFF × FFFF", "1"},
+		{"Sub Below Zero", u8, 16, "0 - 1", "FF"},
+		{"Signed Div", i8, 10, "-7 ÷ 2", "-3"},
+		{"Unsigned Div", u8, 10, "249 ÷ 2", "124"}, // 249 即 -7 的位模式
+		{"Min Div Minus One", i8, 10, "-128 ÷ -1", "-128"},
+		{"Signed Mod", i32, 10, "-7 MOD 3", "-1"},
+		{"Shift Left", u8, 2, "1 << 111", "10000000"},
+		{"Shift Out", u8, 10, "1 << 8", "0"},
+		{"Arithmetic Shift", i8, 10, "-16 >> 2", "-4"},
+		{"Logical Shift", u8, 16, "F0 >> 4", "F"},
+		{"Rotate Left", u8, 2, "10000001 ROL 1", "11"},
+		{"Rotate Right", u8, 2, "11 ROR 1", "10000001"},
+		{"Rotate Full", u16, 16, "1234 ROL 10", "1234"}, // 10 是十六进制的 16
+		{"Rotate 64", u64, 16, "8000000000000000 ROL 1", "1"},
+		{"Min Int64", i64, 10, "-9223372036854775807 - 1", "-9223372036854775808"},
+		{"Precedence", u16, 10, "1 + 2 × 3 << 1", "14"},
+		{"Parentheses", u16, 8, "(7 + 1) × 2", "20"},
+		{"Octal", u16, 8, "777 AND 70", "70"},
+		{"Empty", u8, 10, "", "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := tt.word.Evaluate(tt.expr, tt.base)
+			if err != nil {
+				t.Fatalf("Evaluate(%q): %v", tt.expr, err)
+			}
+			if got := tt.word.Format(v, tt.base); got != tt.expected {
+				t.Errorf("Evaluate(%q): Expected: %s, Got: %s", tt.expr, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	u8 := Word{Bits: 8}
+	tests := []struct {
+		expr       string
+		base       int
+		incomplete bool
+	}{
+		{"FF AND", 16, true},
+		{"(1 + 2", 10, true},
+		{"NOT", 10, true},
+		{"1 + 2)", 10, false},
+		{"12", 2, false},  // 二进制中没有 2
+		{"FF", 10, false}, // 十进制中没有字母
+		{"256", 10, false},
+		{"1 ÷ 0", 10, false},
+		{"1 2", 10, false},
+	}
+	for _, tt := range tests {
+		_, err := u8.Evaluate(tt.expr, tt.base)
+		if err == nil {
+			t.Errorf("Evaluate(%q) should fail", tt.expr)
+			continue
+		}
+		if errors.Is(err, ErrIncomplete) != tt.incomplete {
+			t.Errorf("Evaluate(%q): incomplete = %v, got error %v", tt.expr, tt.incomplete, err)
+		}
+	}
+
+	i8 := Word{Bits: 8, Signed: true}
+	if _, err := i8.Evaluate("1 << -1", 10); err == nil {
+		t.Error("Negative shift should fail")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	i16 := Word{Bits: 16, Signed: true}
+	v, _ := i16.Evaluate("-2", 10)
+	tests := []struct {
+		base     int
+		expected string
+	}{
+		{16, "FFFE"},
+		{10, "-2"},
+		{8, "177776"},
+		{2, "1111111111111110"},
+	}
+	for _, tt := range tests {
+		if got := i16.Format(v, tt.base); got != tt.expected {
+			t.Errorf("Format(-2, %d): Expected: %s, Got: %s", tt.base, tt.expected, got)
+		}
+	}
+	if got := Group("1111111111111110", 4); got != "1111 1111 1111 1110" {
+		t.Errorf("Group: got %q", got)
+	}
+	if got := Group("-12345", 3); got != "-12 345" {
+		t.Errorf("Group negative: got %q", got)
+	}
+	if got := (Word{Bits: 32}).Format(i16.Neg(2), 16); got != "FFFE" {
+		t.Errorf("Format keeps the bit pattern within the width, got %s", got)
+	}
+}
@@ -0,0 +1,212 @@
+// Package plot 为函数图像提供取样、坐标范围、刻度以及零点和交点的查找，不依赖 Fyne
+// 算式用求值引擎 engine 解析，x 是自变量，其余名称与计算界面相同
+package plot
+
+import (
+	"math"
+
+	"github.com/gzjjjfree/MemoryCalculator/calc/engine"
+	"github.com/gzjjjfree/MemoryCalculator/calc/solve"
+)
+
+// Func 是一个一元函数，在 x 处无定义（如 ln(-1)、tan 的渐近线）时 ok 为 false
+type Func func(x float64) (y float64, ok bool)
+
+// Compile 把含有 x 的算式解析为函数，只解析一次，每次调用重新求值
+// 算式按浮点计算（不使用精确模式和复数模式），同名的用户变量 x 被自变量遮住
+// 算式中有未知的名称或参数个数不对时返回错误；只在部分 x 上无定义不算错误
+func Compile(expr string, opts engine.Options) (Func, error) {
+	var x float64
+	opts.Exact, opts.Complex = false, false
+	outer := opts.Variables
+	opts.Variables = func(name string) (float64, bool) {
+		if name == "x" {
+			return x, true
+		}
+		if outer == nil {
+			return 0, false
+		}
+		return outer(name)
+	}
+
+	node, err := engine.Parse(expr, opts)
+	if err != nil {
+		return nil, err
+	}
+	f := func(v float64) (float64, bool) {
+		x = v
+		res, err := engine.Eval(node, opts)
+		if err != nil || math.IsNaN(res.Value) || math.IsInf(res.Value, 0) {
+			return 0, false
+		}
+		return res.Value, true
+	}
+
+	// 在几个点上试算：名称和参数个数的错误与 x 无关，任何一点都会出现
+	for _, v := range []float64{0, 1, -1, 0.5} {
+		x = v
+		_, err := engine.Eval(node, opts)
+		if e := engine.AsError(err); e != nil && (e.Kind == engine.KindUnknownName || e.Kind == engine.KindArity) {
+			return nil, e
+		}
+	}
+	return f, nil
+}
+
+// Point 是平面上的一个点
+type Point struct {
+	X, Y float64
+}
+
+// Viewport 是图像显示的坐标范围
+type Viewport struct {
+	XMin, XMax float64
+	YMin, YMax float64
+}
+
+// DefaultViewport 是复位后的坐标范围
+var DefaultViewport = Viewport{XMin: -10, XMax: 10, YMin: -10, YMax: 10}
+
+// 缩放时坐标范围宽度的上下限，超出后浮点精度不足以画出图像
+const (
+	minSpan = 1e-9
+	maxSpan = 1e12
+)
+
+// Pan 把坐标范围平移 (dx, dy)
+func (v Viewport) Pan(dx, dy float64) Viewport {
+	return Viewport{v.XMin + dx, v.XMax + dx, v.YMin + dy, v.YMax + dy}
+}
+
+// Zoom 以 (cx, cy) 为中心缩放，factor 小于 1 时放大；宽度或高度超出限制时保持不变
+func (v Viewport) Zoom(factor, cx, cy float64) Viewport {
+	w, h := (v.XMax-v.XMin)*factor, (v.YMax-v.YMin)*factor
+	if w < minSpan || h < minSpan || w > maxSpan || h > maxSpan {
+		return v
+	}
+	return Viewport{
+		XMin: cx - (cx-v.XMin)*factor,
+		XMax: cx + (v.XMax-cx)*factor,
+		YMin: cy - (cy-v.YMin)*factor,
+		YMax: cy + (v.YMax-cy)*factor,
+	}
+}
+
+// Clip 把线段 ab 裁剪到坐标范围内（Liang–Barsky 算法），线段完全在范围外时 ok 为 false
+func (v Viewport) Clip(a, b Point) (Point, Point, bool) {
+	t0, t1 := 0.0, 1.0
+	dx, dy := b.X-a.X, b.Y-a.Y
+	edges := [4][2]float64{
+		{-dx, a.X - v.XMin},
+		{dx, v.XMax - a.X},
+		{-dy, a.Y - v.YMin},
+		{dy, v.YMax - a.Y},
+	}
+	for _, e := range edges {
+		p, q := e[0], e[1]
+		if p == 0 {
+			if q < 0 {
+				return a, b, false // 与这条边平行且在外侧
+			}
+			continue
+		}
+		t := q / p
+		if p < 0 {
+			t0 = max(t0, t)
+		} else {
+			t1 = min(t1, t)
+		}
+		if t0 > t1 {
+			return a, b, false
+		}
+	}
+	return Point{a.X + t0*dx, a.Y + t0*dy}, Point{a.X + t1*dx, a.Y + t1*dy}, true
+}
+
+// Sample 在坐标范围的 x 区间上均匀取 n+1 个点，返回可以分别连成折线的若干段
+// 无定义的点和不连续处（如 tan 的渐近线）把曲线断开，不会画出跨越断点的竖线
+func Sample(f Func, v Viewport, n int) [][]Point {
+	var segments [][]Point
+	var current []Point
+	flush := func() {
+		if len(current) > 0 {
+			segments = append(segments, current)
+		}
+		current = nil
+	}
+	height := v.YMax - v.YMin
+	for i := 0; i <= n; i++ {
+		x := v.XMin + (v.XMax-v.XMin)*float64(i)/float64(n)
+		y, ok := f(x)
+		if !ok {
+			flush()
+			continue
+		}
+		p := Point{x, y}
+		if len(current) > 0 && jump(f, current[len(current)-1], p, height) {
+			flush()
+		}
+		current = append(current, p)
+	}
+	flush()
+	return segments
+}
+
+// 判断相邻两点之间是否断开：变化超过可见的高度，并且中点的值不在两点之间
+// 连续而陡峭的曲线中点总在两点之间；渐近线两侧的中点会落到其中一侧的更远处
+func jump(f Func, a, b Point, height float64) bool {
+	if math.Abs(b.Y-a.Y) <= height {
+		return false
+	}
+	ym, ok := f((a.X + b.X) / 2)
+	return !ok || ym < min(a.Y, b.Y) || ym > max(a.Y, b.Y)
+}
+
+// Ticks 返回区间内间隔为 1、2、5 乘以 10 的整数次幂的刻度，大约 target 个
+func Ticks(lo, hi float64, target int) []float64 {
+	if !(hi > lo) || target < 1 {
+		return nil
+	}
+	raw := (hi - lo) / float64(target)
+	step := math.Pow(10, math.Floor(math.Log10(raw)))
+	switch m := raw / step; {
+	case m > 5:
+		step *= 10
+	case m > 2:
+		step *= 5
+	case m > 1:
+		step *= 2
+	}
+
+	var ticks []float64
+	first := math.Ceil(lo / step)
+	for i := first; i*step <= hi+step*1e-9; i++ {
+		v := i * step
+		if math.Abs(v) < step*1e-9 {
+			v = 0 // 消除 0 附近的舍入误差，如 -1.1e-17
+		}
+		ticks = append(ticks, v)
+	}
+	return ticks
+}
+
+// Roots 在 [a, b] 上分成 n 段查找 f 的零点，按从小到大排列，查找方法见 solve.All
+func Roots(f Func, a, b float64, n int) []float64 {
+	return solve.All(solve.Func(f), a, b, (b-a)*1e-12, n)
+}
+
+// Intersections 在 [a, b] 上查找两条曲线的交点，按 x 从小到大排列
+func Intersections(f, g Func, a, b float64, n int) []Point {
+	diff := func(x float64) (float64, bool) {
+		fy, ok1 := f(x)
+		gy, ok2 := g(x)
+		return fy - gy, ok1 && ok2
+	}
+	var points []Point
+	for _, x := range Roots(diff, a, b, n) {
+		if y, ok := f(x); ok {
+			points = append(points, Point{x, y})
+		}
+	}
+	return points
+}
@@ -0,0 +1,157 @@
+package plot
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/gzjjjfree/MemoryCalculator/calc/engine"
+)
+
+// 编译算式，失败时结束测试
+func compile(t *testing.T, expr string, opts engine.Options) Func {
+	t.Helper()
+	f, err := Compile(expr, opts)
+	if err != nil {
+		t.Fatalf("Compile(%q): %v", expr, err)
+	}
+	return f
+}
+
+// 比较两组数值，允许 tol 以内的误差
+func closeTo(got, want []float64, tol float64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if math.Abs(got[i]-want[i]) > tol {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCompile(t *testing.T) {
+	rad := engine.Options{Angle: engine.Radian}
+	f := compile(t, "2x^2+sin(x)", rad)
+	if y, ok := f(math.Pi); !ok || math.Abs(y-2*math.Pi*math.Pi) > 1e-9 {
+		t.Errorf("2x^2+sin(x) at π: got %v %v", y, ok)
+	}
+	if _, ok := compile(t, "ln(x)", rad)(-1); ok {
+		t.Error("ln(-1) should be undefined")
+	}
+
+	// 其他名称与计算界面相同，x 遮住同名的变量
+	vars := rad
+	vars.Variables = func(name string) (float64, bool) {
+		switch name {
+		case "a":
+			return 3, true
+		case "x":
+			return 100, true
+		}
+		return 0, false
+	}
+	if y, _ := compile(t, "a×x", vars)(2); y != 6 {
+		t.Errorf("a×x at 2: got %v", y)
+	}
+
+	// 角度模式与计算界面一致
+	if y, _ := compile(t, "sin(x)", engine.Options{})(90); math.Abs(y-1) > 1e-12 {
+		t.Errorf("sin(90) in degrees: got %v", y)
+	}
+
+	for _, expr := range []string{"x+", "foo(x)", "x+q"} {
+		if _, err := Compile(expr, rad); err == nil {
+			t.Errorf("Compile(%q) should fail", expr)
+		}
+	}
+}
+
+func TestSample(t *testing.T) {
+	rad := engine.Options{Angle: engine.Radian}
+	v := Viewport{XMin: -5, XMax: 5, YMin: -5, YMax: 5}
+
+	// tan 在 ±π/2 和 ±3π/2 处断开，共五段
+	if segs := Sample(compile(t, "tan(x)", rad), v, 400); len(segs) != 5 {
+		t.Errorf("tan(x): expected 5 segments, got %d", len(segs))
+	}
+	// 陡峭但连续的曲线不断开
+	if segs := Sample(compile(t, "x^3", rad), v, 50); len(segs) != 1 {
+		t.Errorf("x^3: expected 1 segment, got %d", len(segs))
+	}
+	// 无定义的部分不画
+	segs := Sample(compile(t, "sqrt(x)", rad), v, 10)
+	if len(segs) != 1 || segs[0][0].X != 0 || len(segs[0]) != 6 {
+		t.Errorf("sqrt(x): got %v", segs)
+	}
+	// 1/x 在 0 处无定义，两侧分开
+	if segs := Sample(compile(t, "1÷x", rad), v, 10); len(segs) != 2 {
+		t.Errorf("1/x: expected 2 segments, got %d", len(segs))
+	}
+}
+
+func TestRoots(t *testing.T) {
+	rad := engine.Options{Angle: engine.Radian}
+	tests := []struct {
+		expr string
+		a, b float64
+		want []float64
+	}{
+		{"x^2-2", -5, 5, []float64{-math.Sqrt2, math.Sqrt2}},
+		{"sin(x)", -1, 7, []float64{0, math.Pi, 2 * math.Pi}},
+		{"(x-1)^2", -5, 5, []float64{1}},           // 不变号的重根
+		{"tan(x)", 1, 2, nil},                      // 渐近线不是零点
+		{"x^2+1", -5, 5, nil},                      // 没有实根
+		{"ln(x)", -3, 3, []float64{1}},             // 部分区间无定义
+		{"x^3-x", -2, 2, []float64{-1, 0, 1}},      // 取样点恰好落在零点上
+		{"cos(x)+1", 0, 6, []float64{math.Pi}},     // 只碰到 0
+		{"x-0.000001", -1, 1, []float64{0.000001}}, // 靠近取样点的零点
+	}
+	for _, tt := range tests {
+		got := Roots(compile(t, tt.expr, rad), tt.a, tt.b, 200)
+		if !closeTo(got, tt.want, 1e-6) {
+			t.Errorf("Roots(%s): Expected: %v, Got: %v", tt.expr, tt.want, got)
+		}
+	}
+}
+
+func TestIntersections(t *testing.T) {
+	rad := engine.Options{Angle: engine.Radian}
+	got := Intersections(compile(t, "x^2", rad), compile(t, "x+2", rad), -10, 10, 400)
+	want := []Point{{-1, 1}, {2, 4}}
+	if len(got) != 2 || !closeTo([]float64{got[0].X, got[0].Y, got[1].X, got[1].Y}, []float64{-1, 1, 2, 4}, 1e-9) {
+		t.Errorf("x^2 ∩ x+2: Expected: %v, Got: %v", want, got)
+	}
+	if got := Intersections(compile(t, "x", rad), compile(t, "x+1", rad), -10, 10, 400); got != nil {
+		t.Errorf("Parallel lines: got %v", got)
+	}
+}
+
+func TestViewport(t *testing.T) {
+	v := Viewport{XMin: -10, XMax: 10, YMin: -5, YMax: 5}
+	if got := v.Zoom(0.5, 2, 0); got != (Viewport{XMin: -4, XMax: 6, YMin: -2.5, YMax: 2.5}) {
+		t.Errorf("Zoom: got %+v", got)
+	}
+	if got := v.Pan(1, -1); got != (Viewport{XMin: -9, XMax: 11, YMin: -6, YMax: 4}) {
+		t.Errorf("Pan: got %+v", got)
+	}
+	if got := v.Zoom(1e-12, 0, 0); got != v {
+		t.Errorf("Zoom beyond the limit should keep the viewport, got %+v", got)
+	}
+
+	a, b, ok := v.Clip(Point{0, 0}, Point{0, 100})
+	if !ok || a != (Point{0, 0}) || b != (Point{0, 5}) {
+		t.Errorf("Clip: got %v %v %v", a, b, ok)
+	}
+	if _, _, ok := v.Clip(Point{20, 0}, Point{30, 1}); ok {
+		t.Error("Segment outside should be dropped")
+	}
+
+	if got := Ticks(-10, 10, 5); !reflect.DeepEqual(got, []float64{-10, -5, 0, 5, 10}) {
+		t.Errorf("Ticks(-10, 10): got %v", got)
+	}
+	if got := Ticks(0.1, 0.7, 4); !closeTo(got, []float64{0.2, 0.4, 0.6}, 1e-12) {
+		t.Errorf("Ticks(0.1, 0.7): got %v", got)
+	}
+}
@@ -0,0 +1,82 @@
+package solve
+
+import (
+	"errors"
+	"math/big"
+)
+
+// 一次方程组的最大元数
+const MaxUnknowns = 4
+
+var (
+	ErrSize       = errors.New("只能求解一到四元一次方程组")
+	ErrNoSolution = errors.New("方程组无解")
+	ErrInfinite   = errors.New("方程组有无穷多个解")
+)
+
+// Linear 用分数精确地求解 n 元一次方程组 a·x = b，a 是 n×n 的系数矩阵（1 ≤ n ≤ 4）
+// 用高斯–约当消元，系数矩阵奇异时区分无解和无穷多个解
+func Linear(a [][]*big.Rat, b []*big.Rat) ([]*big.Rat, error) {
+	n := len(a)
+	if n < 1 || n > MaxUnknowns || len(b) != n {
+		return nil, ErrSize
+	}
+	// 增广矩阵，最后一列是常数项
+	m := make([][]*big.Rat, n)
+	for i := range a {
+		if len(a[i]) != n {
+			return nil, ErrSize
+		}
+		m[i] = make([]*big.Rat, n+1)
+		for j := range a[i] {
+			m[i][j] = new(big.Rat).Set(a[i][j])
+		}
+		m[i][n] = new(big.Rat).Set(b[i])
+	}
+
+	rank := 0
+	tmp := new(big.Rat)
+	for col := 0; col < n; col++ {
+		pivot := -1
+		for r := rank; r < n; r++ {
+			if m[r][col].Sign() != 0 {
+				pivot = r
+				break
+			}
+		}
+		if pivot < 0 {
+			continue
+		}
+		m[rank], m[pivot] = m[pivot], m[rank]
+
+		inv := new(big.Rat).Inv(m[rank][col])
+		for j := col; j <= n; j++ {
+			m[rank][j].Mul(m[rank][j], inv)
+		}
+		for r := range m {
+			if r == rank || m[r][col].Sign() == 0 {
+				continue
+			}
+			factor := new(big.Rat).Set(m[r][col])
+			for j := col; j <= n; j++ {
+				m[r][j].Sub(m[r][j], tmp.Mul(factor, m[rank][j]))
+			}
+		}
+		rank++
+	}
+
+	// 消元后系数全为 0 的行：常数项不为 0 时矛盾
+	for r := rank; r < n; r++ {
+		if m[r][n].Sign() != 0 {
+			return nil, ErrNoSolution
+		}
+	}
+	if rank < n {
+		return nil, ErrInfinite
+	}
+	x := make([]*big.Rat, n)
+	for i := range x {
+		x[i] = m[i][n]
+	}
+	return x, nil
+}
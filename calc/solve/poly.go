@@ -0,0 +1,307 @@
+package solve
+
+import (
+	"cmp"
+	"errors"
+	"math"
+	"math/big"
+	"math/cmplx"
+	"slices"
+)
+
+var ErrDegree = errors.New("只能求解一到四次的多项式方程")
+
+// Polynomial 按升幂排列系数：p[0] + p[1]·x + p[2]·x² + …，nil 表示 0
+type Polynomial []*big.Rat
+
+// Root 是多项式方程的一个根，有理数根的 Exact 不为 nil
+type Root struct {
+	Value complex128
+	Exact *big.Rat
+}
+
+// IsReal 判断是否为实根
+func (r Root) IsReal() bool {
+	return imag(r.Value) == 0
+}
+
+// Degree 返回多项式的次数，零多项式返回 -1
+func (p Polynomial) Degree() int {
+	for i := len(p) - 1; i >= 0; i-- {
+		if p[i] != nil && p[i].Sign() != 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// Eval 精确计算 x 处的值
+func (p Polynomial) Eval(x *big.Rat) *big.Rat {
+	sum := new(big.Rat)
+	for i := len(p) - 1; i >= 0; i-- {
+		sum.Mul(sum, x)
+		if p[i] != nil {
+			sum.Add(sum, p[i])
+		}
+	}
+	return sum
+}
+
+// Roots 求多项式方程 p(x) = 0 的全部根（含复数根，重根重复列出），实根在前并从小到大排列
+// 有理数根逐个精确地找出并从多项式中除去，剩下的根用求根公式或 Durand–Kerner 迭代求近似值
+func (p Polynomial) Roots() ([]Root, error) {
+	d := p.Degree()
+	if d < 1 || d > 4 {
+		return nil, ErrDegree
+	}
+	q := make(Polynomial, d+1)
+	for i := range q {
+		q[i] = new(big.Rat)
+		if p[i] != nil {
+			q[i].Set(p[i])
+		}
+	}
+
+	var roots []Root
+	for len(q) > 1 {
+		r := q.rationalRoot()
+		if r == nil {
+			break
+		}
+		f, _ := r.Float64()
+		roots = append(roots, Root{Value: complex(f, 0), Exact: r})
+		q = q.deflate(r)
+	}
+	if len(q) > 1 {
+		for _, z := range numericRoots(q.floats()) {
+			roots = append(roots, Root{Value: z})
+		}
+	}
+
+	slices.SortStableFunc(roots, func(a, b Root) int {
+		if a.IsReal() != b.IsReal() {
+			if a.IsReal() {
+				return -1
+			}
+			return 1
+		}
+		if c := cmp.Compare(real(a.Value), real(b.Value)); c != 0 {
+			return c
+		}
+		return cmp.Compare(imag(a.Value), imag(b.Value))
+	})
+	return roots, nil
+}
+
+// 查找一个有理数根，没有时返回 nil
+// 先按有理根定理逐个精确验证候选根 ±a/b（系数化为整数后，a 整除常数项，b 整除最高次系数），
+// 这样重根附近近似值不够准时也能找到；系数太大、候选根太多时，
+// 改为从近似根出发，用连分数列出近似根的渐近分数逐个验证
+func (p Polynomial) rationalRoot() *big.Rat {
+	if p[0].Sign() == 0 {
+		return new(big.Rat)
+	}
+	if r, ok := p.candidateRoot(); ok {
+		return r
+	}
+	for _, z := range numericRoots(p.floats()) {
+		if math.Abs(imag(z)) > 1e-6*max(1, cmplx.Abs(z)) {
+			continue
+		}
+		for _, r := range convergents(real(z)) {
+			if p.Eval(r).Sign() == 0 {
+				return r
+			}
+		}
+	}
+	return nil
+}
+
+// 有理根定理的候选根个数上限
+const maxCandidates = 10000
+
+// 按有理根定理查找有理数根（常数项不能为 0），ok 为 false 表示系数太大或候选根太多，没有查找
+func (p Polynomial) candidateRoot() (r *big.Rat, ok bool) {
+	// 各系数分母的最小公倍数，乘上后系数都是整数
+	lcm := big.NewInt(1)
+	for _, c := range p {
+		g := new(big.Int).GCD(nil, nil, lcm, c.Denom())
+		lcm.Mul(lcm, new(big.Int).Quo(c.Denom(), g))
+	}
+	integer := func(c *big.Rat) *big.Int {
+		n := new(big.Int).Quo(lcm, c.Denom())
+		return n.Mul(n, c.Num())
+	}
+	nums, ok1 := divisors(integer(p[0]))
+	dens, ok2 := divisors(integer(p[len(p)-1]))
+	if !ok1 || !ok2 || len(nums)*len(dens) > maxCandidates {
+		return nil, false
+	}
+
+	one := big.NewInt(1)
+	for _, b := range dens {
+		for _, a := range nums {
+			if new(big.Int).GCD(nil, nil, big.NewInt(a), big.NewInt(b)).Cmp(one) != 0 {
+				continue // 不是最简分数，已经验证过
+			}
+			for _, c := range []*big.Rat{big.NewRat(a, b), big.NewRat(-a, b)} {
+				if p.Eval(c).Sign() == 0 {
+					return c, true
+				}
+			}
+		}
+	}
+	return nil, true
+}
+
+// 列出 |n| 的全部正因数，从小到大排列；n 为 0 或 |n| 超过 2⁴⁰ 时不列出
+func divisors(n *big.Int) ([]int64, bool) {
+	if n.Sign() == 0 || n.BitLen() > 40 {
+		return nil, false
+	}
+	v := new(big.Int).Abs(n).Int64()
+	var small, large []int64
+	for i := int64(1); i*i <= v; i++ {
+		if v%i == 0 {
+			small = append(small, i)
+			if i*i != v {
+				large = append(large, v/i)
+			}
+		}
+	}
+	slices.Reverse(large)
+	return append(small, large...), true
+}
+
+// 用综合除法精确地除以 (x - r)，r 必须是根
+func (p Polynomial) deflate(r *big.Rat) Polynomial {
+	q := make(Polynomial, len(p)-1)
+	carry := new(big.Rat)
+	for i := len(p) - 1; i >= 1; i-- {
+		carry.Mul(carry, r)
+		carry.Add(carry, p[i])
+		q[i-1] = new(big.Rat).Set(carry)
+	}
+	return q
+}
+
+func (p Polynomial) floats() []float64 {
+	c := make([]float64, len(p))
+	for i, r := range p {
+		c[i], _ = r.Float64()
+	}
+	return c
+}
+
+// 连分数展开 x 得到的渐近分数，分母不超过 10⁶；|x| 太大时不展开
+func convergents(x float64) []*big.Rat {
+	const maxDen = 1_000_000
+	if math.IsNaN(x) || math.Abs(x) > 1e9 {
+		return nil
+	}
+	var list []*big.Rat
+	h0, h1 := int64(0), int64(1)
+	k0, k1 := int64(1), int64(0)
+	v := x
+	for range 40 {
+		a := math.Floor(v)
+		h2, k2 := int64(a)*h1+h0, int64(a)*k1+k0
+		if k2 > maxDen {
+			break
+		}
+		h0, h1, k0, k1 = h1, h2, k1, k2
+		list = append(list, big.NewRat(h1, k1))
+		frac := v - a
+		if frac < 1e-12 {
+			break
+		}
+		v = 1 / frac
+	}
+	return list
+}
+
+// 求系数为 c（升幂，最高次系数不为 0）的多项式的全部复数根
+// 一、二次用求根公式；三、四次用 Durand–Kerner 迭代，再用牛顿法修正。
+// 虚部相对很小的根当作实根（重根附近只能达到大约一半的有效数字）
+func numericRoots(c []float64) []complex128 {
+	n := len(c) - 1
+	switch n {
+	case 1:
+		return []complex128{complex(-c[0]/c[1], 0)}
+	case 2:
+		return quadratic(c[2], c[1], c[0])
+	}
+
+	a := make([]complex128, n+1)
+	for i := range c {
+		a[i] = complex(c[i]/c[n], 0)
+	}
+	eval := func(z complex128) (complex128, complex128) {
+		var v, dv complex128
+		for i := n; i >= 0; i-- {
+			dv = dv*z + v
+			v = v*z + a[i]
+		}
+		return v, dv
+	}
+
+	z := make([]complex128, n)
+	seed := complex(0.4, 0.9)
+	z[0] = 1
+	for i := 1; i < n; i++ {
+		z[i] = z[i-1] * seed
+	}
+	for range 1000 {
+		var change float64
+		for i := range z {
+			v, _ := eval(z[i])
+			den := complex(1, 0)
+			for j := range z {
+				if j != i {
+					den *= z[i] - z[j]
+				}
+			}
+			if den == 0 {
+				den = 1e-30
+			}
+			step := v / den
+			z[i] -= step
+			change = max(change, cmplx.Abs(step)/max(1, cmplx.Abs(z[i])))
+		}
+		if change < 1e-16 {
+			break
+		}
+	}
+
+	for i := range z {
+		for range 3 {
+			v, dv := eval(z[i])
+			if dv == 0 {
+				break
+			}
+			next := z[i] - v/dv
+			if nv, _ := eval(next); cmplx.Abs(nv) >= cmplx.Abs(v) {
+				break
+			}
+			z[i] = next
+		}
+		if math.Abs(imag(z[i])) <= 1e-7*max(1, cmplx.Abs(z[i])) {
+			z[i] = complex(real(z[i]), 0)
+		}
+	}
+	return z
+}
+
+// 用避免相减抵消的形式求 ax² + bx + c = 0 的两个根
+func quadratic(a, b, c float64) []complex128 {
+	disc := b*b - 4*a*c
+	if disc < 0 {
+		re, im := -b/(2*a), math.Sqrt(-disc)/(2*math.Abs(a))
+		return []complex128{complex(re, -im), complex(re, im)}
+	}
+	q := -(b + math.Copysign(math.Sqrt(disc), b)) / 2
+	if q == 0 {
+		return []complex128{0, 0}
+	}
+	return []complex128{complex(q/a, 0), complex(c/q, 0)}
+}
@@ -0,0 +1,211 @@
+// Package solve 求解方程：一元方程的数值求根、四次以内的多项式方程和四元以内的一次方程组
+package solve
+
+import (
+	"errors"
+	"math"
+)
+
+// Func 是一个一元函数，在 x 处无定义时 ok 为 false
+type Func func(x float64) (y float64, ok bool)
+
+var (
+	ErrNoRoot      = errors.New("在范围内没有找到根")
+	ErrNotBracket  = errors.New("区间两端的函数值同号")
+	ErrUndefined   = errors.New("函数在迭代点处无定义")
+	ErrNoConverge  = errors.New("迭代没有收敛")
+	ErrInvalidSpan = errors.New("范围无效：下限必须小于上限")
+)
+
+// 迭代的最大次数
+const maxIterations = 200
+
+// 用中心差分近似 f 在 x 处的导数
+func derivative(f Func, x float64) (float64, bool) {
+	h := 1e-6 * max(1, math.Abs(x))
+	y1, ok1 := f(x - h)
+	y2, ok2 := f(x + h)
+	return (y2 - y1) / (2 * h), ok1 && ok2
+}
+
+// Newton 从 x0 出发用牛顿法迭代（导数用中心差分近似），相邻两次的差不超过 tol 时结束
+// 迭代点离开 [lo, hi]、导数为 0 或者不收敛时返回错误
+func Newton(f Func, x0, lo, hi, tol float64) (float64, error) {
+	x := x0
+	for range maxIterations {
+		y, ok := f(x)
+		if !ok {
+			return 0, ErrUndefined
+		}
+		if y == 0 {
+			return x, nil
+		}
+		d, ok := derivative(f, x)
+		if !ok || d == 0 || math.IsNaN(d) || math.IsInf(d, 0) {
+			return 0, ErrNoConverge
+		}
+		next := x - y/d
+		if next < lo || next > hi {
+			return 0, ErrNoRoot
+		}
+		if math.Abs(next-x) <= tol {
+			return next, nil
+		}
+		x = next
+	}
+	return 0, ErrNoConverge
+}
+
+// Bracket 在 f(a) 与 f(b) 异号的区间上求根：牛顿步落在当前区间内时采用，否则二分，
+// 区间每一步都会缩小，因此一定收敛；区间宽度不超过 tol 或者无法再分时结束
+func Bracket(f Func, a, b, tol float64) (float64, error) {
+	fa, ok1 := f(a)
+	fb, ok2 := f(b)
+	switch {
+	case !ok1 || !ok2:
+		return 0, ErrUndefined
+	case fa == 0:
+		return a, nil
+	case fb == 0:
+		return b, nil
+	case (fa < 0) == (fb < 0):
+		return 0, ErrNotBracket
+	}
+	if a > b {
+		a, b, fa = b, a, fb
+	}
+
+	x := (a + b) / 2
+	for range maxIterations {
+		y, ok := f(x)
+		if !ok {
+			return 0, ErrUndefined
+		}
+		if y == 0 {
+			return x, nil
+		}
+		if (y < 0) == (fa < 0) {
+			a, fa = x, y
+		} else {
+			b = x
+		}
+		if b-a <= tol {
+			return (a + b) / 2, nil
+		}
+
+		next := (a + b) / 2
+		if d, ok := derivative(f, x); ok && d != 0 {
+			if n := x - y/d; n > a && n < b {
+				next = n
+			}
+		}
+		if next == x || next == a || next == b {
+			return x, nil // 已经到达浮点数的精度
+		}
+		x = next
+	}
+	return x, nil
+}
+
+// Solve 在 [lo, hi] 内求一个根：先从 guess 做牛顿迭代，失败时把范围分成 n 段，
+// 在离 guess 最近的变号段中用 Bracket 求根
+func Solve(f Func, guess, lo, hi, tol float64, n int) (float64, error) {
+	if !(lo < hi) {
+		return 0, ErrInvalidSpan
+	}
+	if x, err := Newton(f, guess, lo, hi, tol); err == nil {
+		return x, nil
+	}
+	best, found := 0.0, false
+	for _, r := range All(f, lo, hi, tol, n) {
+		if !found || math.Abs(r-guess) < math.Abs(best-guess) {
+			best, found = r, true
+		}
+	}
+	if !found {
+		return 0, ErrNoRoot
+	}
+	return best, nil
+}
+
+// All 把 [lo, hi] 分成 n 段，返回其中 f 的全部根，按从小到大排列
+// 变号的一段用 Bracket 求根；不变号但 |f| 在某处接近 0 时（如 x² 在 0 处）用黄金分割法查找极小值。
+// 变号处的函数值变大时是渐近线（如 tan），不算根
+func All(f Func, lo, hi, tol float64, n int) []float64 {
+	if !(lo < hi) || n < 1 {
+		return nil
+	}
+	xs := make([]float64, n+1)
+	ys := make([]float64, n+1)
+	oks := make([]bool, n+1)
+	for i := range xs {
+		xs[i] = lo + (hi-lo)*float64(i)/float64(n)
+		ys[i], oks[i] = f(xs[i])
+	}
+
+	var roots []float64
+	add := func(r float64) {
+		if len(roots) == 0 || r-roots[len(roots)-1] > (hi-lo)/float64(n)/2 {
+			roots = append(roots, r)
+		}
+	}
+	for i := range xs {
+		if !oks[i] {
+			continue
+		}
+		if ys[i] == 0 {
+			add(xs[i])
+			continue
+		}
+		if i+1 < len(xs) && oks[i+1] && ys[i+1] != 0 && (ys[i] < 0) != (ys[i+1] < 0) {
+			if r, err := Bracket(f, xs[i], xs[i+1], tol); err == nil {
+				if y, ok := f(r); ok && math.Abs(y) <= min(math.Abs(ys[i]), math.Abs(ys[i+1])) {
+					add(r)
+				}
+			}
+			continue
+		}
+		// |f| 在这一点比两侧都小且两侧同号：可能在附近碰到 0 而不变号
+		if i > 0 && i+1 < len(xs) && oks[i-1] && oks[i+1] &&
+			(ys[i-1] < 0) == (ys[i] < 0) && (ys[i+1] < 0) == (ys[i] < 0) &&
+			math.Abs(ys[i]) < math.Abs(ys[i-1]) && math.Abs(ys[i]) < math.Abs(ys[i+1]) {
+			r, y := minimizeAbs(f, xs[i-1], xs[i+1], tol)
+			scale := max(math.Abs(ys[i-1]), math.Abs(ys[i+1]))
+			if math.Abs(y) <= scale*1e-10 {
+				add(r)
+			}
+		}
+	}
+	return roots
+}
+
+// 用黄金分割法在 [lo, hi] 上查找 |f| 的极小值，返回极小值点和该点的函数值
+func minimizeAbs(f Func, lo, hi, tol float64) (float64, float64) {
+	g := func(x float64) float64 {
+		y, ok := f(x)
+		if !ok {
+			return math.Inf(1)
+		}
+		return math.Abs(y)
+	}
+	const r = 0.6180339887498949
+	c, d := hi-r*(hi-lo), lo+r*(hi-lo)
+	gc, gd := g(c), g(d)
+	for range maxIterations {
+		if hi-lo <= tol {
+			break
+		}
+		if gc < gd {
+			hi, d, gd = d, c, gc
+			c = hi - r*(hi-lo)
+			gc = g(c)
+		} else {
+			lo, c, gc = c, d, gd
+			d = lo + r*(hi-lo)
+			gd = g(d)
+		}
+	}
+	x := (lo + hi) / 2
+	y, _ := f(x)
+	return x, y
+}
@@ -0,0 +1,226 @@
+package solve
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"math/cmplx"
+	"testing"
+)
+
+// 比较浮点数，允许极小的误差
+func near(a, b, tol float64) bool {
+	return math.Abs(a-b) <= tol*math.Max(1, math.Abs(b))
+}
+
+func poly(x float64) Func {
+	return func(v float64) (float64, bool) { return v*v - x, true }
+}
+
+func TestNewton(t *testing.T) {
+	x, err := Newton(poly(2), 1, -10, 10, 1e-12)
+	if err != nil || !near(x, math.Sqrt2, 1e-12) {
+		t.Errorf("Expected √2, got %v %v", x, err)
+	}
+	// 导数为 0 的起点
+	if _, err := Newton(poly(2), 0, -10, 10, 1e-12); err == nil {
+		t.Error("Zero derivative should fail")
+	}
+	// 迭代点离开范围
+	if _, err := Newton(poly(2), 1, 0, 1.2, 1e-12); !errors.Is(err, ErrNoRoot) {
+		t.Errorf("Expected ErrNoRoot, got %v", err)
+	}
+}
+
+func TestBracket(t *testing.T) {
+	tests := []struct {
+		name     string
+		f        Func
+		a, b     float64
+		expected float64
+	}{
+		{"Square Root", poly(2), 0, 2, math.Sqrt2},
+		{"Reversed", poly(2), 2, 0, math.Sqrt2},
+		{"Cosine", func(x float64) (float64, bool) { return math.Cos(x) - x, true }, 0, 1, 0.7390851332151607},
+		{"Endpoint", poly(4), 2, 5, 2},
+		// 牛顿步很差（接近平坦）时仍靠二分收敛
+		{"Flat", func(x float64) (float64, bool) { return math.Cbrt(x - 1), true }, -3, 10, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Bracket(tt.f, tt.a, tt.b, 1e-13)
+			if err != nil || !near(got, tt.expected, 1e-12) {
+				t.Errorf("Expected: %v, Got: %v %v", tt.expected, got, err)
+			}
+		})
+	}
+	if _, err := Bracket(poly(2), 2, 3, 1e-12); !errors.Is(err, ErrNotBracket) {
+		t.Errorf("Expected ErrNotBracket, got %v", err)
+	}
+}
+
+func TestSolve(t *testing.T) {
+	// 从 -1 出发，牛顿法收敛到 -√2
+	x, err := Solve(poly(2), -1, -5, 5, 1e-12, 100)
+	if err != nil || !near(x, -math.Sqrt2, 1e-12) {
+		t.Errorf("Expected -√2, got %v %v", x, err)
+	}
+	// 起点导数为 0，改用分段查找离起点最近的根
+	x, err = Solve(poly(2), 0.1, 0, 5, 1e-12, 100)
+	if err != nil || !near(x, math.Sqrt2, 1e-12) {
+		t.Errorf("Expected √2, got %v %v", x, err)
+	}
+	if _, err := Solve(poly(-1), 0, -5, 5, 1e-12, 100); !errors.Is(err, ErrNoRoot) {
+		t.Errorf("Expected ErrNoRoot, got %v", err)
+	}
+	if _, err := Solve(poly(2), 0, 5, -5, 1e-12, 100); !errors.Is(err, ErrInvalidSpan) {
+		t.Errorf("Expected ErrInvalidSpan, got %v", err)
+	}
+}
+
+func TestAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		f        Func
+		lo, hi   float64
+		expected []float64
+	}{
+		{"Sine", func(x float64) (float64, bool) { return math.Sin(x), true }, -1, 7, []float64{0, math.Pi, 2 * math.Pi}},
+		{"Touch", func(x float64) (float64, bool) { return (x - 1) * (x - 1), true }, -3, 4, []float64{1}},
+		{"Cubic", func(x float64) (float64, bool) { return x*x*x - 2*x - 5, true }, -10, 10, []float64{2.0945514815423265}},
+		// tan 的渐近线不算根
+		{"Tangent", func(x float64) (float64, bool) { return math.Tan(x), true }, -2, 2, []float64{0}},
+		{"None", poly(-1), -10, 10, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := All(tt.f, tt.lo, tt.hi, 1e-12, 200)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected: %v, Got: %v", tt.expected, got)
+			}
+			for i := range got {
+				if !near(got[i], tt.expected[i], 1e-6) {
+					t.Errorf("Expected: %v, Got: %v", tt.expected, got)
+				}
+			}
+		})
+	}
+}
+
+// 用整数系数构造多项式，按升幂排列
+func ints(c ...int64) Polynomial {
+	p := make(Polynomial, len(c))
+	for i, v := range c {
+		p[i] = big.NewRat(v, 1)
+	}
+	return p
+}
+
+func TestPolynomialRoots(t *testing.T) {
+	tests := []struct {
+		name     string
+		p        Polynomial
+		expected []complex128
+		exact    []string // 有理数根的分数形式，无理数根和复数根为 ""
+	}{
+		{"Linear", ints(3, 4), []complex128{-0.75}, []string{"-3/4"}},
+		{"Rational Quadratic", ints(-3, 1, 2), []complex128{-1.5, 1}, []string{"-3/2", "1"}},
+		{"Irrational", ints(-2, 0, 1), []complex128{complex(-math.Sqrt2, 0), complex(math.Sqrt2, 0)}, []string{"", ""}},
+		{"Complex", ints(5, -2, 1), []complex128{complex(1, -2), complex(1, 2)}, []string{"", ""}},
+		{"Cubic", ints(-6, 11, -6, 1), []complex128{1, 2, 3}, []string{"1", "2", "3"}},
+		{"Double Root", ints(4, 0, -3, 1), []complex128{-1, 2, 2}, []string{"-1", "2", "2"}},
+		{"Zero Roots", ints(0, 0, -1, 1), []complex128{0, 0, 1}, []string{"0", "0", "1"}},
+		{"Quartic", ints(-4, 0, 3, 0, 1), []complex128{-1, 1, complex(0, -2), complex(0, 2)}, []string{"-1", "1", "", ""}},
+		{"Trailing Zeros", ints(-2, 0, 1, 0, 0), []complex128{complex(-math.Sqrt2, 0), complex(math.Sqrt2, 0)}, []string{"", ""}},
+		{"Quadruple", ints(1, -4, 6, -4, 1), []complex128{1, 1, 1, 1}, []string{"1", "1", "1", "1"}},
+		// 根为 ±√2 ± √3
+		{"Irrational Quartic", ints(1, 0, -10, 0, 1), []complex128{
+			complex(-math.Sqrt2-math.Sqrt(3), 0), complex(math.Sqrt2-math.Sqrt(3), 0),
+			complex(math.Sqrt(3)-math.Sqrt2, 0), complex(math.Sqrt2+math.Sqrt(3), 0),
+		}, []string{"", "", "", ""}},
+		// (3x-1)²(x-2)，二重根的近似值只有大约一半的有效数字
+		{"Rational Double Root", ints(-2, 13, -24, 9), []complex128{1.0 / 3, 1.0 / 3, 2}, []string{"1/3", "1/3", "2"}},
+		// (4x-3)³(x-2)，三重根的近似值只有大约三分之一的有效数字
+		{"Rational Triple Root", ints(54, -243, 396, -272, 64), []complex128{0.75, 0.75, 0.75, 2}, []string{"3/4", "3/4", "3/4", "2"}},
+		// 常数项超过 2⁴⁰ 时不列出候选根，从近似根查找
+		{"Large Coefficients", ints(-1<<40, 1<<41), []complex128{0.5}, []string{"1/2"}},
+		{"Fractions", Polynomial{big.NewRat(1, 6), big.NewRat(-5, 6), big.NewRat(1, 1)}, []complex128{1.0 / 3, 0.5}, []string{"1/3", "1/2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.p.Roots()
+			if err != nil {
+				t.Fatalf("Roots: %v", err)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected: %v, Got: %v", tt.expected, got)
+			}
+			for i, r := range got {
+				if cmplx.Abs(r.Value-tt.expected[i]) > 1e-9 {
+					t.Errorf("Root %d: Expected: %v, Got: %v", i, tt.expected[i], r.Value)
+				}
+				exact := ""
+				if r.Exact != nil {
+					exact = r.Exact.RatString()
+				}
+				if exact != tt.exact[i] {
+					t.Errorf("Root %d: Expected exact %q, Got: %q", i, tt.exact[i], exact)
+				}
+			}
+		})
+	}
+
+	for _, p := range []Polynomial{nil, ints(5), ints(1, 1, 1, 1, 1, 1)} {
+		if _, err := p.Roots(); !errors.Is(err, ErrDegree) {
+			t.Errorf("%v: expected ErrDegree, got %v", p, err)
+		}
+	}
+}
+
+func TestLinear(t *testing.T) {
+	rats := func(rows ...[]int64) [][]*big.Rat {
+		m := make([][]*big.Rat, len(rows))
+		for i, row := range rows {
+			m[i] = ints(row...)
+		}
+		return m
+	}
+	tests := []struct {
+		name     string
+		a        [][]*big.Rat
+		b        []*big.Rat
+		expected []string
+		err      error
+	}{
+		{"Single", rats([]int64{3}), ints(2), []string{"2/3"}, nil},
+		{"Two", rats([]int64{2, 1}, []int64{1, -1}), ints(5, 1), []string{"2", "1"}, nil},
+		{"Fractions", rats([]int64{1, 1}, []int64{1, -2}), ints(1, 0), []string{"2/3", "1/3"}, nil},
+		// 第一个主元为 0，需要换行
+		{"Pivot", rats([]int64{0, 1, 1}, []int64{1, 0, 1}, []int64{1, 1, 0}), ints(5, 4, 3), []string{"1", "2", "3"}, nil},
+		{"Four", rats(
+			[]int64{1, 1, 1, 1},
+			[]int64{1, 2, 3, 4},
+			[]int64{1, 4, 9, 16},
+			[]int64{1, 8, 27, 64},
+		), ints(0, 1, 0, 0), []string{"-13/3", "19/2", "-7", "11/6"}, nil},
+		{"No Solution", rats([]int64{1, 1}, []int64{2, 2}), ints(1, 3), nil, ErrNoSolution},
+		{"Infinite", rats([]int64{1, 1}, []int64{2, 2}), ints(1, 2), nil, ErrInfinite},
+		{"Size", rats([]int64{1, 1}), ints(1), nil, ErrSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Linear(tt.a, tt.b)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected: %v, Got: %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i].RatString() != tt.expected[i] {
+					t.Errorf("x%d: Expected: %s, Got: %s", i+1, tt.expected[i], got[i].RatString())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+// Package split 计算账单或分数的平摊：按份数分摊，可以加上小费、税和服务费，并按指定的单位取整。
+// 金额在内部以“分”为单位的整数计算，各人应付之和与合计一致（按 0.5 或整数取整时最多多收不到一个取整单位）
+package split
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Rounding 是每人应付金额的取整单位
+type Rounding int
+
+const (
+	RoundCent  Rounding = iota // 精确到分
+	RoundHalf                  // 精确到 0.5
+	RoundWhole                 // 取整数
+)
+
+// Roundings 是全部取整方式，按界面上的显示顺序排列
+var Roundings = []Rounding{RoundCent, RoundHalf, RoundWhole}
+
+// 取整单位，以分为单位
+func (r Rounding) step() int64 {
+	switch r {
+	case RoundHalf:
+		return 50
+	case RoundWhole:
+		return 100
+	}
+	return 1
+}
+
+// String 返回取整方式的名称
+func (r Rounding) String() string {
+	switch r {
+	case RoundHalf:
+		return "精确到 0.5"
+	case RoundWhole:
+		return "取整数"
+	}
+	return "精确到分"
+}
+
+// Format 按取整方式显示以分为单位的金额：精确到分时保留两位小数，否则省略末尾的 0
+func (r Rounding) Format(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	s := fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+	if r != RoundCent {
+		s = strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// Participant 是一位参与平摊的人
+type Participant struct {
+	Name   string  // 名字，为空时显示为“第 N 人”
+	Weight float64 // 份数，如 2 表示承担两份，0 表示不分摊
+}
+
+// Bill 是一次平摊的输入
+type Bill struct {
+	Amount       float64       // 账单金额（或总分）
+	Tip          float64       // 小费，账单金额的百分比
+	Tax          float64       // 税，账单金额的百分比
+	Service      float64       // 服务费，账单金额的百分比
+	Rounding     Rounding      // 每人应付金额的取整单位
+	Participants []Participant // 参与平摊的人
+}
+
+// Share 是一个人应付的金额
+type Share struct {
+	Participant
+	Amount int64 // 应付金额，以分为单位
+}
+
+// Result 是平摊的结果，金额均以分为单位
+type Result struct {
+	Bill      Bill
+	Amount    int64   // 账单金额
+	Tip       int64   // 小费
+	Tax       int64   // 税
+	Service   int64   // 服务费
+	Total     int64   // 合计：账单金额加上小费、税和服务费
+	Shares    []Share // 每人应付的金额，与 Bill.Participants 一一对应
+	Collected int64   // 各人应付之和，按 0.5 或整数取整时可能略多于合计
+}
+
+// 以分为单位的金额上限：换算为 int64 时不会溢出，账单金额、小费、税和服务费相加也不会溢出
+const maxCents = 9e16
+
+// 金额换算为分，超出上限时返回错误
+func toCents(v float64) (int64, error) {
+	c := math.Round(v * 100)
+	if !(math.Abs(c) < maxCents) {
+		return 0, errors.New("金额太大，无法平摊")
+	}
+	return int64(c), nil
+}
+
+// Split 计算平摊结果
+// 每人先按份数分得合计的相应比例，向下取整到取整单位，剩余的部分按舍去的多少依次每人补一个取整单位
+func (b Bill) Split() (Result, error) {
+	if !(b.Amount > 0) || math.IsInf(b.Amount, 0) {
+		return Result{}, errors.New("金额必须大于 0")
+	}
+	for _, v := range []float64{b.Tip, b.Tax, b.Service} {
+		if !(v >= 0) || math.IsInf(v, 0) { // !(v >= 0) 同时排除 NaN
+			return Result{}, errors.New("小费、税和服务费必须是有限的非负数")
+		}
+	}
+	if len(b.Participants) == 0 {
+		return Result{}, errors.New("请输入人数")
+	}
+	var weights float64
+	for _, p := range b.Participants {
+		if p.Weight < 0 || math.IsInf(p.Weight, 0) || math.IsNaN(p.Weight) {
+			return Result{}, errors.New("份数不能为负数")
+		}
+		weights += p.Weight
+	}
+	if weights == 0 {
+		return Result{}, errors.New("至少要有一人的份数大于 0")
+	}
+
+	r := Result{Bill: b}
+	for _, c := range []struct {
+		cents *int64
+		value float64
+	}{
+		{&r.Amount, b.Amount},
+		{&r.Tip, b.Amount * b.Tip / 100},
+		{&r.Tax, b.Amount * b.Tax / 100},
+		{&r.Service, b.Amount * b.Service / 100},
+	} {
+		var err error
+		if *c.cents, err = toCents(c.value); err != nil {
+			return Result{}, err
+		}
+	}
+	r.Total = r.Amount + r.Tip + r.Tax + r.Service
+
+	step := b.Rounding.step()
+	r.Shares = make([]Share, len(b.Participants))
+	remainders := make([]float64, len(b.Participants))
+	var left int64 = r.Total
+	for i, p := range b.Participants {
+		quota := float64(r.Total) * p.Weight / weights
+		units := int64(math.Floor(quota/float64(step) + 1e-9))
+		r.Shares[i] = Share{Participant: p, Amount: units * step}
+		remainders[i] = quota - float64(units*step)
+		left -= units * step
+	}
+
+	// 舍去最多的人先补，相同时按顺序；只给份数大于 0 的人补
+	order := make([]int, 0, len(b.Participants))
+	for i, p := range b.Participants {
+		if p.Weight > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]+1e-9
+	})
+	for _, i := range order {
+		if left <= 0 {
+			break
+		}
+		r.Shares[i].Amount += step
+		left -= step
+	}
+
+	for _, s := range r.Shares {
+		r.Collected += s.Amount
+	}
+	return r, nil
+}
+
+// Name 返回第 i 个人的名字，没有名字时为“第 N 人”
+func (r Result) Name(i int) string {
+	if name := strings.TrimSpace(r.Shares[i].Name); name != "" {
+		return name
+	}
+	return "第 " + strconv.Itoa(i+1) + " 人"
+}
+
+// 是否所有人都没有名字且份数相同，这时按金额合并显示
+func (r Result) anonymous() bool {
+	for _, s := range r.Shares {
+		if strings.TrimSpace(s.Name) != "" || s.Weight != r.Shares[0].Weight {
+			return false
+		}
+	}
+	return true
+}
+
+// Summary 返回一行摘要，如 “合计 100 | 1人34, 2人33” 或 “合计 116.00 | 张三 58.00, 李四 58.00”
+func (r Result) Summary() string {
+	return "合计 " + r.Bill.Rounding.Format(r.Total) + " | " + r.ShareText()
+}
+
+// ShareText 返回每人应付的金额；都没有名字且份数相同时按金额合并，如 “1人34, 2人33”
+func (r Result) ShareText() string {
+	f := r.Bill.Rounding.Format
+	var parts []string
+	if r.anonymous() {
+		// 金额从高到低，每种金额的人数
+		counts := map[int64]int{}
+		var amounts []int64
+		for _, s := range r.Shares {
+			if counts[s.Amount] == 0 {
+				amounts = append(amounts, s.Amount)
+			}
+			counts[s.Amount]++
+		}
+		sort.Slice(amounts, func(i, j int) bool { return amounts[i] > amounts[j] })
+		for _, a := range amounts {
+			parts = append(parts, fmt.Sprintf("%d人%s", counts[a], f(a)))
+		}
+	} else {
+		for i, s := range r.Shares {
+			parts = append(parts, r.Name(i)+" "+f(s.Amount))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Breakdown 返回多行的明细：账单金额、各项附加费用、合计和每人应付
+func (r Result) Breakdown() string {
+	f := r.Bill.Rounding.Format
+	percent := func(p float64) string {
+		return strconv.FormatFloat(p, 'f', -1, 64) + "%"
+	}
+	lines := []string{"金额 " + f(r.Amount)}
+	if r.Tip != 0 {
+		lines = append(lines, "小费 "+percent(r.Bill.Tip)+" "+f(r.Tip))
+	}
+	if r.Tax != 0 {
+		lines = append(lines, "税 "+percent(r.Bill.Tax)+" "+f(r.Tax))
+	}
+	if r.Service != 0 {
+		lines = append(lines, "服务费 "+percent(r.Bill.Service)+" "+f(r.Service))
+	}
+	lines = append(lines, "合计 "+f(r.Total))
+	for i, s := range r.Shares {
+		line := r.Name(i)
+		if s.Weight != 1 {
+			line += " ×" + strconv.FormatFloat(s.Weight, 'f', -1, 64)
+		}
+		lines = append(lines, line+" "+f(s.Amount))
+	}
+	if extra := r.Collected - r.Total; extra > 0 {
+		lines = append(lines, "因取整多收 "+RoundCent.Format(extra))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,94 @@
+package split
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// n 个没有名字、各占一份的人
+func people(n int) []Participant {
+	ps := make([]Participant, n)
+	for i := range ps {
+		ps[i].Weight = 1
+	}
+	return ps
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name      string
+		bill      Bill
+		total     int64
+		shares    []int64
+		collected int64
+	}{
+		{"Even", Bill{Amount: 90, Rounding: RoundWhole, Participants: people(3)}, 9000, []int64{3000, 3000, 3000}, 9000},
+		{"Integer Remainder", Bill{Amount: 100, Rounding: RoundWhole, Participants: people(3)}, 10000, []int64{3400, 3300, 3300}, 10000},
+		{"Cents", Bill{Amount: 100, Participants: people(3)}, 10000, []int64{3334, 3333, 3333}, 10000},
+		{"Half", Bill{Amount: 10, Rounding: RoundHalf, Participants: people(3)}, 1000, []int64{350, 350, 300}, 1000},
+		{"Rounding Up", Bill{Amount: 100.3, Rounding: RoundWhole, Participants: people(2)}, 10030, []int64{5100, 5000}, 10100},
+		{"Weights", Bill{Amount: 120, Participants: []Participant{{"张三", 2}, {"李四", 1}, {"王五", 1}}}, 12000, []int64{6000, 3000, 3000}, 12000},
+		{"Zero Weight", Bill{Amount: 10, Participants: []Participant{{"", 1}, {"", 0}, {"", 1}}}, 1000, []int64{500, 0, 500}, 1000},
+		{"Extras", Bill{Amount: 100, Tip: 10, Tax: 6, Service: 5, Participants: people(2)}, 12100, []int64{6050, 6050}, 12100},
+		{"Decimal Amount", Bill{Amount: 33.33, Participants: people(2)}, 3333, []int64{1667, 1666}, 3333},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := tt.bill.Split()
+			if err != nil {
+				t.Fatalf("Split: %v", err)
+			}
+			var shares []int64
+			for _, s := range r.Shares {
+				shares = append(shares, s.Amount)
+			}
+			if r.Total != tt.total || !reflect.DeepEqual(shares, tt.shares) || r.Collected != tt.collected {
+				t.Errorf("Expected total %d shares %v collected %d, got %d %v %d", tt.total, tt.shares, tt.collected, r.Total, shares, r.Collected)
+			}
+		})
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	bills := map[string]Bill{
+		"Zero Amount":     {Amount: 0, Participants: people(2)},
+		"No Participants": {Amount: 10},
+		"Negative Weight": {Amount: 10, Participants: []Participant{{"", -1}}},
+		"All Zero Weight": {Amount: 10, Participants: []Participant{{"", 0}, {"", 0}}},
+		"Negative Tip":    {Amount: 10, Tip: -5, Participants: people(2)},
+		"NaN Tip":         {Amount: 10, Tip: math.NaN(), Participants: people(2)},
+		"Infinite Tax":    {Amount: 10, Tax: math.Inf(1), Participants: people(2)},
+		"NaN Service":     {Amount: 10, Service: math.NaN(), Participants: people(2)},
+		"NaN Amount":      {Amount: math.NaN(), Participants: people(2)},
+		"Huge Amount":     {Amount: 1e300, Participants: people(2)},
+		"Huge Tip":        {Amount: 1e12, Tip: 1e10, Participants: people(2)},
+	}
+	for name, b := range bills {
+		if _, err := b.Split(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	r, _ := Bill{Amount: 100, Rounding: RoundWhole, Participants: people(3)}.Split()
+	if got := r.Summary(); got != "合计 100 | 1人34, 2人33" {
+		t.Errorf("Anonymous summary: got %q", got)
+	}
+
+	r, _ = Bill{Amount: 100, Tip: 10, Participants: []Participant{{"张三", 2}, {"", 1}}}.Split()
+	if got := r.Summary(); got != "合计 110.00 | 张三 73.33, 第 2 人 36.67" {
+		t.Errorf("Named summary: got %q", got)
+	}
+	want := "金额 100.00\n小费 10% 10.00\n合计 110.00\n张三 ×2 73.33\n第 2 人 36.67"
+	if got := r.Breakdown(); got != want {
+		t.Errorf("Breakdown: got %q", got)
+	}
+
+	r, _ = Bill{Amount: 100.3, Rounding: RoundHalf, Participants: people(3)}.Split()
+	if got := r.Breakdown(); got != "金额 100.3\n合计 100.3\n第 1 人 33.5\n第 2 人 33.5\n第 3 人 33.5\n因取整多收 0.20" {
+		t.Errorf("Breakdown with surplus: got %q", got)
+	}
+}
@@ -0,0 +1,158 @@
+// Package stats 计算一组数据的描述统计量，以及成对数据的线性回归和相关系数
+package stats
+
+import (
+	"errors"
+	"math"
+	"slices"
+)
+
+// Summary 是一组数据的描述统计量
+type Summary struct {
+	Count  int
+	Sum    float64
+	Mean   float64
+	Median float64
+	Modes  []float64 // 出现次数最多的值，从小到大；每个值都只出现一次时为空
+	Min    float64
+	Max    float64
+	Range  float64 // 极差：最大值减最小值
+
+	PopVariance    float64 // 总体方差，除以 n
+	PopStdDev      float64 // 总体标准差
+	SampleVariance float64 // 样本方差，除以 n-1；只有一个数据时为 NaN
+	SampleStdDev   float64 // 样本标准差；只有一个数据时为 NaN
+
+	Q1  float64 // 下四分位数
+	Q3  float64 // 上四分位数
+	IQR float64 // 四分位距：Q3 - Q1
+}
+
+// Describe 计算描述统计量，data 的顺序不变
+// 四分位数按常见计算器的做法取上下两半的中位数，数据个数为奇数时两半都不含中位数
+func Describe(data []float64) (Summary, error) {
+	n := len(data)
+	if n == 0 {
+		return Summary{}, errors.New("没有数据")
+	}
+	for _, v := range data {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return Summary{}, errors.New("数据中有无效的值")
+		}
+	}
+
+	sorted := slices.Clone(data)
+	slices.Sort(sorted)
+
+	s := Summary{Count: n, Min: sorted[0], Max: sorted[n-1]}
+	s.Range = s.Max - s.Min
+	for _, v := range data {
+		s.Sum += v
+	}
+	s.Mean = s.Sum / float64(n)
+
+	// 先求平均数再求离差平方和，避免数据较大时的抵消误差
+	var ss float64
+	for _, v := range data {
+		ss += (v - s.Mean) * (v - s.Mean)
+	}
+	s.PopVariance = ss / float64(n)
+	s.PopStdDev = math.Sqrt(s.PopVariance)
+	s.SampleVariance, s.SampleStdDev = math.NaN(), math.NaN()
+	if n > 1 {
+		s.SampleVariance = ss / float64(n-1)
+		s.SampleStdDev = math.Sqrt(s.SampleVariance)
+	}
+
+	s.Median = median(sorted)
+	if n == 1 {
+		s.Q1, s.Q3 = sorted[0], sorted[0]
+	} else {
+		s.Q1 = median(sorted[:n/2])
+		s.Q3 = median(sorted[(n+1)/2:])
+	}
+	s.IQR = s.Q3 - s.Q1
+	s.Modes = modes(sorted)
+	return s, nil
+}
+
+// 已排序数据的中位数
+func median(sorted []float64) float64 {
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// 已排序数据中出现次数最多的值
+func modes(sorted []float64) []float64 {
+	var result []float64
+	best := 1
+	for i := 0; i < len(sorted); {
+		j := i
+		for j < len(sorted) && sorted[j] == sorted[i] {
+			j++
+		}
+		switch count := j - i; {
+		case count > best:
+			best = count
+			result = []float64{sorted[i]}
+		case count == best && best > 1:
+			result = append(result, sorted[i])
+		}
+		i = j
+	}
+	return result
+}
+
+// Regression 是成对数据 (x, y) 的最小二乘直线 y = Intercept + Slope·x
+type Regression struct {
+	Slope     float64
+	Intercept float64
+	R         float64 // 相关系数；y 全部相同时为 NaN
+	R2        float64 // 决定系数 r²；y 全部相同时为 NaN
+}
+
+// Fit 对成对数据做线性回归
+func Fit(xs, ys []float64) (Regression, error) {
+	if len(xs) != len(ys) {
+		return Regression{}, errors.New("x 与 y 的个数不同")
+	}
+	if len(xs) < 2 {
+		return Regression{}, errors.New("至少需要两组数据")
+	}
+	x, err := Describe(xs)
+	if err != nil {
+		return Regression{}, err
+	}
+	y, err := Describe(ys)
+	if err != nil {
+		return Regression{}, err
+	}
+
+	var sxx, syy, sxy float64
+	for i := range xs {
+		dx, dy := xs[i]-x.Mean, ys[i]-y.Mean
+		sxx += dx * dx
+		syy += dy * dy
+		sxy += dx * dy
+	}
+	if sxx == 0 {
+		return Regression{}, errors.New("x 的值全部相同，无法回归")
+	}
+
+	r := Regression{Slope: sxy / sxx}
+	r.Intercept = y.Mean - r.Slope*x.Mean
+	r.R, r.R2 = math.NaN(), math.NaN()
+	if syy > 0 {
+		r.R = max(-1, min(1, sxy/math.Sqrt(sxx*syy)))
+		r.R2 = r.R * r.R
+	}
+	return r, nil
+}
+
+// Predict 返回回归直线在 x 处的值
+func (r Regression) Predict(x float64) float64 {
+	return r.Intercept + r.Slope*x
+}
@@ -0,0 +1,118 @@
+package stats
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// 比较浮点数，允许极小的舍入误差
+func near(a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []float64
+		expected Summary
+	}{
+		{"Odd", []float64{7, 1, 3, 9, 5}, Summary{
+			Count: 5, Sum: 25, Mean: 5, Median: 5, Min: 1, Max: 9, Range: 8,
+			PopVariance: 8, PopStdDev: math.Sqrt(8), SampleVariance: 10, SampleStdDev: math.Sqrt(10),
+			Q1: 2, Q3: 8, IQR: 6,
+		}},
+		{"Even", []float64{2, 4, 4, 4, 5, 5, 7, 9}, Summary{
+			Count: 8, Sum: 40, Mean: 5, Median: 4.5, Modes: []float64{4}, Min: 2, Max: 9, Range: 7,
+			PopVariance: 4, PopStdDev: 2, SampleVariance: 32.0 / 7, SampleStdDev: math.Sqrt(32.0 / 7),
+			Q1: 4, Q3: 6, IQR: 2,
+		}},
+		{"Two Modes", []float64{3, 1, 3, 1, 2}, Summary{
+			Count: 5, Sum: 10, Mean: 2, Median: 2, Modes: []float64{1, 3}, Min: 1, Max: 3, Range: 2,
+			PopVariance: 0.8, PopStdDev: math.Sqrt(0.8), SampleVariance: 1, SampleStdDev: 1,
+			Q1: 1, Q3: 3, IQR: 2,
+		}},
+		{"Single", []float64{-2.5}, Summary{
+			Count: 1, Sum: -2.5, Mean: -2.5, Median: -2.5, Min: -2.5, Max: -2.5,
+			SampleVariance: math.NaN(), SampleStdDev: math.NaN(), Q1: -2.5, Q3: -2.5,
+		}},
+		// 数值很大而离差很小时，方差仍然准确
+		{"Large Offset", []float64{1e9 + 4, 1e9 + 7, 1e9 + 13, 1e9 + 16}, Summary{
+			Count: 4, Sum: 4e9 + 40, Mean: 1e9 + 10, Median: 1e9 + 10, Min: 1e9 + 4, Max: 1e9 + 16, Range: 12,
+			PopVariance: 22.5, PopStdDev: math.Sqrt(22.5), SampleVariance: 30, SampleStdDev: math.Sqrt(30),
+			Q1: 1e9 + 5.5, Q3: 1e9 + 14.5, IQR: 9,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Describe(tt.data)
+			if err != nil {
+				t.Fatalf("Describe: %v", err)
+			}
+			e := tt.expected
+			pairs := map[string][2]float64{
+				"Sum": {got.Sum, e.Sum}, "Mean": {got.Mean, e.Mean}, "Median": {got.Median, e.Median},
+				"Min": {got.Min, e.Min}, "Max": {got.Max, e.Max}, "Range": {got.Range, e.Range},
+				"PopVariance": {got.PopVariance, e.PopVariance}, "PopStdDev": {got.PopStdDev, e.PopStdDev},
+				"SampleVariance": {got.SampleVariance, e.SampleVariance}, "SampleStdDev": {got.SampleStdDev, e.SampleStdDev},
+				"Q1": {got.Q1, e.Q1}, "Q3": {got.Q3, e.Q3}, "IQR": {got.IQR, e.IQR},
+			}
+			for name, p := range pairs {
+				if !near(p[0], p[1]) {
+					t.Errorf("%s: Expected: %v, Got: %v", name, p[1], p[0])
+				}
+			}
+			if got.Count != e.Count || !reflect.DeepEqual(got.Modes, e.Modes) {
+				t.Errorf("Expected count %d modes %v, got %d %v", e.Count, e.Modes, got.Count, got.Modes)
+			}
+		})
+	}
+
+	if _, err := Describe(nil); err == nil {
+		t.Error("Empty data should fail")
+	}
+	if _, err := Describe([]float64{1, math.Inf(1)}); err == nil {
+		t.Error("Infinite value should fail")
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		name      string
+		xs, ys    []float64
+		slope     float64
+		intercept float64
+		r         float64
+	}{
+		{"Exact Line", []float64{1, 2, 3, 4}, []float64{3, 5, 7, 9}, 2, 1, 1},
+		{"Negative", []float64{0, 1, 2}, []float64{4, 2, 0}, -2, 4, -1},
+		{"Scatter", []float64{1, 2, 3, 4, 5}, []float64{2, 4, 5, 4, 5}, 0.6, 2.2, 6 / math.Sqrt(60)},
+		{"Flat", []float64{1, 2, 3}, []float64{5, 5, 5}, 0, 5, math.NaN()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Fit(tt.xs, tt.ys)
+			if err != nil {
+				t.Fatalf("Fit: %v", err)
+			}
+			if !near(got.Slope, tt.slope) || !near(got.Intercept, tt.intercept) || !near(got.R, tt.r) {
+				t.Errorf("Expected slope %v intercept %v r %v, got %+v", tt.slope, tt.intercept, tt.r, got)
+			}
+		})
+	}
+
+	errs := map[string][2][]float64{
+		"Length Mismatch": {{1, 2}, {1}},
+		"Too Few":         {{1}, {1}},
+		"Vertical":        {{2, 2, 2}, {1, 2, 3}},
+	}
+	for name, d := range errs {
+		if _, err := Fit(d[0], d[1]); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
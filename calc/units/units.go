@@ -0,0 +1,173 @@
+// Package units 提供数据驱动的单位换算，单位定义来自 JSON，新增单位不需要修改代码
+package units
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+//go:embed units.json
+var defaultData []byte
+
+// Version 是当前支持的单位定义文件格式版本
+const Version = 1
+
+// Unit 是一个单位
+// 换算到本类别基准单位的公式为：基准值 = (数值 + Offset) × Factor
+// 大多数单位 Offset 为 0，只有温度这类非比例单位需要偏移量
+type Unit struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Symbol string  `json:"symbol"`
+	Factor float64 `json:"factor"`
+	Offset float64 `json:"offset,omitempty"`
+}
+
+// Category 是一组可以互相换算的单位，如长度、质量
+type Category struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Units []Unit `json:"units"`
+}
+
+// Catalog 是全部单位定义
+type Catalog struct {
+	Version    int        `json:"version"`
+	Categories []Category `json:"categories"`
+}
+
+// Default 返回内置的单位定义
+func Default() *Catalog {
+	c, err := Parse(defaultData)
+	if err != nil {
+		panic("units: invalid built-in definitions: " + err.Error())
+	}
+	return c
+}
+
+// Parse 解析 JSON 格式的单位定义，并用 Validate 检查
+func Parse(data []byte) (*Catalog, error) {
+	var c Catalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	if c.Version > Version {
+		return nil, fmt.Errorf("unsupported units version %d", c.Version)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Validate 检查每个类别都有 ID 和至少一个单位，每个单位都有 ID、非 0 的有限系数和有限的偏移量。
+// 换算界面按名称查找类别、按 Label 查找单位，因此类别的 ID 和名称、同一类别中单位的 ID 和 Label 都不能重复
+func (c *Catalog) Validate() error {
+	catIDs := map[string]bool{}
+	catNames := map[string]bool{}
+	for _, cat := range c.Categories {
+		if cat.ID == "" {
+			return errors.New("category without id")
+		}
+		if catIDs[cat.ID] {
+			return fmt.Errorf("duplicate category id %q", cat.ID)
+		}
+		if catNames[cat.Name] {
+			return fmt.Errorf("duplicate category name %q", cat.Name)
+		}
+		catIDs[cat.ID], catNames[cat.Name] = true, true
+		if len(cat.Units) == 0 {
+			return fmt.Errorf("category %q has no units", cat.ID)
+		}
+
+		unitIDs := map[string]bool{}
+		labels := map[string]bool{}
+		for _, u := range cat.Units {
+			if u.ID == "" || u.Factor == 0 || !finite(u.Factor) || !finite(u.Offset) {
+				return fmt.Errorf("invalid unit %q in category %q", u.ID, cat.ID)
+			}
+			if unitIDs[u.ID] {
+				return fmt.Errorf("duplicate unit id %q in category %q", u.ID, cat.ID)
+			}
+			if labels[u.Label()] {
+				return fmt.Errorf("duplicate unit %q in category %q", u.Label(), cat.ID)
+			}
+			unitIDs[u.ID], labels[u.Label()] = true, true
+		}
+	}
+	return nil
+}
+
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// Merge 把另一份定义合并进来：同 ID 的类别追加或替换其中的单位，新类别追加在末尾
+func (c *Catalog) Merge(other *Catalog) {
+	for _, oc := range other.Categories {
+		cat := c.Category(oc.ID)
+		if cat == nil {
+			c.Categories = append(c.Categories, oc)
+			continue
+		}
+		if oc.Name != "" {
+			cat.Name = oc.Name
+		}
+		for _, u := range oc.Units {
+			if existing := cat.Unit(u.ID); existing != nil {
+				*existing = u
+			} else {
+				cat.Units = append(cat.Units, u)
+			}
+		}
+	}
+}
+
+// Category 按 ID 查找类别，找不到时返回 nil
+func (c *Catalog) Category(id string) *Category {
+	for i := range c.Categories {
+		if c.Categories[i].ID == id {
+			return &c.Categories[i]
+		}
+	}
+	return nil
+}
+
+// Unit 按 ID 查找单位，找不到时返回 nil
+func (cat *Category) Unit(id string) *Unit {
+	for i := range cat.Units {
+		if cat.Units[i].ID == id {
+			return &cat.Units[i]
+		}
+	}
+	return nil
+}
+
+// Label 是单位在换算界面中显示的文字：名称和符号，符号为空或与名称相同时只显示名称
+func (u Unit) Label() string {
+	if u.Symbol == "" || u.Symbol == u.Name {
+		return u.Name
+	}
+	return u.Name + " (" + u.Symbol + ")"
+}
+
+// ToBase 把数值换算为基准单位
+func (u Unit) ToBase(v float64) float64 {
+	return (v + u.Offset) * u.Factor
+}
+
+// FromBase 把基准单位的数值换算为本单位
+func (u Unit) FromBase(v float64) float64 {
+	return v/u.Factor - u.Offset
+}
+
+// Convert 把 from 单位的数值换算为 to 单位
+func Convert(v float64, from, to Unit) float64 {
+	if from == to {
+		return v
+	}
+	return to.FromBase(from.ToBase(v))
+}
@@ -0,0 +1,138 @@
+package units
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	c := Default()
+
+	tests := []struct {
+		name     string
+		category string
+		from, to string
+		input    float64
+		expected float64
+	}{
+		{"Km To Mile", "length", "km", "mi", 1.609344, 1},
+		{"Inch To Cm", "length", "in", "cm", 1, 2.54},
+		{"Mu To M2", "area", "mu", "m2", 15, 10000},
+		{"Gallon To Liter", "volume", "gal", "l", 1, 3.785411784},
+		{"Jin To Kg", "mass", "jin", "kg", 3, 1.5},
+		{"Boiling F To C", "temperature", "f", "c", 212, 100},
+		{"Freezing C To F", "temperature", "c", "f", 0, 32},
+		{"Zero K To C", "temperature", "k", "c", 0, -273.15},
+		{"Kmh To Ms", "speed", "kmh", "mps", 36, 10},
+		{"Atm To KPa", "pressure", "atm", "kpa", 1, 101.325},
+		{"KWh To MJ", "energy", "kwh", "kj", 1, 3600},
+		{"GiB To MiB", "data", "gib", "mib", 1, 1024},
+		{"Day To Hour", "time", "d", "h", 1, 24},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cat := c.Category(tt.category)
+			if cat == nil {
+				t.Fatalf("category %s not found", tt.category)
+			}
+			from, to := cat.Unit(tt.from), cat.Unit(tt.to)
+			if from == nil || to == nil {
+				t.Fatalf("unit %s or %s not found", tt.from, tt.to)
+			}
+			got := Convert(tt.input, *from, *to)
+			if math.Abs(got-tt.expected) > 1e-9*math.Max(1, math.Abs(tt.expected)) {
+				t.Errorf("%g %s -> %s: Expected: %g, Got: %g", tt.input, tt.from, tt.to, tt.expected, got)
+			}
+			// 反向换算应当还原
+			if back := Convert(got, *to, *from); math.Abs(back-tt.input) > 1e-9*math.Max(1, math.Abs(tt.input)) {
+				t.Errorf("%s -> %s round trip: Expected: %g, Got: %g", tt.to, tt.from, tt.input, back)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	c := Default()
+	extra, err := Parse([]byte(`{"version":1,"categories":[
+		{"id":"length","units":[{"id":"zhang","name":"丈","symbol":"丈","factor":3.3333333333333335}]},
+		{"id":"angle","name":"角度","units":[{"id":"deg","name":"度","factor":1},{"id":"rad","name":"弧度","factor":57.29577951308232}]}
+	]}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	c.Merge(extra)
+
+	if c.Category("length").Unit("zhang") == nil || c.Category("length").Name != "长度" {
+		t.Errorf("Merge into existing category failed")
+	}
+	if c.Category("angle") == nil {
+		t.Errorf("Merge new category failed")
+	}
+
+	if _, err := Parse([]byte(`{"version":1,"categories":[{"id":"x","units":[{"id":"bad","factor":0}]}]}`)); err == nil {
+		t.Errorf("Parse should reject zero factor")
+	}
+	// 没有单位的类别在换算界面中无法选择单位
+	if _, err := Parse([]byte(`{"version":1,"categories":[{"id":"x","name":"X","units":[]}]}`)); err == nil {
+		t.Errorf("Parse should reject a category without units")
+	}
+	c.Merge(&Catalog{Categories: []Category{{ID: "empty"}}})
+	if err := c.Validate(); err == nil {
+		t.Errorf("Validate should reject a merged category without units")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	unit := func(id, name string, factor float64) Unit { return Unit{ID: id, Name: name, Factor: factor} }
+	tests := []struct {
+		name       string
+		categories []Category
+	}{
+		{"NaN Factor", []Category{{ID: "x", Name: "X", Units: []Unit{unit("a", "A", math.NaN())}}}},
+		{"Infinite Factor", []Category{{ID: "x", Name: "X", Units: []Unit{unit("a", "A", math.Inf(1))}}}},
+		{"Infinite Offset", []Category{{ID: "x", Name: "X", Units: []Unit{{ID: "a", Name: "A", Factor: 1, Offset: math.Inf(-1)}}}}},
+		{"Duplicate Unit ID", []Category{{ID: "x", Name: "X", Units: []Unit{unit("a", "A", 1), unit("a", "B", 2)}}}},
+		{"Duplicate Label", []Category{{ID: "x", Name: "X", Units: []Unit{unit("a", "A", 1), unit("b", "A", 2)}}}},
+		{"Duplicate Category ID", []Category{
+			{ID: "x", Name: "X", Units: []Unit{unit("a", "A", 1)}},
+			{ID: "x", Name: "Y", Units: []Unit{unit("a", "A", 1)}},
+		}},
+		{"Duplicate Category Name", []Category{
+			{ID: "x", Name: "X", Units: []Unit{unit("a", "A", 1)}},
+			{ID: "y", Name: "X", Units: []Unit{unit("a", "A", 1)}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Catalog{Version: Version, Categories: tt.categories}
+			if err := c.Validate(); err == nil {
+				t.Errorf("Validate should reject %v", tt.categories)
+			}
+		})
+	}
+
+	if err := Default().Validate(); err != nil {
+		t.Errorf("Built-in definitions: %v", err)
+	}
+
+	// 用户文件本身没有问题，合并后与内置定义重复
+	for _, data := range []string{
+		`{"version":1,"categories":[{"id":"length","units":[{"id":"kilo","name":"千米","symbol":"km","factor":1000}]}]}`,
+		`{"version":1,"categories":[{"id":"distance","name":"长度","units":[{"id":"m","name":"米","factor":1}]}]}`,
+	} {
+		extra, err := Parse([]byte(data))
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		c := Default()
+		c.Merge(extra)
+		if err := c.Validate(); err == nil {
+			t.Errorf("Validate should reject merged %s", data)
+		}
+	}
+	// JSON 中同一类别重复的单位
+	if _, err := Parse([]byte(`{"version":1,"categories":[{"id":"x","name":"X","units":[{"id":"a","name":"A","factor":1},{"id":"a","name":"B","factor":2}]}]}`)); err == nil {
+		t.Errorf("Parse should reject duplicate unit ids")
+	}
+}
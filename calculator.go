@@ -1,37 +1,26 @@
 package main
 
 import (
-	"errors"
-	"fmt"
-	"math"
-	"regexp"
-	"strconv"
 	"strings"
 
-	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/canvas"
-	"fyne.io/fyne/v2/container"
-	"fyne.io/fyne/v2/data/binding"
-	"fyne.io/fyne/v2/layout"
-	"fyne.io/fyne/v2/theme"
-	"fyne.io/fyne/v2/widget"
-	"github.com/Knetic/govaluate"
+	"github.com/gzjjjfree/MemoryCalculator/calc/engine"
+	"github.com/gzjjjfree/MemoryCalculator/calc/history"
 )
 
 // 处理按键输入的核心函数
 func (s *CalcState) OnTap(char string) {
 	// 如果处于拦截模式，将按键传给临时函数，不执行计算逻辑
-	if s.isInterceptingForScore && s.onScoreInput != nil {
-		s.onScoreInput(char)
+	if s.intercept(char) {
 		return
 	}
+	defer s.beginEdit()()
 
 	// 获取当前是否处于结果模式（结果模式下输入算式会重置当前输入）
 	isResultMode, _ := s.isResultMode.Get()
 
-	// 如果当前是结果模式，点击 % 则运行特定函数, 不执行计算逻辑
+	// 如果当前是结果模式，点击 % 则打开平摊界面, 不执行计算逻辑
 	if char == "%" && isResultMode {
-		s.displayScore()
+		s.displaySplit()
 		return
 	}
 
@@ -39,67 +28,89 @@ func (s *CalcState) OnTap(char string) {
 
 	// 防止第一个字符就是运算符 (除了减号表示负数)
 	if current == "" && s.isNewNumber {
-		if strings.ContainsAny(char, "+×÷)") {
+		if strings.ContainsAny(char, "+×÷),") {
 			return
 		}
 	}
 
 	// 如果是新数字开始，重置加粗状态
 	if s.isNewNumber {
-		// 如果新输入的字符是运算符，且当前结果不是 0，则保留结果作为新输入的开头（例如继续在结果后面输入运算符）
+		// 刚按过 = 时输入运算符，用 ans（上一次的结果）作为新算式的开头，由求值引擎解析
+		// 按 C 清除后或刚启动时结果行是 0，这时输入的 - 表示负数
 		result, _ := s.result.Get()
 		current = ""
-		if result != "0" && strings.ContainsAny(char, "+-×÷)") {
-			current = result[2:]
+		if _, ok := s.lookupVariable("ans"); ok && isResultMode && strings.HasPrefix(result, "= ") && strings.ContainsAny(char, "+-×÷^") {
+			current = "ans"
 		}
+		s.resetCaret()
 		s.isResultMode.Set(false)
 		s.display.Set(current + char)
 		s.isNewNumber = false
 		return
 	} else {
-		// 处理重复点击运算符：如果最后一个字符是运算符，再次点击则替换它
+		// 处理重复点击运算符：如果光标前一个字符是运算符，再次点击则替换它
 		operators := "+-×÷"
-		if len(current) > 0 && strings.ContainsAny(char, operators) {
-			lastChar := current[len(current)-1:]
-			if strings.ContainsAny(lastChar, operators) {
-				s.display.Set(current[:len(current)-1] + char)
-				return
-			}
+		if last := s.runeBeforeCaret(); last != 0 && strings.ContainsAny(char, operators) && strings.ContainsRune(operators, last) {
+			s.deleteBeforeCaret()
 		}
-		s.display.Set(current + char)
+		s.insertAtCaret(char)
 	}
 
 	// 实时更新结果
 	newEq, _ := s.display.Get()
-	res := s.Calculate(newEq)
-	if res != "" {
-		s.result.Set("= " + res)
+	s.updatePreview(newEq)
+}
+
+// 拦截按键：平摊、换算、统计或程序员界面打开时，按键交给对应的回调处理
+func (s *CalcState) intercept(char string) bool {
+	if s.isInterceptingForScore && s.onScoreInput != nil {
+		s.onScoreInput(char)
+		return true
 	}
+	if s.isConverting && s.onConvertInput != nil {
+		s.onConvertInput(char)
+		return true
+	}
+	if s.isStatistics && s.onStatsInput != nil {
+		s.onStatsInput(char)
+		return true
+	}
+	if s.isProgramming && s.onProgramInput != nil {
+		s.onProgramInput(char)
+		return true
+	}
+	return false
+}
+
+// 平摊、换算、统计或程序员界面接管了键盘，此时不响应针对计算界面的操作（光标、粘贴、内存、撤销等）
+func (s *CalcState) isTakenOver() bool {
+	return s.isInterceptingForScore || s.isConverting || s.isStatistics || s.isProgramming
 }
 
 // 处理清除键
 func (s *CalcState) OnClear() {
 	// 如果处于拦截模式，将按键传给临时函数，不执行计算逻辑
-	if s.isInterceptingForScore && s.onScoreInput != nil {
-		s.onScoreInput("C")
+	if s.intercept("C") {
 		return
 	}
+	defer s.beginEdit()()
 
 	current, _ := s.display.Get()
-	history, _ := s.history.Get()
 	finalRes := s.Calculate(current)
 	// 只有当当前有输入内容时才存入历史，避免存入多余空行
 	if s.isNewNumber == false && current != "0" && current != "" {
 		current = checkLastOperator(current)
 
 		newHistory, _ := updateFontSizeBasedOnWidth(current+" = "+finalRes, nil) // 更新字体大小和换行状态
-		s.history.Set(history + "\n" + newHistory)
-
-		s.recordToHistory(current, finalRes) // 追加到历史记录中
+		// 追加到历史记录中，标记为清除时归档
+		rec := s.recordToHistory(current, finalRes, history.TagCleared)
+		s.addSessionEntry(rec, newHistory)
 	}
 
 	s.display.Set("")
 	s.result.Set("0")
+	s.errorSpan.Set(engine.Span{})
+	s.resetCaret()
 
 	s.isNewNumber = true
 	isChangeRow = false
@@ -109,265 +120,217 @@ func (s *CalcState) OnClear() {
 // 处理等号键
 func (s *CalcState) OnEqual() {
 	// 如果处于拦截模式，将按键传给临时函数，不执行计算逻辑
-	if s.isInterceptingForScore && s.onScoreInput != nil {
-		s.onScoreInput("=")
+	if s.intercept("=") {
 		return
 	}
+	defer s.beginEdit()()
 
-	history, _ := s.history.Get()
 	current, _ := s.display.Get()
 
-	// 自动补全未闭合的括号 (防止 govaluate 报错)
+	// 自动补全未闭合的括号，让写入历史的算式保持完整
 	leftCount := strings.Count(current, "(")
 	rightCount := strings.Count(current, ")")
 	if leftCount > rightCount {
 		current += strings.Repeat(")", leftCount-rightCount)
 	}
 
-	finalRes := s.Calculate(current)
+	// 只输入了一个名称（如 x 或 f(x)）时，按 = 开始写赋值语句 x=
+	if isResult, _ := s.isResultMode.Get(); !isResult && !s.isNewNumber && s.caretTail() == 0 && s.isAssignable(current) {
+		s.display.Set(current + "=")
+		return
+	}
+
+	res, assignment, evalErr := s.evaluate(current)
+	if evalErr != nil {
+		// 显示错误原因，并在输入框中标出出错的位置
+		s.showError(evalErr)
+		return
+	}
+	finalRes := res.Text
+	if assignment != nil {
+		if err := s.apply(assignment, res); err != nil {
+			s.showError(engine.AsError(err))
+			return
+		}
+	}
 
 	// 只有当当前有输入内容时才存入历史，避免存入多余空行
-	if finalRes != "" && finalRes != "Error" {
+	if finalRes != "" {
 		current = checkLastOperator(current)
 
 		newHistory, _ := updateFontSizeBasedOnWidth(current+" = "+finalRes, nil) // 更新字体大小和换行状态
-		s.history.Set(history + "\n" + newHistory)
+		// 追加到历史记录中
+		rec := s.recordToHistory(current, finalRes)
+		s.addSessionEntry(rec, newHistory)
 
 		s.result.Set("= " + finalRes)
 		s.isNewNumber = true
 		isChangeRow = false
+		s.resetCaret()
 		s.isResultMode.Set(true)
+	}
+}
 
-		s.recordToHistory(current, finalRes) // 追加到历史记录中
+// 重用历史中的算式：替换当前输入，可以继续编辑
+func (s *CalcState) RecallExpression(rec history.Record) {
+	// 平摊、换算、统计或程序员界面打开时不响应
+	if s.isTakenOver() {
+		return
 	}
+	defer s.beginEdit()()
+	s.errorSpan.Set(engine.Span{})
+	s.isNewNumber = false
+	isChangeRow = false
+	s.isResultMode.Set(false)
+	s.resetCaret()
+	s.display.Set(rec.Expression)
+	s.updatePreview(rec.Expression)
 }
 
-// 计算函数，使用 govaluate 解析和计算表达式
-func (s *CalcState) Calculate(equation string) string {
-	if equation == "" || equation == "0" {
-		return "0"
-	}
-
-	equation = checkLastOperator(equation)
-
-	// 安全符号替换与自动补全
-	exprStr := equation
-	// 替换 × ÷
-	exprStr = strings.ReplaceAll(exprStr, "×", "*")
-	exprStr = strings.ReplaceAll(exprStr, "÷", "/")
-	exprStr = strings.ReplaceAll(exprStr, "%", "*0.01")   // 修复百分号
-	exprStr = strings.ReplaceAll(exprStr, "1/x(", "inv(") // 修复倒数函数
-	// 替换 π（仅独立常量，不在函数名中）
-	exprStr = replaceConstant(exprStr, "π", fmt.Sprintf("%f", math.Pi))
-	// 替换 e（仅独立常量，不在函数名、exp等中）
-	exprStr = replaceConstant(exprStr, "e", fmt.Sprintf("%f", math.E))
-	// 替换 ^ 为 pow 函数（如 2^3 -> pow(2,3)）
-	exprStr = replacePower(exprStr)
-
-	// 自动补全未闭合的括号 (防止 govaluate 报错)
-	leftCount := strings.Count(exprStr, "(")
-	rightCount := strings.Count(exprStr, ")")
-	if leftCount > rightCount {
-		exprStr += strings.Repeat(")", leftCount-rightCount)
+// 重用历史中的结果：作为一个数值插入当前输入，结果不是数值时忽略
+func (s *CalcState) RecallResult(rec history.Record) {
+	if s.isTakenOver() {
+		return
 	}
+	if _, ok := rec.Value(); !ok {
+		return
+	}
+	defer s.beginEdit()()
+	s.errorSpan.Set(engine.Span{})
+	s.insertValue(rec.Result)
+}
 
-	isRad, _ := s.isRadian.Get()
-	// 定义高级函数映射 (增加安全检查)
-	functions := map[string]govaluate.ExpressionFunction{
-		"sin": func(args ...any) (any, error) {
-			if len(args) < 1 {
-				return 0.0, nil
-			}
-			val, ok := args[0].(float64)
-			if !ok {
-				return 0.0, nil
-			}
-			if !isRad { // 如果不是弧度模式，进行转换
-				val = val * math.Pi / 180
-			}
-			return math.Sin(val), nil
-		},
-		"asin": func(args ...any) (any, error) {
-			if len(args) < 1 {
-				return nil, errors.New("Domain Error")
-			}
-			val, ok := args[0].(float64)
-			if !ok || val < -1 || val > 1 {
-				return nil, errors.New("Domain Error")
-			}
-			res := math.Asin(val)
-			if !isRad {
-				res = res * 180 / math.Pi
-			}
-			return res, nil
-		},
-		"cos": func(args ...any) (any, error) {
-			if len(args) < 1 {
-				return 0.0, nil
-			}
-			val, ok := args[0].(float64)
-			if !ok {
-				return 0.0, nil
-			}
-			if !isRad { // 如果不是弧度模式，进行转换
-				val = val * math.Pi / 180
-			}
-			return math.Cos(val), nil
-			//return math.Cos(val * math.Pi / 180), nil
-		},
-		"acos": func(args ...any) (any, error) {
-			val := args[0].(float64)
-			if val < -1 || val > 1 {
-				return nil, errors.New("Domain Error")
-			}
-			res := math.Acos(val)
-			if !isRad {
-				res = res * 180 / math.Pi
-			}
-			return res, nil
-		},
-		"tan": func(args ...any) (any, error) {
-			if len(args) < 1 {
-				return 0.0, nil
-			}
-			val, ok := args[0].(float64)
-			if !ok {
-				return 0.0, nil
-			}
-			if !isRad { // 如果不是弧度模式，进行转换
-				val = val * math.Pi / 180
-			}
-			return math.Tan(val), nil
-		},
-		"atan": func(args ...any) (any, error) {
-			val := args[0].(float64)
-			res := math.Atan(val)
-			if !isRad {
-				res = res * 180 / math.Pi
-			}
-			return res, nil
-		},
-		"sqrt": func(args ...any) (any, error) {
-			if len(args) < 1 {
-				return 0.0, nil
-			}
-			val, ok := args[0].(float64)
-			if !ok {
-				return 0.0, nil
-			}
-			return math.Sqrt(val), nil
-		},
-		"lg": func(args ...any) (any, error) {
-			if len(args) < 1 {
-				return 0.0, nil
-			}
-			val, ok := args[0].(float64)
-			if !ok {
-				return 0.0, nil
-			}
-			return math.Log10(val), nil
-		},
-		"ln": func(args ...any) (any, error) {
-			if len(args) < 1 {
-				return 0.0, nil
-			}
-			val, ok := args[0].(float64)
-			if !ok {
-				return 0.0, nil
-			}
-			return math.Log(val), nil
-		},
-		"pow": func(args ...any) (any, error) {
-			if len(args) < 2 {
-				return nil, errors.New("pow requires 2 arguments")
-			}
-			return math.Pow(args[0].(float64), args[1].(float64)), nil
-		},
-		"pow10": func(args ...any) (any, error) {
-			return math.Pow(10, args[0].(float64)), nil
-		},
-		"exp": func(args ...any) (any, error) {
-			return math.Exp(args[0].(float64)), nil
-		},
-		"sqr": func(args ...any) (any, error) {
-			val := args[0].(float64)
-			return val * val, nil
-		},
-		"fact": func(args ...any) (any, error) {
-			n := args[0].(float64)
-			if n < 0 {
-				return 0.0, nil
-			}
-			res := 1.0
-			for i := 2.0; i <= n; i++ {
-				res *= i
-			}
-			return res, nil
-		},
-		"inv": func(args ...any) (any, error) {
-			val := args[0].(float64)
-			if val == 0 {
-				return nil, errors.New("Division by zero")
-			}
-			return 1.0 / val, nil
-		},
+// 计算函数，是求值引擎 engine.Evaluate 的适配层
+// 解析失败时返回空字符串，计算失败时返回 "Error"
+func (s *CalcState) Calculate(equation string) string {
+	res, err := s.Evaluate(equation)
+	if err != nil {
+		if err.Kind.IsSyntax() {
+			return ""
+		}
+		return "Error"
 	}
+	return res
+}
 
-	// 执行解析计算
-	expression, err := govaluate.NewEvaluableExpressionWithFunctions(exprStr, functions)
+// 计算算式，失败时返回带类别和出错位置的结构化错误；赋值语句返回等号右边的值
+func (s *CalcState) Evaluate(equation string) (string, *engine.Error) {
+	res, _, err := s.evaluate(equation)
 	if err != nil {
-		return ""
+		return "", err
 	}
+	return res.Text, nil
+}
 
-	res, err := expression.Evaluate(map[string]any{})
+// 计算算式、赋值语句 "x = 3.5" 或函数定义 "f(x) = x^2"，
+// 是赋值语句时同时返回解析出的赋值（不会修改变量和函数）
+func (s *CalcState) evaluate(equation string) (engine.Result, *engine.Assignment, *engine.Error) {
+	opts := s.engineOptions()
+	expr := checkLastOperator(equation)
+	a, err := engine.ParseAssignment(expr, opts)
 	if err != nil {
-		return "Error"
+		return engine.Result{}, nil, engine.AsError(err)
 	}
-	// 检查结果是否有效
-	f, ok := res.(float64)
-	if !ok {
-		return "Error"
+	if a == nil {
+		res, err := s.evalCache.Evaluate(expr, opts)
+		return res, nil, engine.AsError(err)
+	}
+
+	// ans、ans1… 总是指向历史结果，不能赋值
+	if _, ok := answerIndex(a.Name); ok {
+		start := len([]rune(expr[:strings.Index(expr, a.Name)]))
+		span := engine.Span{Start: start, End: start + len([]rune(a.Name))}
+		return engine.Result{}, nil, &engine.Error{Kind: engine.KindSyntax, Msg: a.Name + " 是历史结果，不能赋值", Span: span}
+	}
+	if a.IsFunction() {
+		// 函数定义没有值，检查函数体能否编译，结果行显示函数头
+		if _, ok := s.variables[a.Name]; ok {
+			return engine.Result{}, nil, &engine.Error{Kind: engine.KindSyntax, Msg: a.Name + " 已经是变量，不能用作函数名"}
+		}
+		if _, err := engine.Define(a, opts); err != nil {
+			return engine.Result{}, nil, engine.AsError(err)
+		}
+		return engine.Result{Text: "函数 " + a.Signature()}, a, nil
+	}
+	res, err := s.evalCache.Evaluate(a.Value, opts)
+	if err != nil {
+		return engine.Result{}, nil, a.Shift(engine.AsError(err))
 	}
-	if math.IsInf(f, 0) || math.IsNaN(f) {
-		return "Error" // 这样 1/0 就会返回 Error 了
+	if !res.IsReal() {
+		return engine.Result{}, nil, &engine.Error{Kind: engine.KindDomain, Msg: "变量只能保存实数"}
 	}
-	// 格式化输出，如果是整数则不带小数点
-	return fmt.Sprintf("%g", res)
+	return res, a, nil
 }
 
-// 安全替换常量（仅替换独立的 π、e，不在函数名、变量名中）
-func replaceConstant(expr, symbol, value string) string {
-	if symbol == "π" {
-		return strings.ReplaceAll(expr, "π", value)
+// 实时预览：算式还没写完整时保留上一次的预览，计算出错时显示错误原因
+func (s *CalcState) updatePreview(equation string) {
+	res, err := s.Evaluate(equation)
+	switch {
+	case err == nil:
+		s.errorSpan.Set(engine.Span{})
+		s.result.Set("= " + res)
+	case err.Kind.IsSyntax():
+		s.errorSpan.Set(engine.Span{})
+	default:
+		s.showError(err)
 	}
-	// \b 匹配单词边界
-	pattern := fmt.Sprintf(`\b%s\b`, regexp.QuoteMeta(symbol))
-	re := regexp.MustCompile(pattern)
-	return re.ReplaceAllString(expr, value)
 }
 
-// 替换幂运算符 ^ 为 pow(x,y)
-func replacePower(expr string) string {
-	// 用正则匹配形如 a^b 的表达式，替换为 pow(a,b)
-	// 只处理简单数字和括号表达式
-	pattern := `([0-9.]+|\([^)]+\))\^([0-9.]+|\([^)]+\))`
-	re := regexp.MustCompile(pattern)
-	return re.ReplaceAllStringFunc(expr, func(m string) string {
-		parts := strings.Split(m, "^")
-		if len(parts) == 2 {
-			return fmt.Sprintf("pow(%s,%s)", parts[0], parts[1])
+// 在结果行显示错误原因，并记录出错位置供输入框标红
+func (s *CalcState) showError(err *engine.Error) {
+	s.result.Set(err.Msg)
+	s.errorSpan.Set(err.Span)
+}
+
+// 根据当前界面状态生成求值参数
+func (s *CalcState) engineOptions() engine.Options {
+	opts := engine.Options{Angle: engine.Degree}
+	if isRad, _ := s.isRadian.Get(); isRad {
+		opts.Angle = engine.Radian
+	}
+	opts.Exact, _ = s.isExact.Get()
+	opts.Places, _ = s.decimalPlaces.Get()
+	opts.Complex, _ = s.isComplex.Get()
+	opts.Polar, _ = s.isPolar.Get()
+	opts.Variables = s.lookupVariable
+	opts.Functions = s.functions
+	return opts
+}
+
+// 查找算式中的变量：先查用户定义的变量，
+// 再查 ans（最近一次按 = 得到的结果），ans1、ans2… 依次是最近第 1、2… 个结果（ans1 即 ans）
+func (s *CalcState) lookupVariable(name string) (float64, bool) {
+	if v, ok := s.variables[name]; ok {
+		return v, true
+	}
+	n, ok := answerIndex(name)
+	if !ok {
+		return 0, false
+	}
+
+	s.historyMutex.Lock()
+	defer s.historyMutex.Unlock()
+	for i := len(s.records) - 1; i >= 0; i-- {
+		rec := s.records[i]
+		if rec.HasTag(history.TagCleared) {
+			continue // 按 C 归档的算式不是结果
 		}
-		return m
-	})
+		if v, ok := rec.Value(); ok {
+			if n--; n == 0 {
+				return v, true
+			}
+		}
+	}
+	return 0, false
 }
 
 // 处理退格键
 func (s *CalcState) OnBackspace() {
 	// 如果处于拦截模式，将按键传给临时函数，不执行计算逻辑
-	if s.isInterceptingForScore && s.onScoreInput != nil {
-		s.onScoreInput("⌫")
+	if s.intercept("⌫") {
 		return
 	}
+	defer s.beginEdit()()
 
 	// 如果当前处于结果显示模式，退格键通常应该直接清空结果回到输入模式
 	isResult, _ := s.isResultMode.Get()
@@ -382,6 +345,19 @@ func (s *CalcState) OnBackspace() {
 		return
 	}
 
+	// 光标不在末尾时，删除光标前的一个字符，已有的换行保持不变
+	if s.caretTail() > 0 {
+		if s.runeBeforeCaret() == 0 {
+			return // 光标在开头
+		}
+		if s.deleteBeforeCaret() == '(' {
+			s.trimFunctionBeforeCaret()
+		}
+		newEq, _ := s.display.Get()
+		s.updatePreview(newEq)
+		return
+	}
+
 	// 使用 rune 处理多字节字符
 	runes := []rune(current)
 
@@ -393,12 +369,7 @@ func (s *CalcState) OnBackspace() {
 		// 删掉最后一个字符
 		newEq := string(runes[:len(runes)-1])
 		// 自动清理掉残余的函数名，如输入了 sin( 删掉 ( 后，把 sin 也删掉，保持算式整洁
-		for _, fn := range []string{"sin", "cos", "tan", "lg", "ln", "sqrt", "fact"} {
-			if strings.HasSuffix(newEq, fn) {
-				newEq = strings.TrimSuffix(newEq, fn)
-				break
-			}
-		}
+		newEq = trimFunctionName(newEq)
 
 		// 清理掉最后一个换行符
 		lastNewlineIdx := strings.LastIndexAny(newEq, "\n")
@@ -412,42 +383,55 @@ func (s *CalcState) OnBackspace() {
 		s.display.Set(newEq)
 
 		// 更新实时预览
-		res := s.Calculate(newEq)
-		if res != "" {
-			s.result.Set("= " + res)
-		} else {
-			s.result.Set("0")
+		s.updatePreview(newEq)
+	}
+}
+
+// 键盘上的函数键输入的函数名，长的在前，避免 asin 只删掉 sin
+var functionNames = []string{"pow10", "asin", "acos", "atan", "sqrt", "fact", "1/x", "sqr", "exp", "sin", "cos", "tan", "lg", "ln"}
+
+// 去掉算式末尾残余的函数名
+func trimFunctionName(eq string) string {
+	for _, fn := range functionNames {
+		if strings.HasSuffix(eq, fn) {
+			return strings.TrimSuffix(eq, fn)
 		}
 	}
+	return eq
 }
 
-// 切换大布局的动作
+// 切换键盘布局的动作：基本 → 科学 → 程序员 → 基本
 func (s *CalcState) OnGoBigGrid() {
 	s.isNewNumber = true
-	if ok, _ := s.isCalcBig.Get(); ok {
+	isBig, _ := s.isCalcBig.Get()
+	isProgrammer, _ := s.isProgrammer.Get()
+	switch {
+	case isProgrammer:
+		s.isProgrammer.Set(false)
 		s.isCalcBig.Set(false)
-	} else {
+	case isBig:
+		s.isProgrammer.Set(true)
+	default:
 		s.isCalcBig.Set(true)
 	}
 }
 
 // 处理高级函数按钮的输入
 func (s *CalcState) OnAdvancedTap(op string) {
-	s.isNewNumber = false
-	s.isResultMode.Set(false)
 	is2nd, _ := s.is2ndMode.Get()
-	current, _ := s.display.Get()
 
 	// 注意：这里的 key 必须和你按钮初始化的 text 一致
 	opMapping := map[string]string{
 		"sin": "sin(", "cos": "cos(", "tan": "tan(",
 		"lg": "lg(", "ln": "ln(", "√x": "sqrt(",
+		"x!": "!", "1/x": "1/x(",
 	}
 
 	// 如果是 2nd 模式，映射到对应的反函数或二次幂
 	secondMapping := map[string]string{
 		"sin": "asin(", "cos": "acos(", "tan": "atan(",
 		"lg": "pow10(", "ln": "exp(", "√x": "sqr(",
+		"x!": "i", "1/x": "1/x(", "e": "ans",
 	}
 
 	var toAdd string
@@ -465,19 +449,23 @@ func (s *CalcState) OnAdvancedTap(op string) {
 		}
 	}
 
-	s.display.Set(current + toAdd)
+	// 如果处于拦截模式，将映射后的文本传给临时函数
+	if s.intercept(toAdd) {
+		return
+	}
+	defer s.beginEdit()()
+
+	s.isNewNumber = false
+	s.isResultMode.Set(false)
+	newEq := s.insertAtCaret(toAdd)
 
-	newEq, _ := s.display.Get()
 	// 如果是以 "(" 结尾（刚输入完函数名），通常不需要显示即时预览结果
 	if strings.HasSuffix(newEq, "(") {
 		return
 	}
 
 	// 实时预览
-	res := s.Calculate(newEq)
-	if res != "" {
-		s.result.Set("= " + res)
-	}
+	s.updatePreview(newEq)
 }
 
 // 切换 DEG/RAD 的动作
@@ -488,10 +476,25 @@ func (s *CalcState) OnDegToRad() {
 
 	// 切换后重新触发一次计算，更新预览结果
 	current, _ := s.display.Get()
-	if current == "" {
-		s.result.Set("0")
-	}
-	s.result.Set("= " + s.Calculate(current))
+	s.updatePreview(current)
+}
+
+// 切换复数模式的动作：实数 → 复数（直角坐标 a+bi）→ 复数（极坐标 r∠θ）→ 实数
+func (s *CalcState) OnComplexMode() {
+	isComplex, _ := s.isComplex.Get()
+	isPolar, _ := s.isPolar.Get()
+	switch {
+	case !isComplex:
+		s.isComplex.Set(true)
+		s.isPolar.Set(false)
+	case !isPolar:
+		s.isPolar.Set(true)
+	default:
+		s.isComplex.Set(false)
+		s.isPolar.Set(false)
+	}
+	s.evalCache.Reset() // 复数模式下 i 是常量，会改变连写名称的拆分
+	s.saveSettings()    // 保存设置，并刷新实时预览
 }
 
 // 切换 2nd 状态的动作
@@ -512,123 +515,3 @@ func checkLastOperator(equation string) string {
 	}
 	return equation
 }
-
-// 显示平摊分数的界面
-func (s *CalcState) displayScore() {
-	result, _ := s.result.Get()
-	scoreStr := strings.TrimPrefix(result, "= ")
-	scoreFloat, _ := strconv.ParseFloat(scoreStr, 64)
-	totalScore := int(scoreFloat)
-
-	if totalScore <= 0 || totalScore >= 600 {
-		return
-	}
-
-	peopleInput := binding.NewString()
-	peopleInput.Set("")
-
-	// 定义核心计算逻辑
-	updatePreview := func() {
-		val, _ := peopleInput.Get()
-		if val == "" {
-			s.result.Set(fmt.Sprintf("总分:%d | 请输入人数", totalScore))
-			s.isResultMode.Set(false)
-			return
-		}
-
-		num, err := strconv.Atoi(val)
-		if err != nil || num <= 0 {
-			s.result.Set("人数无效")
-			s.isResultMode.Set(false)
-			return
-		}
-
-		// 计算平均分和余数
-		base := totalScore / num
-		rem := totalScore % num
-		var finalStr string
-		if rem == 0 {
-			finalStr = fmt.Sprintf("总分:%d | %d人%d分  ", totalScore, num, base)
-		} else {
-			finalStr = fmt.Sprintf("总分:%d | %d人%d分, %d人%d分  ",
-				totalScore, rem, base+1, num-rem, base)
-		}
-		s.result.Set(finalStr)
-		s.isResultMode.Set(false)
-	}
-
-	// 监听输入变化实现实时预览
-	peopleInput.AddListener(binding.NewDataListener(func() {
-		updatePreview()
-	}))
-
-	displayLabel := widget.NewLabelWithData(peopleInput)
-	displayLabel.Alignment = fyne.TextAlignCenter
-	displayLabel.TextStyle = fyne.TextStyle{Bold: true}
-
-	title := widget.NewLabel("请输入平摊人数")
-	title.Alignment = fyne.TextAlignCenter
-
-	// 3. 按钮逻辑改造
-	btnCancel := widget.NewButton("返回", func() {
-		s.isInterceptingForScore = false
-		s.scoreOverlay.Hide()
-		// 返回时恢复原始结果显示
-		s.result.Set(result)
-	})
-
-	// 变更为重置按钮
-	btnReset := widget.NewButton("重置", func() {
-		peopleInput.Set("") // 清空输入，触发监听器更新预览
-	})
-
-	cardContent := container.NewVBox(
-		title,
-		container.NewPadded(displayLabel),
-		container.NewHBox(layout.NewSpacer(), layout.NewSpacer(), btnCancel, layout.NewSpacer(), btnReset, layout.NewSpacer(), layout.NewSpacer()),
-	)
-
-	cardBackground := canvas.NewRectangle(theme.BackgroundColor())
-	cardBackground.SetMinSize(fyne.NewSize(360, 150))
-	card := container.NewStack(cardBackground, cardContent)
-
-	s.scoreOverlay.Objects = []fyne.CanvasObject{
-		container.NewVBox(
-			layout.NewSpacer(),
-			container.NewCenter(card),
-			layout.NewSpacer(),
-			layout.NewSpacer(),
-			layout.NewSpacer(),
-			layout.NewSpacer(),
-			layout.NewSpacer(),
-			layout.NewSpacer(),
-			layout.NewSpacer(),
-		),
-	}
-	s.scoreOverlay.Refresh()
-	s.scoreOverlay.Show()
-
-	// 接管输入
-	s.isInterceptingForScore = true
-	s.onScoreInput = func(char string) {
-		current, _ := peopleInput.Get()
-		if char >= "0" && char <= "9" {
-			// 限制人数长度防止溢出
-			if len(current) < 3 {
-				peopleInput.Set(current + char)
-			}
-		} else if char == "C" {
-			s.isInterceptingForScore = false
-			s.scoreOverlay.Hide()
-			s.result.Set(result)
-			s.isResultMode.Set(false)
-		} else if char == "⌫" {
-			if current != "" {
-				runes := []rune(current)
-				// 截取掉最后一个字符
-				newVal := string(runes[:len(runes)-1])
-				peopleInput.Set(newVal)
-			}
-		}
-	}
-}
@@ -2,10 +2,23 @@ package main
 
 import (
 	"math"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/gzjjjfree/MemoryCalculator/calc/engine"
+	"github.com/gzjjjfree/MemoryCalculator/calc/history"
+	"github.com/gzjjjfree/MemoryCalculator/calc/integer"
+	"github.com/gzjjjfree/MemoryCalculator/calc/solve"
+	"github.com/gzjjjfree/MemoryCalculator/calc/split"
 )
 
 func TestCalculate(t *testing.T) {
@@ -97,3 +110,1061 @@ func compareResults(got, expected string) bool {
 
 	return false
 }
+
+func TestMemoryRegisters(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir()) // OnClear 会写历史文件，测试 App 的存储目录位于临时目录下
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	s := NewCalcState(testApp.NewWindow("Test Window"))
+
+	// MS 存入 5，M+ 累加 3，M- 累减 1
+	for _, step := range []struct{ input, op string }{{"5", "MS"}, {"3", "M+"}, {"1", "M-"}} {
+		s.display.Set(step.input)
+		s.OnMemory(step.op)
+	}
+	if !s.memory.used[0] || s.memory.values[0] != 7 {
+		t.Fatalf("M: expected 7, got %v (used %v)", s.memory.values[0], s.memory.used[0])
+	}
+
+	// 命名寄存器互不影响
+	s.OnSelectMemory(3)
+	s.display.Set("2×4")
+	s.OnMemory("MS")
+	s.OnSelectMemory(0)
+
+	// MR 在新算式中插入寄存器的值
+	s.OnClear()
+	s.OnMemory("MR")
+	if got, _ := s.display.Get(); got != "7" {
+		t.Errorf("MR: expected display 7, got %q", got)
+	}
+
+	// 重新创建状态时从 Preferences 恢复
+	restored := NewCalcState(testApp.NewWindow("Restored"))
+	if restored.memory.values[0] != 7 || restored.memory.values[3] != 8 || !restored.memory.used[3] {
+		t.Errorf("Restore: got %v / %v", restored.memory.values, restored.memory.used)
+	}
+
+	// MC 只清除当前寄存器
+	restored.OnMemory("MC")
+	if restored.memory.used[0] || !restored.memory.used[3] {
+		t.Errorf("MC: got used %v", restored.memory.used)
+	}
+
+	// 赋值后 M+ 累加所赋的值，不重新计算
+	restored.display.Set("x=3")
+	restored.OnEqual()
+	restored.OnMemory("M+")
+	restored.display.Set("x=x+1")
+	restored.OnEqual()
+	restored.OnMemory("M+")
+	if restored.memory.values[0] != 7 {
+		t.Errorf("M+ after assignment: expected 7, got %v", restored.memory.values[0])
+	}
+	// 输入中的赋值语句取所赋的值
+	restored.OnClear()
+	restored.display.Set("y=2×5")
+	restored.isNewNumber = false
+	restored.OnMemory("MS")
+	if restored.memory.values[0] != 10 {
+		t.Errorf("MS on assignment: expected 10, got %v", restored.memory.values[0])
+	}
+
+	// 没有数值时提示原因，内存不变
+	restored.display.Set("g(t)=t+1")
+	restored.OnMemory("MS")
+	if got, _ := restored.result.Get(); !strings.Contains(got, "函数定义") || restored.memory.values[0] != 10 {
+		t.Errorf("MS on function definition: got %q, memory %v", got, restored.memory.values[0])
+	}
+	restored.display.Set("sqrt(-1")
+	restored.OnMemory("MS")
+	if got, _ := restored.result.Get(); got == "" || strings.HasPrefix(got, "= ") {
+		t.Errorf("MS on invalid expression should show an error, got %q", got)
+	}
+}
+
+func TestHistoryPersistence(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	root := testApp.Storage().RootURI().Path()
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// 旧版纯文本历史在第一次加载时导入
+	legacy := "--- 2026-03-30 ---\n1+1 = 2\n"
+	if err := os.WriteFile(filepath.Join(root, "history.txt"), []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewCalcState(testApp.NewWindow("Test Window"))
+	s.display.Set("2×3")
+	s.OnEqual() // 加载完成前的记录也要保留
+	s.loadHistory()
+	if _, err := os.Stat(filepath.Join(root, "history.txt")); !os.IsNotExist(err) {
+		t.Errorf("Legacy file should be removed after migration, got %v", err)
+	}
+
+	// 每次计算立即写入文件，不依赖退出时保存
+	s.display.Set("1+2")
+	s.OnEqual()
+
+	restored := NewCalcState(testApp.NewWindow("Restored"))
+	restored.loadHistory()
+	got := restored.historyRecords()
+	want := []string{"1+1 = 2", "2×3 = 6", "1+2 = 3"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d records, got %+v", len(want), got)
+	}
+	for i, rec := range got {
+		if rec.String() != want[i] {
+			t.Errorf("Record %d: expected %q, got %q", i, want[i], rec.String())
+		}
+	}
+
+	if err := restored.ClearAllHistoryLocal(); err != nil {
+		t.Fatalf("ClearAllHistoryLocal: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "history.jsonl")); !os.IsNotExist(err) {
+		t.Errorf("History file should be removed, got %v", err)
+	}
+}
+
+func TestHistoryRecall(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	s := NewCalcState(testApp.NewWindow("Test Window"))
+	s.display.Set("2×3")
+	s.OnEqual()
+	entries := s.sessionEntries()
+	if len(entries) != 1 || entries[0].record.String() != "2×3 = 6" {
+		t.Fatalf("Session entries: got %+v", entries)
+	}
+	rec := entries[0].record
+
+	// 重用算式后可以继续编辑
+	s.RecallExpression(rec)
+	s.OnTap("+")
+	s.OnTap("1")
+	if got, _ := s.display.Get(); got != "2×3+1" {
+		t.Errorf("RecallExpression: expected 2×3+1, got %q", got)
+	}
+
+	// 重用结果插入当前算式
+	s.OnTap("×")
+	s.RecallResult(rec)
+	if got, _ := s.display.Get(); got != "2×3+1×6" {
+		t.Errorf("RecallResult: expected 2×3+1×6, got %q", got)
+	}
+	if mode, _ := s.isResultMode.Get(); mode || s.isNewNumber {
+		t.Errorf("RecallResult should leave input mode, got resultMode=%v newNumber=%v", mode, s.isNewNumber)
+	}
+
+	// 结果模式下重用结果开始新的算式
+	s.OnEqual()
+	s.RecallResult(rec)
+	if got, _ := s.display.Get(); got != "6" {
+		t.Errorf("RecallResult after equal: expected 6, got %q", got)
+	}
+}
+
+func TestKeyboard(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	w := testApp.NewWindow("Test Window")
+	s := NewCalcState(w)
+	bindKeyboard(s, w.Canvas(), loadKeymap())
+
+	test.TypeOnCanvas(w.Canvas(), "2*(3+4)^2")
+	if got, _ := s.display.Get(); got != "2×(3+4)^2" {
+		t.Errorf("Typed runes: expected 2×(3+4)^2, got %q", got)
+	}
+	w.Canvas().OnTypedKey()(&fyne.KeyEvent{Name: fyne.KeyReturn})
+	if got, _ := s.result.Get(); got != "= 98" {
+		t.Errorf("Return: expected = 98, got %q", got)
+	}
+	w.Canvas().OnTypedKey()(&fyne.KeyEvent{Name: fyne.KeyEscape})
+	if got, _ := s.display.Get(); got != "" {
+		t.Errorf("Escape: expected empty display, got %q", got)
+	}
+
+	// 字母键入自身：可以直接输入赋值语句、函数定义和内置函数名
+	for _, typed := range []string{"x=3.5\r", "f(x)=x^2+x\r", "sqrt(f(2x)+8)\r"} {
+		for _, r := range typed {
+			if r == '\r' {
+				w.Canvas().OnTypedKey()(&fyne.KeyEvent{Name: fyne.KeyReturn})
+			} else {
+				w.Canvas().OnTypedRune()(r)
+			}
+		}
+	}
+	if v, ok := s.variables["x"]; !ok || v != 3.5 {
+		t.Errorf("Typed assignment: got x=%v (%v)", v, ok)
+	}
+	if _, ok := s.userFunctions["f"]; !ok {
+		t.Errorf("Typed function definition: got %v", s.userFunctionNames())
+	}
+	if got, _ := s.result.Get(); got != "= 8" {
+		t.Errorf("Typed call: expected = 8, got %q", got)
+	}
+	// 逗号分隔多个参数
+	for _, r := range "g(a,b)=a-b\rg(5,2)\r" {
+		if r == '\r' {
+			w.Canvas().OnTypedKey()(&fyne.KeyEvent{Name: fyne.KeyReturn})
+		} else {
+			w.Canvas().OnTypedRune()(r)
+		}
+	}
+	if got, _ := s.result.Get(); got != "= 3" {
+		t.Errorf("Typed call with two arguments: expected = 3, got %q", got)
+	}
+	if sc := (desktop.CustomShortcut{KeyName: "P", Modifier: fyne.KeyModifierAlt}); loadKeymap().shortcuts[sc] != "π" {
+		t.Error("Alt+P should type π")
+	}
+
+	// 自定义键位：按键写法错误或动作不存在时报错
+	if _, err := parseKeymap(map[string]string{"Mod+": "="}); err == nil {
+		t.Error("Expected error for empty key")
+	}
+	if _, err := parseKeymap(map[string]string{"q": "unknown"}); err == nil {
+		t.Error("Expected error for unknown action")
+	}
+	km, err := parseKeymap(map[string]string{"Ctrl+Shift+k": "MS", "Tab": "2nd"})
+	if err != nil {
+		t.Fatalf("parseKeymap: %v", err)
+	}
+	sc := desktop.CustomShortcut{KeyName: "K", Modifier: fyne.KeyModifierControl | fyne.KeyModifierShift}
+	if km.shortcuts[sc] != "MS" || km.keys[fyne.KeyTab] != "2nd" {
+		t.Errorf("parseKeymap: got %+v", km)
+	}
+}
+
+func TestClipboard(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"2*3/4", "2×3÷4", false},
+		{" $1,234.50 + ¥2,000 ", "1234.50+2000", false},
+		{"１２３＋（４）", "123+(4)", false},
+		{"12 元 − 3", "12-3", false},
+		{"sin(30) = 0.5", "sin(30)", false},
+		{"1'000'000×2", "1000000×2", false},
+		// 函数参数之间的逗号不是千位分隔符
+		{"pow(2,300)", "pow(2,300)", false},
+		{"max(1,100) + 2,000", "max(1,100)+2000", false},
+		{"log10(sin(30),1,000)", "log10(sin(30),1,000)", false},
+		{"2×(1,234+1)", "2×(1234+1)", false},
+		{"rate = 0.05", "rate=0.05", false},
+		{"x=3.5 = 3.5", "x=3.5", false},
+		// 函数定义保留等号右边；调用和内置函数的结果只取算式
+		{"f(x,y)=x+y", "f(x,y)=x+y", false},
+		{"f(2) = 5", "f(2)", false},
+		{"e = 2.718", "e", false},
+		{"2 & 3", "", true},
+		{"  ", "", true},
+	}
+	for _, tt := range tests {
+		got, err := sanitizePaste(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("sanitizePaste(%q): expected %q (error %v), got %q (%v)", tt.input, tt.want, tt.wantErr, got, err)
+		}
+	}
+
+	t.Setenv("TMPDIR", t.TempDir())
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	s := NewCalcState(testApp.NewWindow("Test Window"))
+	if err := s.PasteText("1,250 * 4"); err != nil {
+		t.Fatalf("PasteText: %v", err)
+	}
+	if got, _ := s.display.Get(); got != "1250×4" {
+		t.Errorf("Paste: expected 1250×4, got %q", got)
+	}
+	if got, _ := s.result.Get(); got != "= 5000" {
+		t.Errorf("Paste preview: expected = 5000, got %q", got)
+	}
+
+	// 输入中复制算式，结果模式下复制结果
+	s.OnCopy()
+	if got := testApp.Clipboard().Content(); got != "1250×4" {
+		t.Errorf("Copy expression: got %q", got)
+	}
+	s.OnEqual()
+	s.OnCopy()
+	if got := testApp.Clipboard().Content(); got != "5000" {
+		t.Errorf("Copy result: got %q", got)
+	}
+}
+
+func TestCaretEditing(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	s := NewCalcState(testApp.NewWindow("Test Window"))
+	for _, c := range []string{"1", "2", "+", "3"} {
+		s.OnTap(c)
+	}
+
+	// 光标移到 + 之前，插入数字和函数
+	s.MoveCaret(-2)
+	s.OnTap("5")
+	s.OnTap("×")
+	s.OnAdvancedTap("sin")
+	if got, _ := s.display.Get(); got != "125×sin(+3" {
+		t.Fatalf("Insert at caret: got %q", got)
+	}
+
+	// 退格删除光标前的字符，删掉 ( 时连同函数名一起删除
+	s.OnBackspace()
+	if got, _ := s.display.Get(); got != "125×+3" {
+		t.Errorf("Backspace at caret: got %q", got)
+	}
+	// 光标前是运算符时，再点运算符替换它
+	s.OnTap("-")
+	if got, _ := s.display.Get(); got != "125-+3" {
+		t.Errorf("Replace operator at caret: got %q", got)
+	}
+
+	// 自动换行插入的 \n 不影响光标位置
+	s.display.Set("125-\n+3")
+	s.MoveCaretTo(1)
+	s.OnTap("0")
+	if got, _ := s.display.Get(); got != "1025-\n+3" || s.caretTail() != 5 {
+		t.Errorf("Caret with wrap: got %q, tail %d", got, s.caretTail())
+	}
+
+	// 按 C 后光标回到末尾
+	s.OnClear()
+	s.OnTap("7")
+	s.OnTap("8")
+	if got, _ := s.display.Get(); got != "78" {
+		t.Errorf("After clear: got %q", got)
+	}
+}
+
+func TestUndoRedo(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	s := NewCalcState(testApp.NewWindow("Test Window"))
+	for _, c := range []string{"1", "+", "2"} {
+		s.OnTap(c)
+	}
+
+	// 撤销 C，恢复刚才清除的算式和预览
+	s.OnClear()
+	s.Undo()
+	if got, _ := s.display.Get(); got != "1+2" {
+		t.Fatalf("Undo clear: expected 1+2, got %q", got)
+	}
+	if got, _ := s.result.Get(); got != "= 3" {
+		t.Errorf("Undo clear: expected = 3, got %q", got)
+	}
+	if mode, _ := s.isResultMode.Get(); mode || s.isNewNumber {
+		t.Errorf("Undo clear: expected input mode, got resultMode=%v newNumber=%v", mode, s.isNewNumber)
+	}
+
+	// 重做再次清除，继续撤销逐步回退
+	s.Redo()
+	if got, _ := s.display.Get(); got != "" {
+		t.Errorf("Redo: expected empty display, got %q", got)
+	}
+	s.Undo()
+	s.Undo()
+	if got, _ := s.display.Get(); got != "1+" {
+		t.Errorf("Undo tap: expected 1+, got %q", got)
+	}
+
+	// 新的编辑清空重做栈
+	s.OnTap("5")
+	s.Redo()
+	if got, _ := s.display.Get(); got != "1+5" {
+		t.Errorf("Redo after edit: expected 1+5, got %q", got)
+	}
+
+	// 撤销栈有上限
+	for i := 0; i < undoLimit+20; i++ {
+		s.OnTap("1")
+	}
+	if len(s.undo.undo) != undoLimit {
+		t.Errorf("Expected %d undo steps, got %d", undoLimit, len(s.undo.undo))
+	}
+}
+
+func TestAnswerVariables(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	s := NewCalcState(testApp.NewWindow("Test Window"))
+	if got := s.Calculate("ans+1"); got != "" {
+		t.Errorf("ans without history should be an unknown name, got %q", got)
+	}
+	for _, expr := range []string{"2×3", "10÷4"} {
+		s.display.Set(expr)
+		s.OnEqual()
+	}
+
+	// 按 = 之后输入运算符，以 ans 开头继续计算
+	s.OnTap("×")
+	s.OnTap("2")
+	if got, _ := s.display.Get(); got != "ans×2" {
+		t.Errorf("Continue after equal: expected ans×2, got %q", got)
+	}
+	if got, _ := s.result.Get(); got != "= 5" {
+		t.Errorf("Continue after equal: expected = 5, got %q", got)
+	}
+	s.OnEqual()
+
+	tests := []struct{ input, want string }{
+		{"ans", "5"},
+		{"ans1", "5"},
+		{"ans2+ans3", "8.5"},
+		{"2ans3", "12"},
+		{"ans01", ""}, // 只接受规范的编号
+		{"ans4", ""},
+	}
+	for _, tt := range tests {
+		if got := s.Calculate(tt.input); got != tt.want {
+			t.Errorf("Calculate(%q): expected %q, got %q", tt.input, tt.want, got)
+		}
+	}
+
+	// 按 C 归档的算式不算作结果
+	s.display.Set("100")
+	s.isNewNumber = false
+	s.OnClear()
+	if got := s.Calculate("ans"); got != "5" {
+		t.Errorf("Cleared expressions should be skipped, got %q", got)
+	}
+
+	// 按 C 之后输入 - 开始一个负数，不接在 ans 后面
+	s.OnTap("-")
+	s.OnTap("3")
+	if got, _ := s.display.Get(); got != "-3" {
+		t.Errorf("Minus after clear: expected -3, got %q", got)
+	}
+
+	// 重新启动后（结果行为 0）也一样
+	s = NewCalcState(testApp.NewWindow("Restart"))
+	s.loadHistory()
+	s.OnTap("-")
+	if got, _ := s.display.Get(); got != "-" {
+		t.Errorf("Minus at startup: expected -, got %q", got)
+	}
+}
+
+func TestVariables(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	s := NewCalcState(testApp.NewWindow("Test Window"))
+
+	// 只输入了名称时，按 = 开始写赋值语句
+	s.OnTap("2")
+	s.display.Set("x")
+	s.OnEqual()
+	if got, _ := s.display.Get(); got != "x=" {
+		t.Fatalf("Equal after a name: expected x=, got %q", got)
+	}
+	s.display.Set("x=3×1.5")
+	s.OnEqual()
+	if got, _ := s.result.Get(); got != "= 4.5" {
+		t.Errorf("Assignment: expected = 4.5, got %q", got)
+	}
+
+	tests := []struct{ input, want string }{
+		{"x+1", "5.5"},
+		{"2x", "9"},
+		{"sin(x×20)", "1"},
+		{"ans", "4.5"}, // 赋值语句的值也是结果
+		{"y=x^2", "20.25"},
+		{"y", ""}, // 预览不会定义变量
+	}
+	for _, tt := range tests {
+		if got := s.Calculate(tt.input); got != tt.want {
+			t.Errorf("Calculate(%q): expected %q, got %q", tt.input, tt.want, got)
+		}
+	}
+
+	// 不能赋值的名称，出错位置指向名称
+	for _, stmt := range []string{"π=3", "sin=1", "ans=2", "x=1÷0"} {
+		if _, err := s.Assign(stmt); err == nil {
+			t.Errorf("Assign(%q) should fail", stmt)
+		}
+	}
+	if _, _, err := s.evaluate("ans2=1"); err == nil || err.Span != (engine.Span{Start: 0, End: 4}) {
+		t.Errorf("Assign to ans2: got %+v", err)
+	}
+	if _, err := s.Assign("rate=x÷100"); err != nil {
+		t.Fatalf("Assign: %v", err)
+	}
+
+	// 重新启动后变量仍然可用，删除后不再可用
+	s = NewCalcState(testApp.NewWindow("Test Window"))
+	if got := s.variableNames(); len(got) != 2 || got[0] != "rate" || got[1] != "x" {
+		t.Fatalf("Variables after restart: got %v", got)
+	}
+	if got := s.Calculate("rate×100"); got != "4.5" {
+		t.Errorf("Variable after restart: got %q", got)
+	}
+	s.DeleteVariable("x")
+	if got := s.Calculate("x"); got != "" {
+		t.Errorf("Deleted variable should be unknown, got %q", got)
+	}
+}
+
+func TestUserFunctions(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	s := NewCalcState(testApp.NewWindow("Test Window"))
+
+	// 输入函数头后按 = 开始写定义
+	s.OnTap("2")
+	s.display.Set("f(x,y")
+	s.OnEqual()
+	if got, _ := s.display.Get(); got != "f(x,y)=" {
+		t.Fatalf("Equal after a function head: expected f(x,y)=, got %q", got)
+	}
+	s.display.Set("f(x,y)=x^2+y")
+	s.OnEqual()
+	if got, _ := s.result.Get(); got != "= 函数 f(x,y)" {
+		t.Errorf("Define: got %q", got)
+	}
+	if _, err := s.Assign("k=10"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Assign("g(t)=f(t,k)÷2"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct{ input, want string }{
+		{"f(3,1)", "10"},
+		{"g(2)", "7"},
+		{"2f(1,1)+1", "5"},
+		{"f(2)", "Error"}, // 参数个数不对
+		{"ans", ""},       // 函数定义不是数值结果
+	}
+	for _, tt := range tests {
+		if got := s.Calculate(tt.input); got != tt.want {
+			t.Errorf("Calculate(%q): expected %q, got %q", tt.input, tt.want, got)
+		}
+	}
+	if _, err := s.Evaluate("f(2)"); err == nil || err.Msg != "f 需要 2 个参数，实际为 1 个" {
+		t.Errorf("Arity message: got %v", err)
+	}
+
+	// 定义好的函数按 = 是调用；不能与变量、内置函数重名；递归调用有层数限制
+	s.display.Set("g(4")
+	s.isNewNumber = false
+	s.isResultMode.Set(false)
+	s.OnEqual()
+	if got, _ := s.result.Get(); got != "= 13" {
+		t.Errorf("Call with equal: got %q", got)
+	}
+	for _, def := range []string{"k(x)=x", "sin(x)=x", "f=2"} {
+		if _, err := s.Assign(def); err == nil {
+			t.Errorf("Assign(%q) should fail", def)
+		}
+	}
+	if _, err := s.Assign("r(x)=r(x-1)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Evaluate("r(3)"); err == nil || err.Kind != engine.KindRecursion {
+		t.Errorf("Recursion: got %v", err)
+	}
+
+	// 重新启动后函数仍然可用，删除后不再可用
+	s = NewCalcState(testApp.NewWindow("Test Window"))
+	if got := s.userFunctionNames(); len(got) != 3 {
+		t.Fatalf("Functions after restart: got %v", got)
+	}
+	if got := s.Calculate("g(2)"); got != "7" {
+		t.Errorf("Function after restart: got %q", got)
+	}
+	s.DeleteFunction("f")
+	if got := s.Calculate("g(2)"); got != "" {
+		t.Errorf("Deleted function should be unknown, got %q", got)
+	}
+
+	// 函数面板的定义：等号右边保留；改名后删除原来的函数；不是函数定义时报错
+	if err := s.DefineFunction(" f(x,y) = x^2 + y*2 ", ""); err != nil {
+		t.Fatalf("DefineFunction: %v", err)
+	}
+	if got := s.Calculate("f(3,1)"); got != "11" {
+		t.Errorf("Function from panel: expected 11, got %q", got)
+	}
+	if err := s.DefineFunction("h(x,y)=x-y", "f"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if _, ok := s.userFunctions["f"]; ok || s.Calculate("h(3,1)") != "2" {
+		t.Errorf("Rename: got %v", s.userFunctionNames())
+	}
+	for _, def := range []string{"f(x,y)", "k=3", "sin(x)=x"} {
+		if err := s.DefineFunction(def, ""); err == nil {
+			t.Errorf("DefineFunction(%q) should fail", def)
+		}
+	}
+}
+
+func TestComplexMode(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	s := NewCalcState(testApp.NewWindow("Test Window"))
+	if _, err := s.Assign("f(x)=x^2+1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Calculate("sqrt(-4)"); got != "Error" {
+		t.Errorf("Real mode sqrt(-4): expected Error, got %q", got)
+	}
+
+	// 实数 → 直角坐标 → 极坐标 → 实数
+	s.OnComplexMode()
+	tests := []struct{ input, want string }{
+		{"sqrt(-4)", "2i"},
+		{"(1+2i)×(3-i)", "5+5i"},
+		{"2i", "2i"},
+		{"f(i)", "0"},    // 自定义函数也接受复数参数
+		{"3!", "6"},      // 没有复数实现的函数在实数参数下照常计算
+		{"(2i)!", "Error"},
+	}
+	for _, tt := range tests {
+		if got := s.Calculate(tt.input); got != tt.want {
+			t.Errorf("Complex Calculate(%q): expected %q, got %q", tt.input, tt.want, got)
+		}
+	}
+	if _, err := s.Evaluate("z=1+i"); err == nil {
+		t.Error("Assigning a complex value should fail")
+	}
+
+	s.OnComplexMode()
+	if got := s.Calculate("1+i"); got != "1.4142135623730951∠45°" {
+		t.Errorf("Polar: got %q", got)
+	}
+
+	// 模式在重新启动后保持
+	s = NewCalcState(testApp.NewWindow("Test Window"))
+	if c, _ := s.isComplex.Get(); !c {
+		t.Error("Complex mode should persist")
+	}
+	s.OnComplexMode()
+	if c, _ := s.isComplex.Get(); c {
+		t.Error("Third toggle should return to real mode")
+	}
+	if got := s.Calculate("2i"); got != "" {
+		t.Errorf("Real mode 2i: expected unknown name, got %q", got)
+	}
+}
+
+func TestProgrammer(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	s := NewCalcState(testApp.NewWindow("Test Window"))
+	p := newProgrammer(s)
+	p.keypad()
+	p.setActive(true)
+
+	// 键盘输入经过 CalcState 转给程序员界面
+	for _, r := range "255" {
+		p.typeRune(r)
+	}
+	s.OnTap("+")
+	s.OnTap("1")
+	if p.value != 256 || p.valueLabels[16].Text != "100" || p.valueLabels[2].Text != "1 0000 0000" {
+		t.Errorf("255+1: value %d, HEX %q, BIN %q", p.value, p.valueLabels[16].Text, p.valueLabels[2].Text)
+	}
+
+	// 切换到 8 位无符号时截断，算式替换为截断后的值
+	p.signedCheck.SetChecked(false)
+	p.sizeSelect.SetSelected("8 位")
+	if p.input != "0" || p.value != 0 {
+		t.Errorf("Wrap to 8 bits: input %q, value %d", p.input, p.value)
+	}
+
+	// 十六进制输入，位运算符单词整个删除
+	p.setBase(16)
+	for _, r := range "f0&3c" {
+		p.typeRune(r)
+	}
+	if p.input != "F0 AND 3C" {
+		t.Fatalf("Hex input: got %q", p.input)
+	}
+	s.OnBackspace()
+	s.OnBackspace()
+	s.OnBackspace()
+	if p.input != "F0" {
+		t.Errorf("Backspace over operator: got %q", p.input)
+	}
+	p.onInput("ROL")
+	p.onInput("4")
+	s.OnEqual()
+	if p.input != "F" || p.valueLabels[10].Text != "15" {
+		t.Errorf("F0 ROL 4: input %q, DEC %q", p.input, p.valueLabels[10].Text)
+	}
+
+	// 二进制中 2 无效；sin 等科学函数在程序员界面中不起作用
+	p.setBase(2)
+	p.onInput("2")
+	s.OnAdvancedTap("sin")
+	if p.input != "1111" || !p.digitBtns["2"].Disabled() || p.digitBtns["1"].Disabled() {
+		t.Errorf("Binary input: got %q", p.input)
+	}
+	if got, _ := s.display.Get(); got != "" {
+		t.Errorf("Calculator display should stay untouched, got %q", got)
+	}
+
+	// 重新打开时恢复位宽、符号和进制
+	p = newProgrammer(s)
+	if p.word != (integer.Word{Bits: 8}) || p.base != 2 {
+		t.Errorf("Settings after restart: %+v base %d", p.word, p.base)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	s := NewCalcState(testApp.NewWindow("Test Window"))
+
+	// 在结果上按 % 打开平摊，键盘输入人数；整数默认按整数平摊
+	s.OnTap("1")
+	s.OnTap("0")
+	s.OnTap("0")
+	s.OnEqual()
+	s.OnTap("%")
+	if !s.isInterceptingForScore {
+		t.Fatal("% on a result should open the split overlay")
+	}
+	s.OnTap("3")
+	if got, _ := s.result.Get(); got != "合计 100 | 1人34, 2人33" {
+		t.Errorf("Integer split: got %q", got)
+	}
+	s.OnClear()
+	if got, _ := s.result.Get(); s.isInterceptingForScore || got != "= 100" {
+		t.Errorf("C should close the overlay and restore the result, got %q", got)
+	}
+	if records := s.historyRecords(); len(records) != 2 || records[1].Split == nil {
+		t.Errorf("Closing should save the finished split, got %+v", records)
+	}
+
+	// 小数金额、小费、名字和份数
+	p := newSplitter(s, "= 99.9", split.Bill{Amount: 99.9})
+	p.show()
+	p.onInput("3")
+	p.tipEntry.SetText("10")
+	p.rows.Objects[0].(*fyne.Container).Objects[0].(*widget.Entry).SetText("张三")
+	p.rows.Objects[0].(*fyne.Container).Objects[1].(*widget.Entry).SetText("2")
+	if got, _ := s.result.Get(); got != "合计 109.89 | 张三 54.95, 第 2 人 27.47, 第 3 人 27.47" {
+		t.Errorf("Named split: got %q", got)
+	}
+	p.roundSelect.SetSelected(split.RoundHalf.String())
+	if got, _ := s.result.Get(); got != "合计 109.89 | 张三 55, 第 2 人 27.5, 第 3 人 27.5" {
+		t.Errorf("Half rounding: got %q", got)
+	}
+	p.tipEntry.SetText("abc")
+	if got, _ := s.result.Get(); got != "小费无效" || !p.saveBtn.Disabled() {
+		t.Errorf("Invalid tip: got %q", got)
+	}
+	p.tipEntry.SetText("")
+
+	// 复制明细，存入历史
+	test.Tap(p.copyBtn)
+	if got := testApp.Clipboard().Content(); !strings.HasPrefix(got, "金额 99.9\n合计 99.9\n张三 ×2 50\n") {
+		t.Errorf("Copied breakdown: got %q", got)
+	}
+	test.Tap(p.saveBtn)
+	records := s.historyRecords()
+	if len(records) != 3 || records[2].Expression != "平摊 99.9（3 人）" || records[2].Result != "张三 50, 第 2 人 25, 第 3 人 25" {
+		t.Errorf("Saved split: got %+v", records)
+	}
+	p.onInput("⌫")
+	if got, _ := s.result.Get(); got != "请输入人数" {
+		t.Errorf("Without people: got %q", got)
+	}
+	p.close()
+	if n := len(s.historyRecords()); n != 3 {
+		t.Errorf("Closing without a valid split should not save, got %d records", n)
+	}
+}
+
+func TestSplitHistory(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	s := NewCalcState(testApp.NewWindow("Test Window"))
+	p := newSplitter(s, "= 0", split.Bill{Amount: 120})
+	p.show()
+	p.onInput("2")
+	p.rows.Objects[0].(*fyne.Container).Objects[0].(*widget.Entry).SetText("张三")
+	p.rows.Objects[0].(*fyne.Container).Objects[1].(*widget.Entry).SetText("3")
+	p.serviceEntry.SetText("5")
+	p.close()
+
+	// 重新启动后平摊记录带有完整的明细
+	s = NewCalcState(testApp.NewWindow("Test Window"))
+	s.loadHistory()
+	records := s.historyRecords()
+	if len(records) != 1 || !records[0].HasTag(history.TagSplit) || records[0].Split == nil {
+		t.Fatalf("Expected one split record, got %+v", records)
+	}
+	rec := records[0]
+	want := &history.Split{Amount: 120, Service: 5, Total: 12600, Shares: []history.SplitShare{
+		{Name: "张三", Weight: 3, Amount: 9450},
+		{Weight: 1, Amount: 3150},
+	}}
+	if !reflect.DeepEqual(rec.Split, want) {
+		t.Errorf("Split record: got %+v", rec.Split)
+	}
+
+	// 重新打开后界面恢复原来的设置，不修改时关闭不会重复保存
+	s.reopenSplit(rec)
+	if got, _ := s.result.Get(); !s.isInterceptingForScore || got != "合计 126.00 | 张三 94.50, 第 2 人 31.50" {
+		t.Fatalf("Reopened split: got %q", got)
+	}
+	s.OnClear()
+	if n := len(s.historyRecords()); n != 1 {
+		t.Errorf("Unchanged split should not be saved again, got %d records", n)
+	}
+
+	// 调整后另存一条，原来的记录不变
+	s.reopenSplit(rec)
+	s.OnBackspace()
+	s.OnTap("3")
+	s.OnClear()
+	records = s.historyRecords()
+	if len(records) != 2 || records[1].Expression != "平摊 126.00（3 人）" || records[1].Result != "张三 75.60, 第 2 人 25.20, 第 3 人 25.20" {
+		t.Errorf("Adjusted split: got %+v", records[1:])
+	}
+	if !reflect.DeepEqual(records[0].Split, want) {
+		t.Errorf("Original record should be unchanged, got %+v", records[0].Split)
+	}
+}
+
+func TestStatistics(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	s := NewCalcState(testApp.NewWindow("Test Window"))
+	p := newStatsPanel(s)
+	p.setActive(true)
+
+	// 键盘输入经过 CalcState 转给统计界面，可以输入算式
+	enter := func(input string) {
+		for _, r := range input {
+			s.OnTap(string(r))
+		}
+		s.OnEqual()
+	}
+	for _, v := range []string{"2", "4", "4", "4", "5", "5", "7", "3×3"} {
+		enter(v)
+	}
+	if len(p.xs) != 8 || p.xs[7] != 9 {
+		t.Fatalf("Entered data: got %v", p.xs)
+	}
+	report := p.resultLabel.Text
+	for _, want := range []string{"n = 8", "平均数 = 5", "中位数 = 4.5", "众数 = 4", "极差 = 7", "Q1 = 4  Q3 = 6", "σ² = 4  σ = 2"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Report should contain %q, got:\n%s", want, report)
+		}
+	}
+
+	// 修改第一项，再删除最后一项
+	p.list.Select(0)
+	s.OnClear()
+	enter("3")
+	if p.xs[0] != 3 || p.editing != -1 {
+		t.Errorf("Edit: got %v, editing %d", p.xs, p.editing)
+	}
+	p.list.Select(7)
+	test.Tap(p.deleteBtn)
+	if len(p.xs) != 7 || p.xs[6] != 7 {
+		t.Errorf("Delete: got %v", p.xs)
+	}
+	enter("1÷0")
+	if p.msgLabel.Text == "" || len(p.xs) != 7 {
+		t.Errorf("Invalid value should be rejected, got %v", p.xs)
+	}
+
+	// 成对数据：先输入 x，按 = 后输入 y
+	p.clear()
+	p.pairedCheck.SetChecked(true)
+	for _, pair := range [][2]string{{"1", "4"}, {"2", "2"}, {"3", "0"}} {
+		enter(pair[0])
+		enter(pair[1])
+	}
+	if !strings.Contains(p.resultLabel.Text, "y = 6 - 2x") || !strings.Contains(p.resultLabel.Text, "r = -1  r² = 1") {
+		t.Errorf("Regression: got:\n%s", p.resultLabel.Text)
+	}
+
+	// 数据在重新打开后恢复
+	restored := newStatsPanel(s)
+	if !restored.paired || !reflect.DeepEqual(restored.ys, []float64{4, 2, 0}) {
+		t.Errorf("Restored data: got %v %v paired=%v", restored.xs, restored.ys, restored.paired)
+	}
+	p.setActive(false)
+}
+
+func TestPlot(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	s := NewCalcState(testApp.NewWindow("Test Window"))
+	s.SetVariable("a", 2)
+	p := newPlotter(s)
+	p.win.Resize(fyne.NewSize(400, 640))
+
+	// 算式可以写成 y=…，使用计算界面的变量；再添加一条曲线
+	p.curves[0].entry.SetText("y = x^2 - a")
+	p.addCurve("x")
+	if len(p.graph.curves) != 2 || p.info.Text != "" {
+		t.Fatalf("Expected 2 curves, got %d (%q)", len(p.graph.curves), p.info.Text)
+	}
+
+	p.findRoots()
+	if want := "y1：x = -1.4142136, 1.4142136\ny2：x = 0"; p.info.Text != want || len(p.graph.marks) != 3 {
+		t.Errorf("Roots: got %q with %d marks", p.info.Text, len(p.graph.marks))
+	}
+	p.findIntersections()
+	if want := "y1 与 y2：(-1, -1), (2, 2)"; p.info.Text != want {
+		t.Errorf("Intersections: got %q", p.info.Text)
+	}
+
+	// 点击图像追踪，坐标范围默认为 -10~10
+	size := p.graph.Size()
+	p.graph.Tapped(&fyne.PointEvent{Position: fyne.NewPos(size.Width*0.6, size.Height/2)})
+	if want := "x = 2   y1 = 2   y2 = 2"; p.info.Text != want {
+		t.Errorf("Trace: got %q", p.info.Text)
+	}
+
+	// 拖动平移，缩放以中心为准
+	p.graph.Dragged(&fyne.DragEvent{Dragged: fyne.Delta{DX: size.Width / 2}})
+	if v := p.graph.view; v.XMin != -20 || v.XMax != 0 {
+		t.Errorf("Pan: got %+v", v)
+	}
+
+	// 错误的算式显示原因，不影响其他曲线
+	p.curves[1].entry.SetText("foo(x)")
+	if len(p.graph.curves) != 1 || !strings.HasPrefix(p.info.Text, "y2：") {
+		t.Errorf("Invalid curve: got %d curves, %q", len(p.graph.curves), p.info.Text)
+	}
+
+	// 算式在重新打开后恢复
+	if got := newPlotter(s); len(got.curves) != 2 || got.curves[1].entry.Text != "foo(x)" {
+		t.Errorf("Restored curves: got %d", len(got.curves))
+	}
+	p.win.Close()
+}
+
+func TestSolver(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	s := NewCalcState(testApp.NewWindow("Test Window"))
+	s.SetVariable("a", 2)
+	p := newSolver(s)
+	p.win.Resize(fyne.NewSize(400, 520))
+
+	// 方程的两边都可以有 x，使用计算界面的变量
+	p.equation.SetText("x^2 = a")
+	p.findAll()
+	if want := "x1 = -1.41421356237\nx2 = 1.41421356237"; p.rootInfo.Text != want {
+		t.Errorf("All roots: Expected %q, got %q", want, p.rootInfo.Text)
+	}
+	// 从初值出发求最近的根，范围可以写算式
+	p.bounds[0].SetText("0")
+	p.bounds[1].SetText("2π")
+	p.bounds[2].SetText("3")
+	p.equation.SetText("sin(x)")
+	s.isRadian.Set(true)
+	p.findRoot()
+	if want := "x = 3.14159265359"; !strings.HasPrefix(p.rootInfo.Text, want) {
+		t.Errorf("Root near 3: Expected %q, got %q", want, p.rootInfo.Text)
+	}
+	for _, c := range [][2]string{
+		{"x^2 = -1", solve.ErrNoRoot.Error()},
+		{"foo(x)", "方程："},
+		{"x = 1 = 2", "方程：方程中只能有一个 ="},
+	} {
+		text, want := c[0], c[1]
+		p.equation.SetText(text)
+		p.findAll()
+		if !strings.HasPrefix(p.rootInfo.Text, want) {
+			t.Errorf("%q: Expected %q, got %q", text, want, p.rootInfo.Text)
+		}
+	}
+
+	// 多项式：有理数根以分数显示，复数根成对列出
+	p.degree.SetSelectedIndex(2)
+	if p.formula.Text != "ax³ + bx² + cx + d = 0" {
+		t.Errorf("Formula: got %q", p.formula.Text)
+	}
+	for i, c := range []string{"2", "-3", "-3", "2"} {
+		p.coeffs[1+i].SetText(c)
+	}
+	p.solvePolynomial()
+	if want := "x1 = -1\nx2 = 1/2 = 0.5\nx3 = 2"; p.polyInfo.Text != want {
+		t.Errorf("Cubic: Expected %q, got %q", want, p.polyInfo.Text)
+	}
+	p.degree.SetSelectedIndex(1)
+	for i, c := range []string{"1", "2", "5"} {
+		p.coeffs[2+i].SetText(c)
+	}
+	p.solvePolynomial()
+	if want := "x1 = -1-2i\nx2 = -1+2i"; p.polyInfo.Text != want {
+		t.Errorf("Complex roots: Expected %q, got %q", want, p.polyInfo.Text)
+	}
+	p.coeffs[2].SetText("0")
+	p.solvePolynomial()
+	if p.polyInfo.Text != "a 不能为 0" {
+		t.Errorf("Leading zero: got %q", p.polyInfo.Text)
+	}
+
+	// 方程组：分数精确求解，奇异时说明原因
+	p.size.SetSelectedIndex(0)
+	for i, row := range [][]string{{"1", "1", "1"}, {"1", "-2", "0"}} {
+		for j, c := range row {
+			p.cells[i][j].SetText(c)
+		}
+	}
+	p.solveSystem()
+	if want := "x1 = 2/3 ≈ 0.666666666667\nx2 = 1/3 ≈ 0.333333333333"; p.systemInfo.Text != want {
+		t.Errorf("System: Expected %q, got %q", want, p.systemInfo.Text)
+	}
+	p.cells[1][0].SetText("2")
+	p.cells[1][1].SetText("2")
+	p.solveSystem()
+	if p.systemInfo.Text != solve.ErrNoSolution.Error() {
+		t.Errorf("Singular system: got %q", p.systemInfo.Text)
+	}
+
+	// 输入在重新打开后恢复
+	got := newSolver(s)
+	if got.equation.Text != "x = 1 = 2" || got.bounds[1].Text != "2π" || got.polyDegree() != 2 ||
+		got.coeffs[3].Text != "2" || got.systemSize() != 2 || got.cells[1][0].Text != "2" {
+		t.Errorf("Restored inputs: got %q %q degree %d size %d", got.equation.Text, got.bounds[1].Text, got.polyDegree(), got.systemSize())
+	}
+	p.win.Close()
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 光标用距末尾的字符数表示（不计自动换行插入的 \n），0 表示在末尾。
+// 这样在末尾追加内容、或者自动换行改写算式时，光标的位置都不受影响
+
+// 光标在 text 中的 rune 下标（计入 \n）
+func caretIndex(text string, tail int) int {
+	runes := []rune(text)
+	i := len(runes)
+	for n := 0; n < tail && i > 0; {
+		i--
+		if runes[i] != '\n' {
+			n++
+		}
+	}
+	return i
+}
+
+// 算式的字符数，不计自动换行插入的 \n
+func logicalLen(text string) int {
+	return len([]rune(text)) - strings.Count(text, "\n")
+}
+
+// 当前光标距末尾的字符数，超出算式长度时按开头处理
+func (s *CalcState) caretTail() int {
+	tail, _ := s.caret.Get()
+	current, _ := s.display.Get()
+	return max(0, min(tail, logicalLen(current)))
+}
+
+// 把光标移到末尾
+func (s *CalcState) resetCaret() {
+	s.caret.Set(0)
+}
+
+// 在光标处插入文字，返回插入后的算式；光标位于插入内容之后
+func (s *CalcState) insertAtCaret(text string) string {
+	current, _ := s.display.Get()
+	runes := []rune(current)
+	i := caretIndex(current, s.caretTail())
+	newEq := string(runes[:i]) + text + string(runes[i:])
+	s.display.Set(newEq)
+	return newEq
+}
+
+// 删除光标前的一个字符（跳过 \n），返回删除的字符，光标在开头时返回 0
+func (s *CalcState) deleteBeforeCaret() rune {
+	current, _ := s.display.Get()
+	runes := []rune(current)
+	for i := caretIndex(current, s.caretTail()) - 1; i >= 0; i-- {
+		if runes[i] != '\n' {
+			s.display.Set(string(runes[:i]) + string(runes[i+1:]))
+			return runes[i]
+		}
+	}
+	return 0
+}
+
+// 删除光标前残余的函数名，如删掉 sin( 的 ( 后，把 sin 也删掉
+func (s *CalcState) trimFunctionBeforeCaret() {
+	current, _ := s.display.Get()
+	runes := []rune(current)
+	i := caretIndex(current, s.caretTail())
+	before := trimFunctionName(string(runes[:i]))
+	s.display.Set(before + string(runes[i:]))
+}
+
+// 光标前的一个字符（跳过 \n），没有时返回 0
+func (s *CalcState) runeBeforeCaret() rune {
+	current, _ := s.display.Get()
+	runes := []rune(current)
+	for i := caretIndex(current, s.caretTail()) - 1; i >= 0; i-- {
+		if runes[i] != '\n' {
+			return runes[i]
+		}
+	}
+	return 0
+}
+
+// 把光标左右移动 delta 个字符
+func (s *CalcState) MoveCaret(delta int) {
+	if s.isTakenOver() {
+		return
+	}
+	s.editFromResult()
+	current, _ := s.display.Get()
+	s.caret.Set(max(0, min(s.caretTail()-delta, logicalLen(current))))
+}
+
+// 把光标移到第 index 个字符之前（不计 \n），超出范围时移到末尾
+func (s *CalcState) MoveCaretTo(index int) {
+	if s.isTakenOver() {
+		return
+	}
+	s.editFromResult()
+	current, _ := s.display.Get()
+	n := logicalLen(current)
+	s.caret.Set(max(0, n-max(0, min(index, n))))
+}
+
+// 结果模式下移动光标，表示要修改刚才的算式：回到输入模式
+func (s *CalcState) editFromResult() {
+	if isResult, _ := s.isResultMode.Get(); isResult {
+		s.isNewNumber = false
+		s.isResultMode.Set(false)
+	}
+}
+
+// 可以点击定位光标的输入框
+type caretInput struct {
+	widget.RichText
+	text     string           // 显示的算式（不含光标），用于计算点击位置
+	onTapped func(index int)  // 参数是点击位置对应的字符下标（不计 \n）
+	onSwiped func(dx float32) // 水平滑动超过 swipeDistance 时调用，dx 为滑动距离
+
+	dragX float32 // 本次拖动累计的水平距离
+}
+
+// 判定为滑动手势的最小水平距离
+const swipeDistance = 60
+
+// 累计拖动距离
+func (t *caretInput) Dragged(ev *fyne.DragEvent) {
+	t.dragX += ev.Dragged.DX
+}
+
+// 拖动结束时判断是否为滑动手势
+func (t *caretInput) DragEnd() {
+	dx := t.dragX
+	t.dragX = 0
+	if t.onSwiped != nil && (dx >= swipeDistance || dx <= -swipeDistance) {
+		t.onSwiped(dx)
+	}
+}
+
+func newCaretInput() *caretInput {
+	t := &caretInput{}
+	t.ExtendBaseWidget(t)
+	return t
+}
+
+// 根据点击位置找到最近的字符间隙：算式按 \n 分行，每行靠右对齐
+func (t *caretInput) Tapped(ev *fyne.PointEvent) {
+	if t.onTapped == nil {
+		return
+	}
+	lines := strings.Split(t.text, "\n")
+	pad := theme.InnerPadding()
+	rowHeight := (t.Size().Height - 2*pad) / float32(len(lines))
+	row := 0
+	if rowHeight > 0 {
+		row = max(0, min(int((ev.Position.Y-pad)/rowHeight), len(lines)-1))
+	}
+
+	size := getRichInputFontSize()
+	style := fyne.TextStyle{Bold: true}
+	line := []rune(lines[row])
+	x := ev.Position.X - (t.Size().Width - pad - fyne.MeasureText(lines[row], size, style).Width)
+	col := len(line)
+	for i := range line {
+		left := fyne.MeasureText(string(line[:i]), size, style).Width
+		right := fyne.MeasureText(string(line[:i+1]), size, style).Width
+		if x < (left+right)/2 {
+			col = i
+			break
+		}
+	}
+
+	index := col
+	for _, l := range lines[:row] {
+		index += len([]rune(l))
+	}
+	t.onTapped(index)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+
+	"github.com/gzjjjfree/MemoryCalculator/calc/engine"
+)
+
+// 粘贴时按键盘写法输入的符号，换成算式中使用的符号
+var pasteReplacer = strings.NewReplacer(
+	"*", "×", "/", "÷", "·", "×",
+	"−", "-", "–", "-", "—", "-",
+)
+
+// 千位分隔符：1 到 3 位数字后跟若干组 ",ddd"，如 1,234,567.89
+var thousandsPattern = regexp.MustCompile(`\d{1,3}(?:[,']\d{3})+`)
+
+// 粘贴时直接去掉的货币符号（Unicode 的 Sc 类之外的）
+const currencyWords = "元"
+
+// 整理剪贴板中的文字，返回可以放入输入框的算式：
+// 去掉空白、货币符号和千位分隔符，全角字符换成半角，*、/ 换成 ×、÷；
+// "算式 = 结果" 形式只保留算式，赋值语句和函数定义保留；含有不支持的字符时返回错误
+func sanitizePaste(text string) (string, error) {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r), unicode.Is(unicode.Sc, r), strings.ContainsRune(currencyWords, r):
+			continue
+		case r >= '！' && r <= '～': // 全角 ASCII
+			r -= '！' - '!'
+		}
+		b.WriteRune(r)
+	}
+	expr := pasteReplacer.Replace(b.String())
+
+	// 从历史复制的 "2×3 = 6"，只取最后一个等号前的算式；
+	// "x = 3.5" 形式的赋值语句和 "f(x,y) = x+y" 形式的函数定义保留，由求值引擎判断等号左边
+	if i := strings.LastIndex(expr, "="); i >= 0 {
+		if a, err := engine.ParseAssignment(expr, engine.Options{}); strings.Index(expr, "=") < i || a == nil || err != nil {
+			expr = expr[:i]
+		}
+	}
+
+	expr = stripThousands(expr)
+
+	if expr == "" {
+		return "", fmt.Errorf("剪贴板中没有算式")
+	}
+	for _, r := range expr {
+		if !unicode.IsDigit(r) && !(r < unicode.MaxASCII && unicode.IsLetter(r)) &&
+			!strings.ContainsRune(".,_+-×÷^%!()π=", r) {
+			return "", fmt.Errorf("不支持的字符 %q", r)
+		}
+	}
+	return expr, nil
+}
+
+// 去掉千位分隔符；函数调用的括号中的逗号是参数之间的分隔，保留不动，
+// 如 pow(2,300) 不会变成 pow(2300)，而 (1,234+1) 中的 1,234 仍按千位分隔处理
+func stripThousands(expr string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range thousandsPattern.FindAllStringIndex(expr, -1) {
+		text := expr[m[0]:m[1]]
+		if strings.Contains(text, ",") && insideCall(expr[:m[0]]) {
+			continue
+		}
+		b.WriteString(expr[last:m[0]])
+		b.WriteString(strings.NewReplacer(",", "", "'", "").Replace(text))
+		last = m[1]
+	}
+	b.WriteString(expr[last:])
+	return b.String()
+}
+
+// 判断 prefix 之后的位置是否在函数调用的括号中：最内层未闭合的 ( 前面紧挨着函数名
+func insideCall(prefix string) bool {
+	depth := 0
+	for i := len(prefix) - 1; i >= 0; i-- {
+		switch prefix[i] {
+		case ')':
+			depth++
+		case '(':
+			if depth > 0 {
+				depth--
+				continue
+			}
+			// 向前取出字母、数字和下划线组成的名称，以字母或下划线开头才是函数名（log10 是，2 不是）
+			j := i
+			for j > 0 && isNameByte(prefix[j-1]) {
+				j--
+			}
+			return j < i && (prefix[j] < '0' || prefix[j] > '9')
+		}
+	}
+	return false
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+// 当前可以复制的内容：结果模式下是结果的值，输入中是算式
+func (s *CalcState) copyText() string {
+	if isResult, _ := s.isResultMode.Get(); isResult {
+		result, _ := s.result.Get()
+		if result != "0" && strings.HasPrefix(result, "= ") {
+			return strings.TrimPrefix(result, "= ")
+		}
+	}
+	current, _ := s.display.Get()
+	return strings.ReplaceAll(current, "\n", "") // 去掉自动换行插入的 \n
+}
+
+// 复制当前的结果或算式到剪贴板
+func (s *CalcState) OnCopy() {
+	if text := s.copyText(); text != "" {
+		fyne.CurrentApp().Clipboard().SetContent(text)
+	}
+}
+
+// 把粘贴的文字放入输入框：新算式时替换，否则插入在光标处，并立即显示计算结果
+// 文字无法整理为算式时返回错误，输入框保持不变
+func (s *CalcState) PasteText(text string) error {
+	// 平摊、换算、统计或程序员界面打开时不响应
+	if s.isTakenOver() {
+		return nil
+	}
+	expr, err := sanitizePaste(text)
+	if err != nil {
+		return err
+	}
+	defer s.beginEdit()()
+
+	if s.isNewNumber {
+		s.display.Set("")
+		s.resetCaret()
+		s.isNewNumber = false
+	}
+	s.isResultMode.Set(false)
+	current := s.insertAtCaret(expr)
+
+	// 粘贴的算式是完整的，未知名称等语法错误也直接标出
+	if res, evalErr := s.Evaluate(current); evalErr != nil {
+		s.showError(evalErr)
+	} else {
+		s.errorSpan.Set(engine.Span{})
+		s.result.Set("= " + res)
+	}
+	return nil
+}
+
+// 从剪贴板粘贴，无法粘贴时提示原因
+func (s *CalcState) OnPaste() {
+	if err := s.PasteText(fyne.CurrentApp().Clipboard().Content()); err != nil && s.win != nil {
+		dialog.ShowError(fmt.Errorf("无法粘贴：%w", err), s.win)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/gzjjjfree/MemoryCalculator/calc/engine"
+)
+
+// 保存自定义函数时使用的 Preferences 键名
+const prefFunctionDefinitions = "functions.definitions" // []string，每个函数的定义，如 "f(x,y)=x^2+y"
+
+// 从 Preferences 恢复上次保存的自定义函数，无法解析的定义会被忽略
+func (s *CalcState) loadFunctions() {
+	s.userFunctions = map[string]engine.Function{}
+	s.rebuildFunctions()
+	app := fyne.CurrentApp()
+	if app == nil {
+		return
+	}
+	for _, def := range app.Preferences().StringList(prefFunctionDefinitions) {
+		a, err := engine.ParseAssignment(def, s.engineOptions())
+		if err != nil || a == nil || !a.IsFunction() {
+			continue
+		}
+		if fn, err := engine.Define(a, s.engineOptions()); err == nil {
+			s.userFunctions[a.Name] = fn
+			s.rebuildFunctions()
+		}
+	}
+}
+
+// 保存全部自定义函数
+func (s *CalcState) saveFunctions() {
+	names := s.userFunctionNames()
+	defs := make([]string, len(names))
+	for i, name := range names {
+		defs[i] = functionDefinition(name, s.userFunctions[name])
+	}
+	if app := fyne.CurrentApp(); app != nil {
+		app.Preferences().SetStringList(prefFunctionDefinitions, defs)
+	}
+}
+
+// 重新生成传给求值引擎的函数表：内置函数加上自定义函数，并清空按旧函数表缓存的解析结果
+func (s *CalcState) rebuildFunctions() {
+	s.evalCache.Reset()
+	s.functions = engine.DefaultFunctions()
+	for name, fn := range s.userFunctions {
+		s.functions[name] = fn
+	}
+}
+
+// 按名称排序的自定义函数名
+func (s *CalcState) userFunctionNames() []string {
+	names := make([]string, 0, len(s.userFunctions))
+	for name := range s.userFunctions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 定义或修改一个自定义函数
+func (s *CalcState) SetFunction(name string, fn engine.Function) {
+	s.userFunctions[name] = fn
+	s.rebuildFunctions()
+	s.saveFunctions()
+}
+
+// 删除一个自定义函数
+func (s *CalcState) DeleteFunction(name string) {
+	delete(s.userFunctions, name)
+	s.rebuildFunctions()
+	s.saveFunctions()
+}
+
+// 按函数面板中输入的定义新建或修改函数，old 是正在修改的函数名（新建时为空），改名后删除原来的函数
+// 定义直接交给求值引擎，不经过粘贴的整理（那会把等号右边当作结果去掉）
+func (s *CalcState) DefineFunction(def, old string) error {
+	def = strings.TrimSpace(def)
+	a, err := engine.ParseAssignment(def, s.engineOptions())
+	if err != nil {
+		return err
+	}
+	if a == nil || !a.IsFunction() {
+		return engine.NewError(engine.KindSyntax, "函数的定义应当写作 f(x)=算式")
+	}
+	if _, err := s.Assign(def); err != nil {
+		return err
+	}
+	if old != "" && old != a.Name {
+		s.DeleteFunction(old)
+	}
+	return nil
+}
+
+// 函数头，如 f(x,y)
+func functionSignature(name string, fn engine.Function) string {
+	return name + "(" + strings.Join(fn.Params, ",") + ")"
+}
+
+// 函数的完整定义，如 f(x,y)=x^2+y
+func functionDefinition(name string, fn engine.Function) string {
+	return functionSignature(name, fn) + "=" + fn.Body
+}
+
+// 显示函数面板：列出全部自定义函数，点击插入调用，可以新建、修改和删除
+func showFunctions(state *CalcState) {
+	p := &definitionPanel{
+		title: "函数",
+		hint:  "还没有自定义函数，可以输入 f(x)=x^2+1 后按 = 定义",
+		names: state.userFunctionNames,
+		label: func(name string) string {
+			return functionSignature(name, state.userFunctions[name]) + " = " + state.userFunctions[name].Body
+		},
+		insert: func(name string) string { return name + "(" },
+		remove: state.DeleteFunction,
+	}
+	// 新建或修改函数，name 为空时新建；修改时可以改名或增减参数
+	p.edit = func(name string) {
+		entry := widget.NewEntry()
+		entry.SetPlaceHolder("如 f(x,y)=x^2+y")
+		if name != "" {
+			entry.SetText(functionDefinition(name, state.userFunctions[name]))
+		}
+		items := []*widget.FormItem{widget.NewFormItem("定义", entry)}
+		dialog.ShowForm("函数", "确定", "取消", items, func(ok bool) {
+			if !ok {
+				return
+			}
+			if err := state.DefineFunction(entry.Text, name); err != nil {
+				dialog.ShowError(err, p.win)
+				return
+			}
+			p.reload()
+		}, p.win)
+	}
+	p.show(state)
+}
+
+// 键盘上的函数选择：列出自定义函数，点击插入调用
+func showFunctionPicker(state *CalcState, anchor fyne.CanvasObject) {
+	var items []*fyne.MenuItem
+	for _, name := range state.userFunctionNames() {
+		items = append(items, fyne.NewMenuItem(functionSignature(name, state.userFunctions[name]), func() {
+			state.OnAdvancedTap(name + "(")
+		}))
+	}
+	if len(items) == 0 {
+		none := fyne.NewMenuItem("还没有自定义函数", nil)
+		none.Disabled = true
+		items = append(items, none)
+	}
+	items = append(items, fyne.NewMenuItemSeparator(), fyne.NewMenuItem("管理函数…", func() { showFunctions(state) }))
+	widget.ShowPopUpMenuAtRelativePosition(fyne.NewMenu("", items...), state.win.Canvas(),
+		fyne.NewPos(0, anchor.Size().Height), anchor)
+}
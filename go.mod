@@ -4,7 +4,6 @@ go 1.24
 
 require (
 	fyne.io/fyne/v2 v2.7.2
-	github.com/Knetic/govaluate v3.0.0+incompatible
 )
 
 require github.com/goki/freetype v0.0.0-20181231101311-fa8a33aabaff // indirect
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/gzjjjfree/MemoryCalculator/calc/engine"
+	"github.com/gzjjjfree/MemoryCalculator/calc/history"
+)
+
+// 全部历史窗口中的一行：日期标题或者一条记录
+type historyRow struct {
+	section int // 所属的日期分组
+	record  int // 分组内的下标，-1 表示日期标题
+}
+
+// 全部历史窗口：顶部搜索栏，中间按日期分组、可以展开收起的列表，底部清除按钮
+type historyView struct {
+	state  *CalcState
+	win    fyne.Window
+	filter history.Filter
+
+	sections []history.Section // 筛选后的分组
+	expanded map[string]bool   // 展开的日期
+	rows     []historyRow      // 当前显示的行
+
+	list        *widget.List
+	filterLabel *widget.Label // 显示数值和日期范围条件
+}
+
+// 打开全部历史窗口
+func showFullHistory(state *CalcState) {
+	v := &historyView{state: state, expanded: map[string]bool{}}
+	v.win = fyne.CurrentApp().NewWindow("全部历史记录")
+	v.win.Resize(fyne.NewSize(360, 640))
+
+	v.list = widget.NewList(
+		func() int {
+			return len(v.rows)
+		},
+		func() fyne.CanvasObject {
+			return newAllHistoryClickable()
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			v.bindRow(v.rows[id], item.(*allHistoryClickable))
+		},
+	)
+
+	search := widget.NewEntry()
+	search.SetPlaceHolder("搜索算式或结果")
+	search.OnChanged = func(text string) {
+		v.filter.Text = text
+		v.apply()
+	}
+	filterBtn := widget.NewButtonWithIcon("", theme.MenuDropDownIcon(), v.showFilterForm)
+	filterBtn.Importance = widget.LowImportance
+
+	v.filterLabel = widget.NewLabel("")
+	v.filterLabel.SizeName = SmallFont
+	v.filterLabel.Importance = widget.LowImportance
+	v.filterLabel.Hide()
+
+	// 清除逻辑
+	clearBtn := widget.NewButtonWithIcon("清除全部", theme.DeleteIcon(), func() {
+		dialog.ShowConfirm("确认", "确定删除吗？", func(ok bool) {
+			if ok {
+				state.ClearAllHistoryLocal()
+				v.apply()
+			}
+		}, v.win)
+	})
+
+	top := container.NewVBox(container.NewBorder(nil, nil, nil, filterBtn, search), v.filterLabel)
+	v.win.SetContent(container.NewBorder(top, clearBtn, nil, nil, v.list))
+	v.apply()
+	v.win.Show()
+
+	// 自动滚动到底部（在列表渲染完成后执行）
+	if len(v.rows) > 0 {
+		v.list.ScrollToBottom()
+	}
+}
+
+// 按当前条件重新筛选：没有条件时只展开最近一天，有条件时展开所有匹配的日期
+func (v *historyView) apply() {
+	v.sections = history.Group(v.filter.Apply(v.state.historyRecords()))
+	v.expanded = map[string]bool{}
+	if !v.filter.IsZero() {
+		for _, sec := range v.sections {
+			v.expanded[sec.Date] = true
+		}
+	} else if n := len(v.sections); n > 0 {
+		v.expanded[v.sections[n-1].Date] = true
+	}
+	v.rebuildRows()
+}
+
+// 根据展开状态生成显示的行
+func (v *historyView) rebuildRows() {
+	v.rows = v.rows[:0]
+	for i, sec := range v.sections {
+		v.rows = append(v.rows, historyRow{section: i, record: -1})
+		if v.expanded[sec.Date] {
+			for j := range sec.Records {
+				v.rows = append(v.rows, historyRow{section: i, record: j})
+			}
+		}
+	}
+	v.list.Refresh()
+}
+
+// 绑定一行的内容：日期标题居中加粗并显示条数，点击展开或收起这一天；
+// 记录靠右并高亮匹配的文字，点击弹出重用菜单
+func (v *historyView) bindRow(row historyRow, rich *allHistoryClickable) {
+	sec := v.sections[row.section]
+	if row.record == -1 {
+		rich.onTapped = func(*fyne.PointEvent) {
+			v.expanded[sec.Date] = !v.expanded[sec.Date]
+			v.rebuildRows()
+		}
+		arrow := "▸"
+		if v.expanded[sec.Date] {
+			arrow = "▾"
+		}
+		rich.Segments = []widget.RichTextSegment{&widget.TextSegment{
+			Text: fmt.Sprintf("%s %s（%d 条）", arrow, sec.Date, len(sec.Records)),
+			Style: widget.RichTextStyle{
+				Alignment: fyne.TextAlignCenter,
+				SizeName:  SmallFont,
+				TextStyle: fyne.TextStyle{Bold: true},
+			},
+		}}
+	} else {
+		rec := sec.Records[row.record]
+		rich.onTapped = func(ev *fyne.PointEvent) {
+			// 重用后关闭窗口，回到输入界面继续计算
+			showRecallMenu(v.state, rec, v.win.Canvas(), ev.AbsolutePosition, v.win.Close)
+		}
+		rich.Segments = highlightSegments(rec.String(), v.filter.Text)
+	}
+	rich.Refresh()
+}
+
+// 历史记录的点击菜单：重用算式、重用结果、复制，重用后调用 done（可以为 nil）；
+// 平摊记录的菜单是重新打开平摊和复制明细
+func showRecallMenu(state *CalcState, rec history.Record, c fyne.Canvas, pos fyne.Position, done func()) {
+	after := func() {
+		if done != nil {
+			done()
+		}
+	}
+	if rec.Split != nil {
+		reopen := fyne.NewMenuItem("重新打开平摊", func() {
+			state.reopenSplit(rec)
+			after()
+		})
+		copyItem := fyne.NewMenuItem("复制明细", func() {
+			text := rec.String()
+			if r, err := splitBill(rec.Split).Split(); err == nil {
+				text = r.Breakdown()
+			}
+			fyne.CurrentApp().Clipboard().SetContent(text)
+		})
+		widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", reopen, copyItem), c, pos)
+		return
+	}
+	reuseExpr := fyne.NewMenuItem("重用算式", func() {
+		state.RecallExpression(rec)
+		after()
+	})
+	reuseResult := fyne.NewMenuItem("重用结果", func() {
+		state.RecallResult(rec)
+		after()
+	})
+	_, ok := rec.Value()
+	reuseResult.Disabled = !ok // 结果是 Error 等非数值时不能重用
+	copyItem := fyne.NewMenuItem("复制", func() {
+		fyne.CurrentApp().Clipboard().SetContent(rec.String())
+	})
+	widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", reuseExpr, reuseResult, copyItem), c, pos)
+}
+
+// 把匹配搜索文字的部分标为强调色
+func highlightSegments(text, query string) []widget.RichTextSegment {
+	style := widget.RichTextStyle{Alignment: fyne.TextAlignTrailing, SizeName: SmallFont}
+	spans := history.Highlight(text, query)
+	if len(spans) == 0 {
+		return []widget.RichTextSegment{&widget.TextSegment{Text: text, Style: style}}
+	}
+
+	// 同一行内的多个文本段需要设置 Inline，只有最后一段结束本段落
+	inline := style
+	inline.Inline = true
+	mark := inline
+	mark.ColorName = theme.ColorNameHyperlink // 自定义主题把 Primary 用作按键背景色，这里用链接色
+	mark.TextStyle = fyne.TextStyle{Bold: true}
+
+	runes := []rune(text)
+	var segments []widget.RichTextSegment
+	pos := 0
+	for _, sp := range spans {
+		if sp[0] > pos {
+			segments = append(segments, &widget.TextSegment{Text: string(runes[pos:sp[0]]), Style: inline})
+		}
+		segments = append(segments, &widget.TextSegment{Text: string(runes[sp[0]:sp[1]]), Style: mark})
+		pos = sp[1]
+	}
+	segments = append(segments, &widget.TextSegment{Text: string(runes[pos:]), Style: style})
+	return segments
+}
+
+// 数值范围和日期范围的筛选表单，留空表示不限
+func (v *historyView) showFilterForm() {
+	minEntry, maxEntry := widget.NewEntry(), widget.NewEntry()
+	fromEntry, toEntry := widget.NewEntry(), widget.NewEntry()
+	if v.filter.Min != nil {
+		minEntry.SetText(engine.Options{}.Format(*v.filter.Min))
+	}
+	if v.filter.Max != nil {
+		maxEntry.SetText(engine.Options{}.Format(*v.filter.Max))
+	}
+	if !v.filter.From.IsZero() {
+		fromEntry.SetText(v.filter.From.Format("2006-01-02"))
+	}
+	if !v.filter.To.IsZero() {
+		toEntry.SetText(v.filter.To.Format("2006-01-02"))
+	}
+	fromEntry.SetPlaceHolder("2026-03-01")
+	toEntry.SetPlaceHolder("2026-03-31")
+
+	for _, e := range []*widget.Entry{minEntry, maxEntry} {
+		e.Validator = func(s string) error {
+			_, err := v.parseBound(s)
+			return err
+		}
+	}
+	for _, e := range []*widget.Entry{fromEntry, toEntry} {
+		e.Validator = func(s string) error {
+			_, err := parseDateBound(s)
+			return err
+		}
+	}
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("结果 ≥", minEntry),
+		widget.NewFormItem("结果 ≤", maxEntry),
+		widget.NewFormItem("开始日期", fromEntry),
+		widget.NewFormItem("结束日期", toEntry),
+	}
+	dialog.ShowForm("筛选", "确定", "取消", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		v.filter.Min, _ = v.parseBound(minEntry.Text)
+		v.filter.Max, _ = v.parseBound(maxEntry.Text)
+		v.filter.From, _ = parseDateBound(fromEntry.Text)
+		v.filter.To, _ = parseDateBound(toEntry.Text)
+		v.updateFilterLabel()
+		v.apply()
+	}, v.win)
+}
+
+// 解析数值范围的一端，可以输入算式，留空返回 nil
+func (v *historyView) parseBound(s string) (*float64, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	res, err := engine.Evaluate(s, v.state.engineOptions())
+	if err != nil {
+		return nil, err
+	}
+	return &res.Value, nil
+}
+
+// 解析日期范围的一端，留空返回零值
+func parseDateBound(s string) (time.Time, error) {
+	if strings.TrimSpace(s) == "" {
+		return time.Time{}, nil
+	}
+	t, err := history.ParseDate(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("日期格式应为 2026-03-31")
+	}
+	return t, nil
+}
+
+// 在搜索栏下方显示数值和日期范围条件，没有条件时隐藏
+func (v *historyView) updateFilterLabel() {
+	bound := func(lo, hi string) string {
+		if lo == "" && hi == "" {
+			return ""
+		}
+		return lo + " ~ " + hi
+	}
+	format := func(f *float64) string {
+		if f == nil {
+			return ""
+		}
+		return engine.Options{}.Format(*f)
+	}
+	date := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("2006-01-02")
+	}
+
+	var parts []string
+	if r := bound(format(v.filter.Min), format(v.filter.Max)); r != "" {
+		parts = append(parts, "结果 "+r)
+	}
+	if r := bound(date(v.filter.From), date(v.filter.To)); r != "" {
+		parts = append(parts, "日期 "+r)
+	}
+	v.filterLabel.SetText(strings.Join(parts, "，"))
+	if len(parts) > 0 {
+		v.filterLabel.Show()
+	} else {
+		v.filterLabel.Hide()
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/storage"
+)
+
+// 用户自定义键位文件名，放在 App 沙盒根目录下，内容为 {"按键": "动作"}，覆盖同名的默认键位
+const userKeymapFileName = "keymap.json"
+
+// 默认键位。键的写法：
+//   - 单个字符，表示键入的字符，如 "*"、"("
+//   - Fyne 的键名，如 "Return"、"Escape"
+//   - 带修饰键的快捷键，如 "Mod+M"，修饰键可以是 Ctrl、Alt、Shift、Super，Mod 在 macOS 上是 Cmd，其他平台是 Ctrl
+//
+// 动作与键盘上按钮的文字相同，见 keyAction。
+// 字母和下划线键入自身（见 withLetters），用于输入变量名、函数名以及 sin、sqrt 等内置函数，
+// 如 x=3.5、f(x)=x^2；π、√ 和倒数没有对应的字母，用 Alt 快捷键输入
+var defaultKeymap = withLetters(map[string]string{
+	"0": "0", "1": "1", "2": "2", "3": "3", "4": "4",
+	"5": "5", "6": "6", "7": "7", "8": "8", "9": "9",
+	".": ".", ",": ",",
+	"+": "+", "-": "-", "*": "×", "/": "÷",
+	"%": "%", "^": "^", "(": "(", ")": ")", "!": "x!",
+	"=": "=",
+
+	"Alt+P": "π", "Alt+R": "√x", "Alt+I": "1/x",
+
+	"Return":    "=",
+	"KP_Enter":  "=",
+	"BackSpace": "⌫",
+	"Escape":    "C",
+	"Delete":    "C",
+	"Left":      "←",
+	"Right":     "→",
+	"Home":      "home",
+	"End":       "end",
+
+	"Mod+L": "MC", "Mod+R": "MR", "Mod+P": "M+", "Mod+Q": "M-", "Mod+M": "MS",
+	"Mod+D": "DEG", "Mod+S": "2nd", "Mod+G": "grid",
+	"Mod+C": "copy", "Mod+V": "paste",
+	"Mod+Z": "undo", "Mod+Y": "redo", "Mod+Shift+Z": "redo",
+})
+
+// 在键位表中加入 a–z、A–Z 和 _，每个键入自身
+func withLetters(km map[string]string) map[string]string {
+	for r := 'a'; r <= 'z'; r++ {
+		km[string(r)] = string(r)
+		km[string(r-'a'+'A')] = string(r - 'a' + 'A')
+	}
+	km["_"] = "_"
+	return km
+}
+
+// 判断动作是否为键入一个字母或下划线
+func isLetterAction(action string) bool {
+	return len(action) == 1 && isNameByte(action[0]) && (action[0] < '0' || action[0] > '9')
+}
+
+// 键位表：键入的字符、无修饰的按键、快捷键分别对应一个动作
+type keymap struct {
+	runes     map[rune]string
+	keys      map[fyne.KeyName]string
+	shortcuts map[desktop.CustomShortcut]string
+}
+
+// 解析键位定义，按键写法错误或动作不存在时返回错误
+func parseKeymap(defs map[string]string) (*keymap, error) {
+	km := &keymap{
+		runes:     map[rune]string{},
+		keys:      map[fyne.KeyName]string{},
+		shortcuts: map[desktop.CustomShortcut]string{},
+	}
+	for spec, action := range defs {
+		if keyAction(action) == nil {
+			return nil, fmt.Errorf("未知的动作 %q（按键 %q）", action, spec)
+		}
+		if r := []rune(spec); len(r) == 1 {
+			km.runes[r[0]] = action
+			continue
+		}
+
+		parts := strings.Split(spec, "+")
+		key := parts[len(parts)-1]
+		if key == "" {
+			return nil, fmt.Errorf("无法识别的按键 %q", spec)
+		}
+		if len(parts) == 1 {
+			km.keys[fyne.KeyName(key)] = action
+			continue
+		}
+
+		var mod fyne.KeyModifier
+		for _, name := range parts[:len(parts)-1] {
+			switch strings.ToLower(name) {
+			case "ctrl":
+				mod |= fyne.KeyModifierControl
+			case "alt":
+				mod |= fyne.KeyModifierAlt
+			case "shift":
+				mod |= fyne.KeyModifierShift
+			case "super", "cmd":
+				mod |= fyne.KeyModifierSuper
+			case "mod":
+				mod |= fyne.KeyModifierShortcutDefault
+			default:
+				return nil, fmt.Errorf("无法识别的修饰键 %q（按键 %q）", name, spec)
+			}
+		}
+		// 快捷键的字母键名是大写的
+		km.shortcuts[desktop.CustomShortcut{KeyName: fyne.KeyName(strings.ToUpper(key)), Modifier: mod}] = action
+	}
+	return km, nil
+}
+
+// 加载键位：默认键位 + 沙盒中的用户自定义文件
+// 自定义文件有错误时记录日志并只使用默认键位
+func loadKeymap() *keymap {
+	defs := make(map[string]string, len(defaultKeymap))
+	for k, v := range defaultKeymap {
+		defs[k] = v
+	}
+	km, _ := parseKeymap(defs)
+
+	rootURI := fyne.CurrentApp().Storage().RootURI()
+	if rootURI == nil {
+		return km
+	}
+	fileURI, err := storage.Child(rootURI, userKeymapFileName)
+	if err != nil {
+		return km
+	}
+	reader, err := storage.Reader(fileURI)
+	if err != nil {
+		return km // 没有自定义文件是正常的
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return km
+	}
+	var user map[string]string
+	if err := json.Unmarshal(data, &user); err != nil {
+		fyne.LogError("invalid "+userKeymapFileName, err)
+		return km
+	}
+	for k, v := range user {
+		defs[k] = v
+	}
+	merged, err := parseKeymap(defs)
+	if err != nil {
+		fyne.LogError("invalid "+userKeymapFileName, err)
+		return km
+	}
+	return merged
+}
+
+// 动作对应的处理函数，动作不存在时返回 nil
+func keyAction(action string) func(s *CalcState) {
+	switch action {
+	case "=":
+		return (*CalcState).OnEqual
+	case "C":
+		return (*CalcState).OnClear
+	case "⌫":
+		return (*CalcState).OnBackspace
+	case "2nd":
+		return (*CalcState).OnToggle2nd
+	case "DEG":
+		return (*CalcState).OnDegToRad
+	case "grid":
+		return (*CalcState).OnGoBigGrid
+	case "←":
+		return func(s *CalcState) { s.MoveCaret(-1) }
+	case "→":
+		return func(s *CalcState) { s.MoveCaret(1) }
+	case "home":
+		return func(s *CalcState) { s.MoveCaretTo(0) }
+	case "end":
+		return (*CalcState).resetCaret
+	case "undo":
+		return (*CalcState).Undo
+	case "redo":
+		return (*CalcState).Redo
+	case "copy":
+		return (*CalcState).OnCopy
+	case "paste":
+		return (*CalcState).OnPaste
+	case "MC", "MR", "M+", "M-", "MS":
+		return func(s *CalcState) { s.OnMemory(action) }
+	case "sin", "cos", "tan", "lg", "ln", "√x", "x!", "1/x", "π", "e", "ans":
+		return func(s *CalcState) { s.OnAdvancedTap(action) }
+	}
+	if r := []rune(action); len(r) == 1 && strings.ContainsRune("0123456789.+-×÷%()^,", r[0]) || isLetterAction(action) {
+		return func(s *CalcState) { s.OnTap(action) }
+	}
+	return nil
+}
+
+// 在窗口上接收键盘输入，按键位表转发给对应的按键处理函数
+func bindKeyboard(s *CalcState, c fyne.Canvas, km *keymap) {
+	c.SetOnTypedRune(func(r rune) {
+		if s.onTypedRune != nil && s.onTypedRune(r) {
+			return
+		}
+		if action, ok := km.runes[r]; ok {
+			keyAction(action)(s)
+		}
+	})
+	c.SetOnTypedKey(func(ev *fyne.KeyEvent) {
+		if action, ok := km.keys[ev.Name]; ok {
+			keyAction(action)(s)
+		}
+	})
+	for sc, action := range km.shortcuts {
+		c.AddShortcut(standardShortcut(&sc), func(fyne.Shortcut) {
+			keyAction(action)(s)
+		})
+	}
+}
+
+// 桌面驱动把 Ctrl/Cmd+C、V、X、Z、Y、A 作为标准快捷键发送，而不是 CustomShortcut，需要注册对应的类型
+func standardShortcut(sc *desktop.CustomShortcut) fyne.Shortcut {
+	if sc.Modifier != fyne.KeyModifierShortcutDefault {
+		return sc
+	}
+	switch sc.KeyName {
+	case fyne.KeyC:
+		return &fyne.ShortcutCopy{}
+	case fyne.KeyV:
+		return &fyne.ShortcutPaste{}
+	case fyne.KeyX:
+		return &fyne.ShortcutCut{}
+	case fyne.KeyZ:
+		return &fyne.ShortcutUndo{}
+	case fyne.KeyY:
+		return &fyne.ShortcutRedo{}
+	case fyne.KeyA:
+		return &fyne.ShortcutSelectAll{}
+	}
+	return sc
+}
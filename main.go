@@ -20,9 +20,7 @@ func main() {
 	state = NewCalcState(win)
 
 	// 在后台加载历史记录，避免界面卡顿
-	go func() {
-		state.allHistoryBuilder.Write(state.loadHistoryFromFile())
-	}()
+	go state.loadHistory()
 
 	// 创建UI界面
 	ui := CreateUI(state)
@@ -36,9 +34,12 @@ func main() {
 	)
 	win.SetContent(contentStack)
 
+	// 桌面端的键盘输入，键位可以在沙盒中的 keymap.json 里自定义
+	bindKeyboard(state, win.Canvas(), loadKeymap())
+
 	win.Resize(fyne.NewSize(360, 640))
 
-	// 当应用退到后台（例如按了 Home 键），或者被系统停止时触发保存
+	// 每条记录在计算完成时已经写入文件；应用退到后台（例如按了 Home 键）或者被系统停止时整理一次历史文件
 	myApp.Lifecycle().SetOnExitedForeground(func() {
 		state.saveHistoryToFile()
 	})
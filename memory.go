@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/gzjjjfree/MemoryCalculator/calc/engine"
+)
+
+// 内存寄存器个数：下标 0 为主寄存器 M，1..9 为命名寄存器 M1..M9
+const memoryRegisterCount = 10
+
+// 保存内存寄存器时使用的 Preferences 键名
+const (
+	prefMemoryValues = "memory.values" // []float64，每个寄存器的值
+	prefMemoryUsed   = "memory.used"   // []bool，寄存器是否存有数值（区分空寄存器和 0）
+	prefMemoryActive = "memory.active" // int，当前选中的寄存器
+)
+
+// 内存寄存器，以及当前选中的寄存器
+type memoryRegisters struct {
+	values [memoryRegisterCount]float64
+	used   [memoryRegisterCount]bool
+	active int
+
+	indicator binding.String // 显示区的内存指示，如 "M3 = 12.5"，所有寄存器为空时为空字符串
+	name      binding.String // 当前寄存器的名称，显示在选择按钮上
+}
+
+// 寄存器的显示名称
+func memoryName(i int) string {
+	if i == 0 {
+		return "M"
+	}
+	return fmt.Sprintf("M%d", i)
+}
+
+// 从 Preferences 恢复上次保存的寄存器
+func (s *CalcState) loadMemory() {
+	s.memory.indicator = binding.NewString()
+	s.memory.name = binding.NewString()
+
+	if app := fyne.CurrentApp(); app != nil {
+		prefs := app.Preferences()
+		values := prefs.FloatList(prefMemoryValues)
+		used := prefs.BoolList(prefMemoryUsed)
+		for i := 0; i < memoryRegisterCount && i < len(values) && i < len(used); i++ {
+			s.memory.values[i] = values[i]
+			s.memory.used[i] = used[i]
+		}
+		if active := prefs.Int(prefMemoryActive); active >= 0 && active < memoryRegisterCount {
+			s.memory.active = active
+		}
+	}
+	s.refreshMemory()
+}
+
+// 保存寄存器并刷新显示
+func (s *CalcState) saveMemory() {
+	if app := fyne.CurrentApp(); app != nil {
+		prefs := app.Preferences()
+		prefs.SetFloatList(prefMemoryValues, s.memory.values[:])
+		prefs.SetBoolList(prefMemoryUsed, s.memory.used[:])
+		prefs.SetInt(prefMemoryActive, s.memory.active)
+	}
+	s.refreshMemory()
+}
+
+// 更新显示区的内存指示
+func (s *CalcState) refreshMemory() {
+	m := &s.memory
+	m.name.Set(memoryName(m.active))
+
+	var parts []string
+	if m.used[m.active] {
+		parts = append(parts, memoryName(m.active)+" = "+engine.Options{}.Format(m.values[m.active]))
+	}
+	// 其余存有数值的寄存器只显示名称
+	for i := 0; i < memoryRegisterCount; i++ {
+		if i != m.active && m.used[i] {
+			parts = append(parts, memoryName(i))
+		}
+	}
+	m.indicator.Set(strings.Join(parts, " "))
+}
+
+// 当前可存入内存的数值：输入中取算式的值（赋值语句取所赋的值），刚按过 = 时取结果行显示的结果。
+// 已经算出的结果不重新计算，否则 x=x+1 这样的赋值会得到另一个值
+func (s *CalcState) currentValue() (float64, *engine.Error) {
+	current, _ := s.display.Get()
+	result, _ := s.result.Get()
+	if current == "" || s.isNewNumber && strings.HasPrefix(result, "= ") {
+		res, err := engine.Evaluate(strings.TrimPrefix(result, "= "), s.engineOptions())
+		if err != nil || !res.IsReal() {
+			return 0, engine.NewError(engine.KindDomain, "结果不是实数，不能存入内存")
+		}
+		return res.Value, nil
+	}
+
+	res, a, err := s.evaluate(current)
+	if err != nil {
+		return 0, err
+	}
+	if a != nil && a.IsFunction() {
+		return 0, engine.NewError(engine.KindSyntax, "函数定义没有数值，不能存入内存")
+	}
+	if !res.IsReal() {
+		return 0, engine.NewError(engine.KindDomain, "内存只能保存实数")
+	}
+	return res.Value, nil
+}
+
+// 把一个数值插入算式：新输入时替换，否则插入在光标处（负数加括号）
+func (s *CalcState) insertValue(text string) {
+	defer s.beginEdit()()
+	current, _ := s.display.Get()
+	if strings.HasPrefix(text, "-") && !s.isNewNumber && current != "" {
+		text = "(" + text + ")"
+	}
+	if s.isNewNumber {
+		s.display.Set("")
+		s.resetCaret()
+		s.isNewNumber = false
+	}
+	s.isResultMode.Set(false)
+	s.updatePreview(s.insertAtCaret(text))
+}
+
+// 处理内存键：MC 清除、MR 读取、M+ 累加、M- 累减、MS 存入
+func (s *CalcState) OnMemory(op string) {
+	// 平摊、换算、统计或程序员界面打开时不响应内存键
+	if s.isTakenOver() {
+		return
+	}
+
+	m := &s.memory
+	switch op {
+	case "MC":
+		m.values[m.active] = 0
+		m.used[m.active] = false
+	case "MR":
+		if m.used[m.active] {
+			s.insertValue(engine.Options{}.Format(m.values[m.active]))
+		}
+		return
+	case "M+", "M-", "MS":
+		val, err := s.currentValue()
+		if err != nil {
+			s.showError(err)
+			return
+		}
+		switch op {
+		case "M+":
+			m.values[m.active] += val
+		case "M-":
+			m.values[m.active] -= val
+		default:
+			m.values[m.active] = val
+		}
+		m.used[m.active] = true
+		// 存入内存后，下一次输入开始新的算式
+		s.isNewNumber = true
+	}
+	s.saveMemory()
+}
+
+// 选择当前使用的寄存器
+func (s *CalcState) OnSelectMemory(i int) {
+	if i < 0 || i >= memoryRegisterCount {
+		return
+	}
+	s.memory.active = i
+	s.saveMemory()
+}
+
+// 创建内存键一行：MC MR M+ M- MS 以及寄存器选择按钮，两种键盘布局共用
+func createMemoryRow(state *CalcState) fyne.CanvasObject {
+	customTheme := &myTheme{Theme: theme.DefaultTheme(), textSize: 18, colorBackground: color.NRGBA{R: 242, G: 242, B: 242, A: 255}}
+
+	makeMemBtn := func(text string, action func()) fyne.CanvasObject {
+		b := widget.NewButton(text, action)
+		b.Importance = widget.LowImportance
+		container.NewThemeOverride(b, customTheme)
+		return b
+	}
+
+	// 寄存器选择：点击弹出 M、M1..M9 列表，并显示各自的值
+	var selectBtn *widget.Button
+	selectBtn = widget.NewButton("M▾", func() {
+		items := make([]*fyne.MenuItem, 0, memoryRegisterCount)
+		for i := 0; i < memoryRegisterCount; i++ {
+			label := memoryName(i)
+			if state.memory.used[i] {
+				label += " = " + engine.Options{}.Format(state.memory.values[i])
+			}
+			item := fyne.NewMenuItem(label, func() { state.OnSelectMemory(i) })
+			item.Checked = i == state.memory.active
+			items = append(items, item)
+		}
+		widget.ShowPopUpMenuAtRelativePosition(fyne.NewMenu("", items...), state.win.Canvas(),
+			fyne.NewPos(0, selectBtn.Size().Height), selectBtn)
+	})
+	selectBtn.Importance = widget.LowImportance
+	container.NewThemeOverride(selectBtn, customTheme)
+	state.memory.name.AddListener(binding.NewDataListener(func() {
+		name, _ := state.memory.name.Get()
+		selectBtn.SetText(name + "▾")
+	}))
+
+	return container.NewGridWithColumns(6,
+		makeMemBtn("MC", func() { state.OnMemory("MC") }),
+		makeMemBtn("MR", func() { state.OnMemory("MR") }),
+		makeMemBtn("M+", func() { state.OnMemory("M+") }),
+		makeMemBtn("M-", func() { state.OnMemory("M-") }),
+		makeMemBtn("MS", func() { state.OnMemory("MS") }),
+		selectBtn,
+	)
+}
@@ -1,16 +1,22 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/data/binding"
-	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
+
+	"github.com/gzjjjfree/MemoryCalculator/calc/engine"
+	"github.com/gzjjjfree/MemoryCalculator/calc/history"
 )
 
 var isChangeRow bool = false       // 是否需要换行
@@ -113,39 +119,85 @@ func measureWidth(text string, fontSize float32) float32 {
 type CalcState struct {
 	win fyne.Window
 
-	display           binding.String   // 当前输入的算式
-	result            binding.String   // 当前算式的结果预览
-	history           binding.String   // 历史记录（每次计算完成后追加）
-	allHistoryBuilder *strings.Builder // 用于保存所有历史记录的字符串，方便写入文件
-	saveFileName      string           // 本地文件名（如 "history.txt"）
-	lastRecordDate    string           // 记录上一次写入时的日期（如 "2026-03-31"）
+	display        binding.String   // 当前输入的算式
+	result         binding.String   // 当前算式的结果预览
+	history        binding.String   // 历史记录（每次计算完成后追加）
+	records        []history.Record // 全部历史记录，按时间先后排列
+	session        []sessionEntry   // 当次历史，显示在输入框上方
+	historyLoaded  bool             // 启动时的后台加载是否已经完成
+	historyMutex   sync.Mutex       // 保护 records（启动时在后台加载）
+	saveFileName   string           // 本地文件名（"history.jsonl"，每行一条 JSON 记录）
+	legacyFileName string           // 旧版纯文本历史文件名（"history.txt"），启动时自动导入
+
+	store            *history.Store // 历史文件，每条记录立即追加写入
+	storeOnce        sync.Once      // 第一次使用时创建 store
+	storeErr         error          // 创建 store 时的错误（例如平台不支持本地文件）
+	historyWriteErr  error          // 最近一次整理历史文件的结果
+	lastStorageError string         // 最近一次提示过的保存错误，避免重复弹窗
+	historyErrMutex  sync.Mutex     // 保护 lastStorageError
+
+	isNewNumber bool        // 是否正在输入一个新的数字（而不是继续在当前数字后面输入）
+	caret       binding.Int // 光标距算式末尾的字符数（不计自动换行的 \n），0 表示在末尾
 
-	isNewNumber bool // 是否正在输入一个新的数字（而不是继续在当前数字后面输入）
+	errorSpan binding.Item[engine.Span] // 当前算式中出错的位置（rune 偏移），空区间表示没有错误
 
 	isResultMode binding.Bool // true 代表显示结果（结果粗），false 代表输入中（输入粗)
 	isCalcBig    binding.Bool // 是否使用高级计算布局
 	isRadian     binding.Bool // true 为弧度模式，false 为角度模式
 	is2ndMode    binding.Bool // 是否处于 2nd 模式
 
-	isInterceptingForScore bool            // 是否正在拦截输入
+	isExact       binding.Bool // 是否使用精确小数模式（在设置中切换）
+	decimalPlaces binding.Int  // 精确模式下结果保留的小数位数
+	isComplex     binding.Bool // 是否使用复数模式（i 为虚数单位）
+	isPolar       binding.Bool // 复数结果是否按极坐标 r∠θ 显示
+
+	memory    memoryRegisters    // 内存寄存器 M、M1..M9
+	variables map[string]float64 // 用户定义的变量，如 x = 3.5
+
+	userFunctions map[string]engine.Function // 用户定义的函数，如 f(x,y) = x^2 + y
+	functions     map[string]engine.Function // 传给求值引擎的函数表：内置函数加上自定义函数
+	evalCache     *engine.Cache              // 实时预览时复用已经算过的部分，变量或函数增删后清空
+
+	undo undoStack // 输入区的撤销和重做
+
+	isInterceptingForScore bool            // 平摊界面是否正在拦截输入
 	onScoreInput           func(string)    // 拦截时的回调函数
 	scoreOverlay           *fyne.Container // 平摊功能的 UI 容器
+
+	isConverting   bool         // 是否处于单位换算界面
+	onConvertInput func(string) // 换算界面接管按键的回调函数
+
+	isStatistics bool         // 是否处于统计界面
+	onStatsInput func(string) // 统计界面接管按键的回调函数
+
+	isProgrammer   binding.Bool    // 是否使用程序员键盘（整数进制和位运算）
+	isProgramming  bool            // 程序员界面是否接管了按键
+	onProgramInput func(string)    // 程序员界面接管按键的回调函数
+	onTypedRune    func(rune) bool // 接管按键的界面优先处理键入的字符，返回 true 表示已处理
 }
 
 // 构造函数，初始化状态
 func NewCalcState(w fyne.Window) *CalcState {
 	s := &CalcState{
-		display:           binding.NewString(),
-		result:            binding.NewString(),
-		history:           binding.NewString(),
-		allHistoryBuilder: &strings.Builder{},
-		saveFileName:      "history.txt",
-		isNewNumber:       true,
-		isResultMode:      binding.NewBool(),
-		isCalcBig:         binding.NewBool(),
-		isRadian:          binding.NewBool(),
-		is2ndMode:         binding.NewBool(),
-		win:               w,
+		display:        binding.NewString(),
+		result:         binding.NewString(),
+		history:        binding.NewString(),
+		saveFileName:   "history.jsonl",
+		legacyFileName: "history.txt",
+		isNewNumber:    true,
+		caret:          binding.NewInt(),
+		errorSpan:      binding.NewItem(func(a, b engine.Span) bool { return a == b }),
+		isResultMode:   binding.NewBool(),
+		isCalcBig:      binding.NewBool(),
+		isProgrammer:   binding.NewBool(),
+		isRadian:       binding.NewBool(),
+		is2ndMode:      binding.NewBool(),
+		isExact:        binding.NewBool(),
+		decimalPlaces:  binding.NewInt(),
+		isComplex:      binding.NewBool(),
+		isPolar:        binding.NewBool(),
+		evalCache:      engine.NewCache(),
+		win:            w,
 	}
 	s.display.Set("")
 	s.result.Set("0")
@@ -153,130 +205,235 @@ func NewCalcState(w fyne.Window) *CalcState {
 	s.isCalcBig.Set(false)
 	s.isRadian.Set(false) // 默认角度模式
 	s.is2ndMode.Set(false)
+	s.loadSettings()  // 恢复上次保存的设置
+	s.loadMemory()    // 恢复上次保存的内存寄存器
+	s.loadVariables() // 恢复上次保存的变量
+	s.loadFunctions() // 恢复上次保存的自定义函数
 	return s
 }
 
+// 当次历史中的一条：记录以及显示用的文字
+type sessionEntry struct {
+	record history.Record
+	text   string
+}
+
+// 历史记录最多保留的条数，超出时丢弃最早的记录
+const maxHistoryRecords = 5000
+
+// 自上次整理后追加多少条记录时整理一次历史文件
+const historyCompactEvery = 500
+
 // 清除所有历史记录（包括内存和本地文件）
 func (s *CalcState) ClearAllHistoryLocal() error {
 	// 清除当前显示的当次历史
 	s.history.Set("")
-	s.allHistoryBuilder.Reset()
-
-	rootURI := fyne.CurrentApp().Storage().RootURI()
-	if rootURI == nil {
-		return nil
-	}
+	s.historyMutex.Lock()
+	s.records = nil
+	s.session = nil
+	s.historyMutex.Unlock()
 
-	fileURI, err := storage.Child(rootURI, "history.txt")
+	store, err := s.historyStore()
 	if err != nil {
-		return nil
+		return nil // 没有可用的存储，也就没有需要删除的文件
 	}
-
-	// 删除本地文件
-	err = storage.Delete(fileURI)
-	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			return nil
-		}
+	if err := store.Remove(); err != nil {
+		return err
+	}
+	// 同时删除旧版的纯文本文件
+	if err := os.Remove(s.legacyHistoryPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return err
 	}
 	return nil
 }
 
-// 记录历史：每次计算完成后调用，参数是算式和结果
-func (s *CalcState) recordToHistory(expression string, result string) {
-	now := time.Now().Format("2006-01-02")
-
-	// 核心逻辑：对比缓存的日期
-	// 只有当日期变了（或者是 APP 启动后的第一次记录），才插入标题
-	if s.lastRecordDate != now {
-		// 即使日期变了，我们也双重检查一下 Builder 内容（防止初始化时的边界问题）
-		dateHeader := "--- " + now + " ---"
+// 记录历史：每次计算完成后调用，参数是算式、结果和可选的标签
+// 记录会立即追加到历史文件，不必等到应用退出
+func (s *CalcState) recordToHistory(expression string, result string, tags ...string) history.Record {
+	angle := history.AngleDegree
+	if isRad, _ := s.isRadian.Get(); isRad {
+		angle = history.AngleRadian
+	}
+	rec := history.NewRecord(time.Now(), expression, result, angle, tags...)
+	s.appendRecord(rec)
+	return rec
+}
 
-		// 只有当确实没包含这个标题时才写入
-		// 注意：这里只在日期切换的那一刻调用一次 String()，平时不调用
-		if !strings.Contains(s.allHistoryBuilder.String(), dateHeader) {
-			s.allHistoryBuilder.WriteString("\n" + dateHeader + "\n")
-		}
+// 把一条记录加入全部历史并追加到历史文件
+func (s *CalcState) appendRecord(rec history.Record) {
+	s.historyMutex.Lock()
+	s.records = append(s.records, rec)
+	s.historyMutex.Unlock()
 
-		// 更新缓存的日期
-		s.lastRecordDate = now
+	store, err := s.historyStore()
+	if err == nil {
+		err = store.Append(rec)
 	}
+	if err != nil {
+		s.reportStorageError(err)
+		return
+	}
+	if store.NeedsCompaction() {
+		s.saveHistoryToFile()
+	}
+}
 
-	// 假设每行格式为：算式 = 结果
-	entry := fmt.Sprintf("%s = %s\n", expression, result)
+// 把一条记录加入当次历史，text 是显示用的文字（长算式已在运算符处换行）
+func (s *CalcState) addSessionEntry(rec history.Record, text string) {
+	s.historyMutex.Lock()
+	s.session = append(s.session, sessionEntry{record: rec, text: text})
+	s.historyMutex.Unlock()
 
-	// 直接操作 string 字段
-	s.allHistoryBuilder.WriteString(entry)
+	oldHistory, _ := s.history.Get()
+	s.history.Set(oldHistory + "\n" + text)
 }
 
-// 保存历史到文件：在应用退到后台或者被系统停止时调用
-func (s *CalcState) saveHistoryToFile() {
-	if s.allHistoryBuilder == nil || s.allHistoryBuilder.Len() == 0 {
-		return // 如果没有内容，直接返回，避免覆写空文件
-	}
+// 返回当次历史的副本，供界面读取
+func (s *CalcState) sessionEntries() []sessionEntry {
+	s.historyMutex.Lock()
+	defer s.historyMutex.Unlock()
+	return append([]sessionEntry(nil), s.session...)
+}
 
-	// 自动清理逻辑：防止内存中的 Builder 过大
-	// 500KB 约等于 512,000 字节
-	if s.allHistoryBuilder.Len() > 500*1024 {
-		content := s.allHistoryBuilder.String()
-		lines := strings.Split(content, "\n")
+// 返回全部历史记录的副本，供界面读取
+func (s *CalcState) historyRecords() []history.Record {
+	s.historyMutex.Lock()
+	defer s.historyMutex.Unlock()
+	return append([]history.Record(nil), s.records...)
+}
 
-		if len(lines) > 5000 {
-			// 保留最后 5000 行
-			newContent := strings.Join(lines[len(lines)-5000:], "\n")
+// 在后台加载历史：文件中的记录排在本次启动后新产生的记录之前
+func (s *CalcState) loadHistory() {
+	loaded, migrated := s.loadHistoryFromFile()
 
-			// strings.Builder 不支持直接删除，必须重置后重新写入
-			s.allHistoryBuilder.Reset()
-			s.allHistoryBuilder.WriteString(newContent)
+	s.historyMutex.Lock()
+	// 加载完成前产生的记录已经追加到文件中，可能被一并读出，按 ID 去重
+	seen := make(map[string]bool, len(loaded))
+	for _, rec := range loaded {
+		seen[rec.ID] = true
+	}
+	for _, rec := range s.records {
+		if !seen[rec.ID] {
+			loaded = append(loaded, rec)
 		}
 	}
-
-	// 【关键修改：使用 Fyne 的沙盒路径 API 读写文件】
-	// 获取 App 专属的安全沙盒根目录
-	rootURI := fyne.CurrentApp().Storage().RootURI()
-	if rootURI == nil {
-		return // 如果获取不到，直接返回
+	s.records = loaded
+	s.historyLoaded = true
+	s.historyMutex.Unlock()
+
+	// 旧版文件导入后立即写成新格式，成功后删除旧文件
+	if migrated {
+		s.saveHistoryToFile()
+		if s.historyWriteErr == nil {
+			_ = os.Remove(s.legacyHistoryPath())
+		}
 	}
+}
 
-	// 在根目录下构建/获取 history.txt 的完整路径对象
-	fileURI, err := storage.Child(rootURI, s.saveFileName)
-	if err != nil {
+// 整理历史文件：只保留最近的 maxHistoryRecords 条，先写临时文件再替换，写入过程中崩溃不会损坏原文件
+// 在追加的记录足够多，以及应用退到后台或者被系统停止时调用
+func (s *CalcState) saveHistoryToFile() {
+	s.historyMutex.Lock()
+	defer s.historyMutex.Unlock()
+
+	// 历史还没加载完时，内存中的记录不完整，不能用来重写文件
+	if !s.historyLoaded {
 		return
 	}
 
-	// 打开 Writer 写入文件 (会自动覆盖并保存)
-	writer, err := storage.Writer(fileURI)
+	store, err := s.historyStore()
+	if err == nil {
+		var kept []history.Record
+		if kept, err = store.Compact(s.records); err == nil {
+			s.records = append([]history.Record(nil), kept...)
+		}
+	}
+	s.historyWriteErr = err
 	if err != nil {
-		return
+		s.reportStorageError(err)
 	}
-	defer writer.Close()
-
-	_, _ = writer.Write([]byte(s.allHistoryBuilder.String()))
 }
 
-// 从文件加载历史：在应用启动时调用，返回文件内容的字节切片
-func (s *CalcState) loadHistoryFromFile() []byte {
-	rootURI := fyne.CurrentApp().Storage().RootURI()
-	if rootURI == nil {
-		return nil
+// 从文件加载历史：在应用启动时调用
+// 旧版 history.txt 还存在时一并导入，排在新格式记录之前，migrated 表示是否发生了导入
+func (s *CalcState) loadHistoryFromFile() (records []history.Record, migrated bool) {
+	store, err := s.historyStore()
+	if err != nil {
+		s.reportStorageError(err)
+		return nil, false
 	}
 
-	fileURI, err := storage.Child(rootURI, s.saveFileName)
+	records, skipped, err := store.Load()
 	if err != nil {
-		return nil
+		s.reportStorageError(err)
+		return nil, false
+	}
+	if skipped > 0 {
+		fyne.LogError(fmt.Sprintf("skipped %d unreadable history lines", skipped), nil)
 	}
 
-	reader, err := storage.Reader(fileURI)
+	// 旧版文件不存在是正常的（第一次运行或已经导入过）
+	f, err := os.Open(s.legacyHistoryPath())
 	if err != nil {
-		// 文件不存在是正常的（第一次运行），直接返回空
-		return nil
+		return records, false
 	}
-	defer reader.Close()
+	defer f.Close()
 
-	data, err := io.ReadAll(reader)
+	legacy, err := history.MigrateText(f)
 	if err != nil {
-		return nil
+		return records, false
+	}
+	return append(legacy, records...), true
+}
+
+// 历史文件的存储，第一次使用时根据 App 沙盒目录创建
+func (s *CalcState) historyStore() (*history.Store, error) {
+	s.storeOnce.Do(func() {
+		root, err := storageRootPath()
+		if err != nil {
+			s.storeErr = err
+			return
+		}
+		s.store = history.NewStore(filepath.Join(root, s.saveFileName))
+		s.store.MaxRecords = maxHistoryRecords
+		s.store.CompactEvery = historyCompactEvery
+	})
+	return s.store, s.storeErr
+}
+
+// 旧版纯文本历史文件的路径
+func (s *CalcState) legacyHistoryPath() string {
+	root, _ := storageRootPath()
+	return filepath.Join(root, s.legacyFileName)
+}
+
+// App 专属的安全沙盒根目录在本地文件系统中的路径
+// 追加写入、fsync 和原子替换都需要直接操作文件，因此只支持 file:// 类型的存储
+func storageRootPath() (string, error) {
+	rootURI := fyne.CurrentApp().Storage().RootURI()
+	if rootURI == nil || rootURI.Scheme() != "file" {
+		return "", errors.New("当前平台不支持本地文件存储")
+	}
+	return rootURI.Path(), nil
+}
+
+// 提示用户历史记录保存失败，同一个错误只提示一次
+func (s *CalcState) reportStorageError(err error) {
+	fyne.LogError("history storage", err)
+
+	msg := err.Error()
+	s.historyErrMutex.Lock()
+	if msg == s.lastStorageError {
+		s.historyErrMutex.Unlock()
+		return
+	}
+	s.lastStorageError = msg
+	s.historyErrMutex.Unlock()
+
+	if s.win == nil {
+		return
 	}
-	return data
+	fyne.Do(func() {
+		dialog.ShowError(fmt.Errorf("历史记录保存失败：%w", err), s.win)
+	})
 }
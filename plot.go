@@ -0,0 +1,529 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/gzjjjfree/MemoryCalculator/calc/engine"
+	"github.com/gzjjjfree/MemoryCalculator/calc/plot"
+)
+
+// 保存绘图算式时使用的 Preferences 键名
+const prefPlotExpressions = "plot.expressions" // []string，各条曲线的算式
+
+// 最多同时绘制的曲线数
+const maxPlotCurves = 6
+
+// 每条曲线的取样段数，也用于查找零点和交点
+const plotSamples = 600
+
+// 刻度、追踪、零点和交点的显示格式：保留 8 位有效数字
+var plotFormat = engine.Options{Precision: 8}
+
+// 曲线颜色，按顺序使用
+var plotColors = []color.NRGBA{
+	{R: 0x1e, G: 0x88, B: 0xe5, A: 0xff}, // 蓝
+	{R: 0xe5, G: 0x39, B: 0x35, A: 0xff}, // 红
+	{R: 0x43, G: 0xa0, B: 0x47, A: 0xff}, // 绿
+	{R: 0xfb, G: 0x8c, B: 0x00, A: 0xff}, // 橙
+	{R: 0x8e, G: 0x24, B: 0xaa, A: 0xff}, // 紫
+	{R: 0x00, G: 0x89, B: 0x7b, A: 0xff}, // 青
+}
+
+// 一条曲线：算式输入框和编译后的函数
+type plotCurve struct {
+	entry *widget.Entry
+	fn    plot.Func // 算式有误或为空时为 nil
+	err   error     // 算式的错误
+	color color.NRGBA
+}
+
+// 绘图窗口：上方输入各条曲线的算式，中间是图像，下方是缩放按钮、查找零点和交点以及追踪的结果
+type plotter struct {
+	state  *CalcState
+	win    fyne.Window
+	curves []*plotCurve
+
+	rows   *fyne.Container // 算式输入行
+	addBtn *widget.Button
+	graph  *plotGraph
+	info   *widget.Label // 追踪、零点和交点的结果，或者算式的错误
+}
+
+// 打开绘图窗口
+func showPlot(state *CalcState) {
+	newPlotter(state).win.Show()
+}
+
+// 创建绘图窗口，恢复上次的算式
+func newPlotter(state *CalcState) *plotter {
+	p := &plotter{state: state}
+	p.win = fyne.CurrentApp().NewWindow("绘图")
+	p.win.Resize(fyne.NewSize(400, 640))
+
+	p.graph = newPlotGraph()
+	p.graph.onTrace = p.showTrace
+	p.rows = container.NewVBox()
+	p.addBtn = widget.NewButtonWithIcon("添加曲线", theme.ContentAddIcon(), func() {
+		p.addCurve("")
+	})
+	p.addBtn.Importance = widget.LowImportance
+	p.info = widget.NewLabel("")
+	p.info.Wrapping = fyne.TextWrapWord
+	p.info.SizeName = SmallFont
+
+	exprs := fyne.CurrentApp().Preferences().StringList(prefPlotExpressions)
+	if len(exprs) == 0 {
+		exprs = []string{""}
+	}
+	for _, expr := range exprs {
+		p.addCurve(expr)
+	}
+
+	zoom := func(factor float64) func() {
+		return func() {
+			v := p.graph.view
+			p.graph.setView(v.Zoom(factor, (v.XMin+v.XMax)/2, (v.YMin+v.YMax)/2))
+		}
+	}
+	button := func(icon fyne.Resource, tapped func()) *widget.Button {
+		b := widget.NewButtonWithIcon("", icon, tapped)
+		b.Importance = widget.LowImportance
+		return b
+	}
+	angle := "DEG"
+	if isRad, _ := state.isRadian.Get(); isRad {
+		angle = "RAD"
+	}
+	angleLabel := widget.NewLabel(angle)
+	angleLabel.SizeName = SmallFont
+	angleLabel.Importance = widget.LowImportance
+
+	toolbar := container.NewHBox(
+		button(theme.ZoomInIcon(), zoom(0.5)),
+		button(theme.ZoomOutIcon(), zoom(2)),
+		button(theme.ViewRestoreIcon(), func() { p.graph.setView(plot.DefaultViewport) }),
+		angleLabel,
+		layout.NewSpacer(),
+		widget.NewButton("零点", p.findRoots),
+		widget.NewButton("交点", p.findIntersections),
+	)
+
+	top := container.NewVBox(p.rows, p.addBtn)
+	bottom := container.NewVBox(toolbar, p.info)
+	p.win.SetContent(container.NewBorder(top, bottom, nil, nil, p.graph))
+	return p
+}
+
+// 添加一行算式输入
+func (p *plotter) addCurve(expr string) {
+	if len(p.curves) >= maxPlotCurves {
+		return
+	}
+	c := &plotCurve{entry: widget.NewEntry(), color: p.nextColor()}
+	c.entry.SetPlaceHolder("如 sin(x) 或 x^2-2")
+	c.entry.SetText(expr)
+	c.entry.OnChanged = func(string) {
+		p.compile(c)
+		p.save()
+		p.refresh()
+	}
+	p.compile(c)
+	p.curves = append(p.curves, c)
+	p.rebuildRows()
+	p.refresh()
+}
+
+// 没有被其他曲线使用的第一种颜色
+func (p *plotter) nextColor() color.NRGBA {
+	for _, col := range plotColors {
+		used := false
+		for _, c := range p.curves {
+			used = used || c.color == col
+		}
+		if !used {
+			return col
+		}
+	}
+	return plotColors[0]
+}
+
+// 删除一条曲线，至少保留一行
+func (p *plotter) removeCurve(c *plotCurve) {
+	for i, other := range p.curves {
+		if other == c {
+			p.curves = append(p.curves[:i], p.curves[i+1:]...)
+			break
+		}
+	}
+	if len(p.curves) == 0 {
+		p.addCurve("")
+		return
+	}
+	p.save()
+	p.rebuildRows()
+	p.refresh()
+}
+
+// 重建算式输入行：左侧是曲线颜色和名称，右侧是删除按钮
+func (p *plotter) rebuildRows() {
+	p.rows.Objects = nil
+	for i, c := range p.curves {
+		swatch := canvas.NewRectangle(c.color)
+		swatch.SetMinSize(fyne.NewSize(theme.Padding()*2, theme.Padding()*2))
+		swatch.CornerRadius = theme.Padding()
+		name := widget.NewLabel(curveName(i) + " =")
+		deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() { p.removeCurve(c) })
+		deleteBtn.Importance = widget.LowImportance
+		p.rows.Add(container.NewBorder(nil, nil,
+			container.NewHBox(container.NewCenter(swatch), name), deleteBtn, c.entry))
+	}
+	if len(p.curves) < maxPlotCurves {
+		p.addBtn.Enable()
+	} else {
+		p.addBtn.Disable()
+	}
+	// 行数变化后上方的高度随之变化，需要重新布局整个窗口
+	if content := p.win.Content(); content != nil {
+		content.Refresh()
+	}
+}
+
+// 第 i 条曲线的名称：y1、y2…
+func curveName(i int) string {
+	return "y" + strconv.Itoa(i+1)
+}
+
+// 编译一条曲线的算式：可以写成 y=…，*、/ 等符号与粘贴时一样替换
+func (p *plotter) compile(c *plotCurve) {
+	c.fn, c.err = nil, nil
+	text := strings.TrimSpace(c.entry.Text)
+	if rest, ok := strings.CutPrefix(strings.ReplaceAll(text, " ", ""), "y="); ok {
+		text = rest
+	}
+	if text == "" {
+		return
+	}
+	expr, err := sanitizePaste(text)
+	if err == nil {
+		c.fn, err = plot.Compile(checkLastOperator(expr), p.state.engineOptions())
+	}
+	c.err = err
+}
+
+// 保存全部算式
+func (p *plotter) save() {
+	exprs := make([]string, len(p.curves))
+	for i, c := range p.curves {
+		exprs[i] = c.entry.Text
+	}
+	fyne.CurrentApp().Preferences().SetStringList(prefPlotExpressions, exprs)
+}
+
+// 重新绘制，并显示算式中的错误；零点、交点和追踪的结果随之清除
+func (p *plotter) refresh() {
+	var errs []string
+	var curves []graphCurve
+	for i, c := range p.curves {
+		if c.fn != nil {
+			curves = append(curves, graphCurve{fn: c.fn, color: c.color})
+		}
+		if c.err != nil {
+			errs = append(errs, curveName(i)+"："+c.err.Error())
+		}
+	}
+	p.graph.curves = curves
+	p.graph.marks = nil
+	p.graph.tracing = false
+	p.info.SetText(strings.Join(errs, "\n"))
+	p.graph.Refresh()
+}
+
+// 已经编译成功的曲线及其名称
+func (p *plotter) valid() (names []string, fns []plot.Func) {
+	for i, c := range p.curves {
+		if c.fn != nil {
+			names = append(names, curveName(i))
+			fns = append(fns, c.fn)
+		}
+	}
+	return names, fns
+}
+
+// 显示追踪的结果：x 以及各条曲线在 x 处的值
+func (p *plotter) showTrace(x float64) {
+	parts := []string{"x = " + plotFormat.Format(x)}
+	names, fns := p.valid()
+	for i, fn := range fns {
+		y := "无定义"
+		if v, ok := fn(x); ok {
+			y = plotFormat.Format(v)
+		}
+		parts = append(parts, names[i]+" = "+y)
+	}
+	p.info.SetText(strings.Join(parts, "   "))
+}
+
+// 在可见的 x 范围内查找各条曲线的零点，标在图上
+func (p *plotter) findRoots() {
+	names, fns := p.valid()
+	if len(fns) == 0 {
+		p.info.SetText("请先输入算式")
+		return
+	}
+	v := p.graph.view
+	var marks []plot.Point
+	var lines []string
+	for i, fn := range fns {
+		roots := plot.Roots(fn, v.XMin, v.XMax, plotSamples)
+		texts := make([]string, len(roots))
+		for j, r := range roots {
+			r = snapZero(r, v.XMax-v.XMin)
+			marks = append(marks, plot.Point{X: r})
+			texts[j] = plotFormat.Format(r)
+		}
+		if len(roots) == 0 {
+			lines = append(lines, names[i]+"：范围内没有零点")
+		} else {
+			lines = append(lines, names[i]+"：x = "+strings.Join(texts, ", "))
+		}
+	}
+	p.graph.marks = marks
+	p.graph.Refresh()
+	p.info.SetText(strings.Join(lines, "\n"))
+}
+
+// 在可见的 x 范围内查找每两条曲线的交点，标在图上
+func (p *plotter) findIntersections() {
+	names, fns := p.valid()
+	if len(fns) < 2 {
+		p.info.SetText("至少需要两条曲线")
+		return
+	}
+	v := p.graph.view
+	var marks []plot.Point
+	var lines []string
+	for i := range fns {
+		for j := i + 1; j < len(fns); j++ {
+			points := plot.Intersections(fns[i], fns[j], v.XMin, v.XMax, plotSamples)
+			texts := make([]string, len(points))
+			for k, pt := range points {
+				pt = plot.Point{X: snapZero(pt.X, v.XMax-v.XMin), Y: snapZero(pt.Y, v.YMax-v.YMin)}
+				marks = append(marks, pt)
+				texts[k] = fmt.Sprintf("(%s, %s)", plotFormat.Format(pt.X), plotFormat.Format(pt.Y))
+			}
+			if len(points) == 0 {
+				texts = []string{"范围内没有交点"}
+			}
+			lines = append(lines, names[i]+" 与 "+names[j]+"："+strings.Join(texts, ", "))
+		}
+	}
+	p.graph.marks = marks
+	p.graph.Refresh()
+	p.info.SetText(strings.Join(lines, "\n"))
+}
+
+// 与可见范围相比非常接近 0 的值显示为 0，如重根处查找到的 1e-9
+func snapZero(v, span float64) float64 {
+	if math.Abs(v) < span*1e-9 {
+		return 0
+	}
+	return v
+}
+
+// 图像中的一条曲线
+type graphCurve struct {
+	fn    plot.Func
+	color color.NRGBA
+}
+
+// 函数图像：拖动平移，滚轮缩放，点击追踪
+type plotGraph struct {
+	widget.BaseWidget
+	view    plot.Viewport
+	curves  []graphCurve
+	marks   []plot.Point // 零点或交点
+	tracing bool         // 是否显示追踪线
+	traceX  float64
+	onTrace func(x float64)
+}
+
+func newPlotGraph() *plotGraph {
+	g := &plotGraph{view: plot.DefaultViewport}
+	g.ExtendBaseWidget(g)
+	return g
+}
+
+// 改变坐标范围并重绘
+func (g *plotGraph) setView(v plot.Viewport) {
+	g.view = v
+	g.Refresh()
+}
+
+// 图上的位置换算为坐标
+func (g *plotGraph) toData(pos fyne.Position) (float64, float64) {
+	size, v := g.Size(), g.view
+	x := v.XMin + float64(pos.X/size.Width)*(v.XMax-v.XMin)
+	y := v.YMax - float64(pos.Y/size.Height)*(v.YMax-v.YMin)
+	return x, y
+}
+
+// 拖动时平移
+func (g *plotGraph) Dragged(ev *fyne.DragEvent) {
+	size, v := g.Size(), g.view
+	dx := -float64(ev.Dragged.DX/size.Width) * (v.XMax - v.XMin)
+	dy := float64(ev.Dragged.DY/size.Height) * (v.YMax - v.YMin)
+	g.setView(v.Pan(dx, dy))
+}
+
+func (g *plotGraph) DragEnd() {}
+
+// 滚轮以指针位置为中心缩放
+func (g *plotGraph) Scrolled(ev *fyne.ScrollEvent) {
+	factor := 0.9
+	if ev.Scrolled.DY < 0 {
+		factor = 1 / factor
+	}
+	x, y := g.toData(ev.Position)
+	g.setView(g.view.Zoom(factor, x, y))
+}
+
+// 点击时追踪该处的 x，x 按一个像素的宽度取整，如 2.0000005 取为 2
+func (g *plotGraph) Tapped(ev *fyne.PointEvent) {
+	x, _ := g.toData(ev.Position)
+	step := math.Pow(10, math.Floor(math.Log10((g.view.XMax-g.view.XMin)/float64(g.Size().Width))))
+	g.traceX = math.Round(x/step) * step
+	g.tracing = true
+	g.Refresh()
+	if g.onTrace != nil {
+		g.onTrace(g.traceX)
+	}
+}
+
+func (g *plotGraph) MinSize() fyne.Size {
+	return fyne.NewSize(200, 200)
+}
+
+func (g *plotGraph) CreateRenderer() fyne.WidgetRenderer {
+	return &plotRenderer{graph: g}
+}
+
+// 每次布局或刷新时按当前大小重新生成全部图形
+type plotRenderer struct {
+	graph   *plotGraph
+	objects []fyne.CanvasObject
+}
+
+func (r *plotRenderer) Layout(size fyne.Size) {
+	r.objects = r.graph.draw(size)
+}
+
+func (r *plotRenderer) MinSize() fyne.Size {
+	return r.graph.MinSize()
+}
+
+func (r *plotRenderer) Refresh() {
+	r.objects = r.graph.draw(r.graph.Size())
+	canvas.Refresh(r.graph)
+}
+
+func (r *plotRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *plotRenderer) Destroy() {}
+
+// 生成图像：背景、网格和刻度、坐标轴、曲线、零点或交点、追踪线
+func (g *plotGraph) draw(size fyne.Size) []fyne.CanvasObject {
+	if size.Width <= 0 || size.Height <= 0 {
+		return nil
+	}
+	v := g.view
+	px := func(x float64) float32 { return float32((x - v.XMin) / (v.XMax - v.XMin) * float64(size.Width)) }
+	py := func(y float64) float32 { return float32((v.YMax - y) / (v.YMax - v.YMin) * float64(size.Height)) }
+	line := func(x1, y1, x2, y2 float32, c color.Color, width float32) *canvas.Line {
+		l := canvas.NewLine(c)
+		l.Position1, l.Position2 = fyne.NewPos(x1, y1), fyne.NewPos(x2, y2)
+		l.StrokeWidth = width
+		return l
+	}
+
+	background := canvas.NewRectangle(theme.Color(theme.ColorNameInputBackground))
+	background.Resize(size)
+	objects := []fyne.CanvasObject{background}
+
+	gridColor := theme.Color(theme.ColorNameSeparator)
+	axisColor := theme.Color(theme.ColorNameForeground)
+	labelColor := theme.Color(theme.ColorNamePlaceHolder)
+	textSize := theme.CaptionTextSize()
+
+	// 刻度标签沿坐标轴排列，坐标轴在范围外时贴着边缘
+	axisX := min(max(px(0), 0), size.Width-textSize*3)
+	axisY := min(max(py(0), 0), size.Height-textSize*1.5)
+	label := func(text string, x, y float32) {
+		t := canvas.NewText(text, labelColor)
+		t.TextSize = textSize
+		t.Move(fyne.NewPos(x+2, y))
+		objects = append(objects, t)
+	}
+	for _, x := range plot.Ticks(v.XMin, v.XMax, 6) {
+		objects = append(objects, line(px(x), 0, px(x), size.Height, gridColor, 1))
+		if x != 0 {
+			label(plotFormat.Format(x), px(x), axisY)
+		}
+	}
+	for _, y := range plot.Ticks(v.YMin, v.YMax, 6) {
+		objects = append(objects, line(0, py(y), size.Width, py(y), gridColor, 1))
+		if y != 0 {
+			label(plotFormat.Format(y), axisX, py(y))
+		}
+	}
+	if v.YMin <= 0 && v.YMax >= 0 {
+		objects = append(objects, line(0, py(0), size.Width, py(0), axisColor, 1))
+	}
+	if v.XMin <= 0 && v.XMax >= 0 {
+		objects = append(objects, line(px(0), 0, px(0), size.Height, axisColor, 1))
+	}
+
+	// 取样点数与宽度相当，线段裁剪到可见范围内
+	samples := min(plotSamples, max(int(size.Width), 100))
+	for _, c := range g.curves {
+		for _, seg := range plot.Sample(c.fn, v, samples) {
+			for i := 1; i < len(seg); i++ {
+				if a, b, ok := v.Clip(seg[i-1], seg[i]); ok {
+					objects = append(objects, line(px(a.X), py(a.Y), px(b.X), py(b.Y), c.color, 2))
+				}
+			}
+		}
+	}
+
+	dot := func(x, y float64, c color.Color) {
+		d := canvas.NewCircle(c)
+		r := theme.Padding()
+		d.Resize(fyne.NewSize(2*r, 2*r))
+		d.Move(fyne.NewPos(px(x)-r, py(y)-r))
+		objects = append(objects, d)
+	}
+	for _, m := range g.marks {
+		dot(m.X, m.Y, theme.Color(theme.ColorNameHyperlink))
+	}
+	if g.tracing {
+		objects = append(objects, line(px(g.traceX), 0, px(g.traceX), size.Height, labelColor, 1))
+		for _, c := range g.curves {
+			if y, ok := c.fn(g.traceX); ok && y >= v.YMin && y <= v.YMax {
+				dot(g.traceX, y, c.color)
+			}
+		}
+	}
+	return objects
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/gzjjjfree/MemoryCalculator/calc/integer"
+)
+
+// 保存程序员界面设置时使用的 Preferences 键名
+const (
+	prefProgrammerBits   = "programmer.bits"   // int，位宽
+	prefProgrammerSigned = "programmer.signed" // bool，是否有符号
+	prefProgrammerBase   = "programmer.base"   // int，输入使用的进制
+)
+
+// 程序员界面同时显示的进制及其名称，按显示顺序排列
+var programmerBases = []int{16, 10, 8, 2}
+
+var baseNames = map[int]string{16: "HEX", 10: "DEC", 8: "OCT", 2: "BIN"}
+
+// 各进制显示时每组的位数
+var baseGroups = map[int]int{16: 4, 10: 3, 8: 3, 2: 4}
+
+// 两侧加空格书写的二元运算符；删除时整个运算符一起删除，XOR、ROR 要排在 OR 之前
+var programmerOps = []string{"XOR", "ROR", "ROL", "AND", "MOD", "OR", "<<", ">>"}
+
+// 程序员界面：输入整数算式，同时显示结果的十六、十、八、二进制形式
+type programmer struct {
+	state *CalcState
+	word  integer.Word
+	base  int    // 输入使用的进制
+	input string // 当前算式
+	value uint64 // 最近一次算出的值
+	fresh bool   // 刚按过 =，接着输入数字时重新开始
+
+	sizeSelect  *widget.Select
+	signedCheck *widget.Check
+	exprLabel   *widget.Label
+	msgLabel    *widget.Label
+	baseBtns    map[int]*widget.Button
+	valueLabels map[int]*widget.Label
+	digitBtns   map[string]*widget.Button
+}
+
+// 创建程序员界面，恢复上次的位宽、符号和进制
+func newProgrammer(state *CalcState) *programmer {
+	p := &programmer{
+		state:       state,
+		word:        integer.Word{Bits: 32, Signed: true},
+		base:        10,
+		baseBtns:    map[int]*widget.Button{},
+		valueLabels: map[int]*widget.Label{},
+		digitBtns:   map[string]*widget.Button{},
+	}
+	if app := fyne.CurrentApp(); app != nil {
+		prefs := app.Preferences()
+		if bits := prefs.IntWithFallback(prefProgrammerBits, 32); slices.Contains(integer.Sizes, bits) {
+			p.word.Bits = bits
+		}
+		p.word.Signed = prefs.BoolWithFallback(prefProgrammerSigned, true)
+		if base := prefs.IntWithFallback(prefProgrammerBase, 10); baseNames[base] != "" {
+			p.base = base
+		}
+	}
+
+	sizes := make([]string, len(integer.Sizes))
+	for i, bits := range integer.Sizes {
+		sizes[i] = strconv.Itoa(bits) + " 位"
+	}
+	p.sizeSelect = widget.NewSelect(sizes, func(label string) {
+		bits, _ := strconv.Atoi(strings.TrimSuffix(label, " 位"))
+		p.setWord(integer.Word{Bits: bits, Signed: p.word.Signed})
+	})
+	p.signedCheck = widget.NewCheck("有符号", func(on bool) {
+		p.setWord(integer.Word{Bits: p.word.Bits, Signed: on})
+	})
+
+	p.exprLabel = widget.NewLabel("0")
+	p.exprLabel.Alignment = fyne.TextAlignTrailing
+	p.exprLabel.TextStyle = fyne.TextStyle{Bold: true}
+	p.exprLabel.Wrapping = fyne.TextWrapBreak
+	p.msgLabel = widget.NewLabel("")
+	p.msgLabel.Alignment = fyne.TextAlignTrailing
+	p.msgLabel.Importance = widget.DangerImportance
+
+	for _, base := range programmerBases {
+		p.baseBtns[base] = widget.NewButton(baseNames[base], func() { p.setBase(base) })
+		label := widget.NewLabel("0")
+		label.Alignment = fyne.TextAlignTrailing
+		label.Wrapping = fyne.TextWrapBreak
+		label.SizeName = SmallFont
+		p.valueLabels[base] = label
+	}
+
+	p.sizeSelect.SetSelected(strconv.Itoa(p.word.Bits) + " 位")
+	p.signedCheck.SetChecked(p.word.Signed)
+	return p
+}
+
+// 保存当前的位宽、符号和进制
+func (p *programmer) save() {
+	if app := fyne.CurrentApp(); app != nil {
+		prefs := app.Preferences()
+		prefs.SetInt(prefProgrammerBits, p.word.Bits)
+		prefs.SetBool(prefProgrammerSigned, p.word.Signed)
+		prefs.SetInt(prefProgrammerBase, p.base)
+	}
+}
+
+// 切换位宽或符号：当前值截断到新的位宽，算式替换为这个值
+func (p *programmer) setWord(w integer.Word) {
+	if w == p.word {
+		return
+	}
+	p.word = w
+	p.value = w.Wrap(p.value)
+	if p.input != "" {
+		p.input = w.Format(p.value, p.base)
+	}
+	p.save()
+	p.recompute()
+}
+
+// 切换输入的进制：当前值改用新的进制书写
+func (p *programmer) setBase(base int) {
+	if base == p.base {
+		return
+	}
+	if _, err := p.word.Evaluate(p.input, p.base); err == nil && p.input != "" {
+		p.input = p.word.Format(p.value, base)
+	} else {
+		p.input = "" // 算式有错时无法换写，直接清空
+	}
+	p.base = base
+	p.fresh = false
+	p.save()
+	p.recompute()
+}
+
+// 判断按键是否为当前进制中的一位数字
+func (p *programmer) isDigit(char string) bool {
+	if len(char) != 1 {
+		return false
+	}
+	_, err := strconv.ParseUint(char, p.base, 8)
+	return err == nil
+}
+
+// 处理转来的按键，作为 CalcState.onProgramInput 使用：其中的 "C" 是清除键，不是十六进制数字
+func (p *programmer) onKey(char string) {
+	if char == "C" {
+		p.clear()
+		return
+	}
+	p.onInput(char)
+}
+
+// 清除算式
+func (p *programmer) clear() {
+	p.input = ""
+	p.fresh = false
+	p.recompute()
+}
+
+// 处理程序员键盘的输入，"C" 是十六进制数字；科学函数等其他按键在程序员界面中无效
+func (p *programmer) onInput(char string) {
+	switch {
+	case char == "⌫":
+		p.input = trimLastToken(p.input)
+	case char == "=":
+		// 把算式替换为计算结果
+		if v, err := p.word.Evaluate(p.input, p.base); err == nil {
+			p.input = p.word.Format(v, p.base)
+			p.fresh = true
+			p.recompute()
+			return
+		}
+	case p.isDigit(char):
+		if p.fresh || p.input == "0" {
+			p.input = ""
+		}
+		p.input += char
+	case char == "NOT":
+		p.input += "NOT "
+	case slices.Contains(programmerOps, char):
+		p.input = strings.TrimRight(p.input, " ") + " " + char + " "
+	case len([]rune(char)) == 1 && strings.Contains("+-×÷()", char):
+		p.input += char
+	default:
+		return
+	}
+	p.fresh = false
+	p.recompute()
+}
+
+// 删除算式末尾的一个数字、符号或整个运算符单词
+func trimLastToken(input string) string {
+	s := strings.TrimRight(input, " ")
+	for _, op := range append(programmerOps, "NOT") {
+		if strings.HasSuffix(s, op) {
+			return strings.TrimRight(strings.TrimSuffix(s, op), " ")
+		}
+	}
+	if runes := []rune(s); len(runes) > 0 {
+		return string(runes[:len(runes)-1])
+	}
+	return s
+}
+
+// 处理键入的字符：十六进制数字不区分大小写，& | ^ ~ < > % 对应位运算符
+// 返回 false 的字符交给默认的键位处理
+func (p *programmer) typeRune(r rune) bool {
+	switch {
+	case r < unicode.MaxASCII && (unicode.IsDigit(r) || unicode.IsLetter(r) && unicode.ToUpper(r) <= 'F'):
+		p.onInput(string(unicode.ToUpper(r))) // 当前进制中无效的数字被忽略
+	case r == '&':
+		p.onInput("AND")
+	case r == '|':
+		p.onInput("OR")
+	case r == '^':
+		p.onInput("XOR")
+	case r == '~':
+		p.onInput("NOT")
+	case r == '<':
+		p.onInput("<<")
+	case r == '>':
+		p.onInput(">>")
+	case r == '%':
+		p.onInput("MOD")
+	default:
+		return false
+	}
+	return true
+}
+
+// 重新计算算式，并刷新界面；算式不完整时保留上一次的值
+func (p *programmer) recompute() {
+	v, err := p.word.Evaluate(p.input, p.base)
+	switch {
+	case err == nil:
+		p.value = v
+		p.msgLabel.SetText("")
+	case errors.Is(err, integer.ErrIncomplete):
+		p.msgLabel.SetText("")
+	default:
+		p.msgLabel.SetText(err.Error())
+	}
+
+	if p.input == "" {
+		p.exprLabel.SetText("0")
+	} else {
+		p.exprLabel.SetText(p.input)
+	}
+	for _, base := range programmerBases {
+		p.valueLabels[base].SetText(integer.Group(p.word.Format(p.value, base), baseGroups[base]))
+		if base == p.base {
+			p.baseBtns[base].Importance = widget.HighImportance
+		} else {
+			p.baseBtns[base].Importance = widget.LowImportance
+		}
+		p.baseBtns[base].Refresh()
+	}
+	for d, btn := range p.digitBtns {
+		if p.isDigit(d) {
+			btn.Enable()
+		} else {
+			btn.Disable()
+		}
+	}
+}
+
+// 程序员界面的显示区：位宽和符号、算式、四种进制的结果
+func (p *programmer) view() fyne.CanvasObject {
+	rows := []fyne.CanvasObject{
+		container.NewBorder(nil, nil, p.sizeSelect, p.signedCheck),
+		p.exprLabel,
+		p.msgLabel,
+	}
+	for _, base := range programmerBases {
+		rows = append(rows, container.NewBorder(nil, nil, p.baseBtns[base], nil, p.valueLabels[base]))
+	}
+	return container.NewVScroll(container.NewVBox(rows...))
+}
+
+// 程序员键盘（5x7 布局）：A–F 数字、位运算、移位和循环移位
+func (p *programmer) keypad() fyne.CanvasObject {
+	// 数字键按当前进制启用或禁用，需要保留按钮的引用
+	digit := func(d string) fyne.CanvasObject {
+		obj := makeBtn(d, nil, 0, func() { p.onInput(d) })
+		p.digitBtns[d] = obj.(*fyne.Container).Objects[0].(*widget.Button)
+		return obj
+	}
+	op := func(text string) fyne.CanvasObject {
+		return makeBtn(text, nil, 1, func() { p.onInput(text) })
+	}
+
+	grid := container.NewGridWithColumns(5,
+		op("AND"), op("OR"), op("XOR"), op("NOT"),
+		makeBtn("", theme.ContentClearIcon(), 2, p.clear),
+
+		op("<<"), op(">>"), op("ROL"), op("ROR"),
+		makeBtn("⌫", nil, 0, func() { p.onInput("⌫") }),
+
+		digit("A"), digit("B"), op("("), op(")"), op("÷"),
+		digit("C"), digit("7"), digit("8"), digit("9"), op("×"),
+		digit("D"), digit("4"), digit("5"), digit("6"), op("-"),
+		digit("E"), digit("1"), digit("2"), digit("3"), op("+"),
+
+		digit("F"),
+		makeBtn("", theme.GridIcon(), 0, p.state.OnGoBigGrid),
+		digit("0"),
+		op("MOD"),
+		makeBtn("=", nil, 2, func() { p.onInput("=") }),
+	)
+	p.recompute()
+	return grid
+}
+
+// 切换到程序员键盘时接管键盘，离开时交还
+func (p *programmer) setActive(on bool) {
+	s := p.state
+	s.isProgramming = on
+	if on {
+		s.onProgramInput = p.onKey
+		s.onTypedRune = p.typeRune
+	} else {
+		s.onProgramInput = nil
+		s.onTypedRune = nil
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/gzjjjfree/MemoryCalculator/calc/engine"
+)
+
+// 保存设置时使用的 Preferences 键名
+const (
+	prefExactMode     = "settings.exact"   // bool，是否使用精确小数模式
+	prefDecimalPlaces = "settings.places"  // int，精确模式下保留的小数位数
+	prefComplexMode   = "settings.complex" // bool，是否使用复数模式
+	prefPolarForm     = "settings.polar"   // bool，复数结果是否按极坐标显示
+)
+
+// 精确模式下可选的小数位数
+var decimalPlaceOptions = []string{"2", "4", "6", "8", "10", "15", "20", "30"}
+
+// 从 Preferences 恢复设置
+func (s *CalcState) loadSettings() {
+	if app := fyne.CurrentApp(); app != nil {
+		prefs := app.Preferences()
+		s.isExact.Set(prefs.BoolWithFallback(prefExactMode, false))
+		s.decimalPlaces.Set(prefs.IntWithFallback(prefDecimalPlaces, engine.DefaultPlaces))
+		s.isComplex.Set(prefs.BoolWithFallback(prefComplexMode, false))
+		s.isPolar.Set(prefs.BoolWithFallback(prefPolarForm, false))
+	}
+}
+
+// 保存设置，并用新的设置刷新实时预览
+func (s *CalcState) saveSettings() {
+	if app := fyne.CurrentApp(); app != nil {
+		prefs := app.Preferences()
+		exact, _ := s.isExact.Get()
+		places, _ := s.decimalPlaces.Get()
+		prefs.SetBool(prefExactMode, exact)
+		prefs.SetInt(prefDecimalPlaces, places)
+		isComplex, _ := s.isComplex.Get()
+		isPolar, _ := s.isPolar.Get()
+		prefs.SetBool(prefComplexMode, isComplex)
+		prefs.SetBool(prefPolarForm, isPolar)
+	}
+
+	if current, _ := s.display.Get(); current != "" && !s.isNewNumber {
+		s.updatePreview(current)
+	}
+}
+
+// 显示设置对话框
+func showSettings(state *CalcState) {
+	exact, _ := state.isExact.Get()
+	places, _ := state.decimalPlaces.Get()
+
+	placesSelect := widget.NewSelect(decimalPlaceOptions, nil)
+	placesSelect.SetSelected(strconv.Itoa(places))
+
+	exactCheck := widget.NewCheck("精确小数（适合金额计算）", func(on bool) {
+		if on {
+			placesSelect.Enable()
+		} else {
+			placesSelect.Disable()
+		}
+	})
+	exactCheck.SetChecked(exact)
+	if !exact {
+		placesSelect.Disable()
+	}
+
+	form := []*widget.FormItem{
+		widget.NewFormItem("计算模式", exactCheck),
+		widget.NewFormItem("小数位数", placesSelect),
+	}
+	dialog.ShowForm("设置", "确定", "取消", form, func(ok bool) {
+		if !ok {
+			return
+		}
+		state.isExact.Set(exactCheck.Checked)
+		if n, err := strconv.Atoi(placesSelect.Selected); err == nil {
+			state.decimalPlaces.Set(n)
+		}
+		state.saveSettings()
+	}, state.win)
+}
@@ -0,0 +1,500 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/gzjjjfree/MemoryCalculator/calc/engine"
+	"github.com/gzjjjfree/MemoryCalculator/calc/plot"
+	"github.com/gzjjjfree/MemoryCalculator/calc/solve"
+)
+
+// 保存解方程输入时使用的 Preferences 键名
+const (
+	prefSolverEquation   = "solver.equation"   // 方程
+	prefSolverRange      = "solver.range"      // []string，下限、上限、初值、精度
+	prefSolverPolynomial = "solver.polynomial" // []string，多项式的系数，从最高次开始
+	prefSolverSystem     = "solver.system"     // []string，方程组的增广矩阵，按行排列
+)
+
+// 求根时把范围分成的段数
+const solverSamples = 1000
+
+// 根的显示格式：保留 12 位有效数字
+var solverFormat = engine.Options{Precision: 12}
+
+// 方程页输入框的默认值：下限、上限、初值（空白表示取范围的中点）、精度
+var solverRangeDefaults = []string{"-10", "10", "", "1e-12"}
+
+// 多项式各项的名称，从四次项开始
+var polyTerms = []string{"x⁴", "x³", "x²", "x", ""}
+
+// 解方程窗口：分为方程、多项式和方程组三页
+type solver struct {
+	state *CalcState
+	win   fyne.Window
+
+	// 方程页：含 x 的方程，在范围内求根
+	equation *widget.Entry
+	bounds   []*widget.Entry // 下限、上限、初值、精度
+	rootInfo *widget.Label
+
+	// 多项式页：系数从最高次开始，四次时有 5 个
+	degree   *widget.Select
+	formula  *widget.Label
+	coeffs   []*widget.Entry
+	polyRows *fyne.Container
+	polyInfo *widget.Label
+
+	// 方程组页：n 行 n+1 列的增广矩阵，最后一列是常数项
+	size       *widget.Select
+	cells      [][]*widget.Entry
+	grid       *fyne.Container
+	systemInfo *widget.Label
+}
+
+// 打开解方程窗口
+func showSolver(state *CalcState) {
+	newSolver(state).win.Show()
+}
+
+// 创建解方程窗口，恢复上次的输入
+func newSolver(state *CalcState) *solver {
+	s := &solver{state: state}
+	s.win = fyne.CurrentApp().NewWindow("解方程")
+	s.win.Resize(fyne.NewSize(400, 520))
+
+	tabs := container.NewAppTabs(
+		container.NewTabItem("方程", s.equationView()),
+		container.NewTabItem("多项式", s.polynomialView()),
+		container.NewTabItem("方程组", s.systemView()),
+	)
+	s.win.SetContent(tabs)
+	return s
+}
+
+// 结果标签：可以选中复制
+func newSolverInfo() *widget.Label {
+	l := widget.NewLabel("")
+	l.Wrapping = fyne.TextWrapWord
+	l.Selectable = true
+	return l
+}
+
+// 方程页：方程、范围、初值和精度，求离初值最近的根或者范围内的全部根
+func (s *solver) equationView() fyne.CanvasObject {
+	prefs := fyne.CurrentApp().Preferences()
+	s.equation = widget.NewEntry()
+	s.equation.SetPlaceHolder("如 x^3-2x=5，没有 = 时求 …=0")
+	s.equation.SetText(prefs.String(prefSolverEquation))
+	s.equation.OnSubmitted = func(string) { s.findAll() }
+
+	saved := prefs.StringListWithFallback(prefSolverRange, solverRangeDefaults)
+	names := []string{"下限", "上限", "初值", "精度"}
+	form := container.New(layout.NewFormLayout())
+	s.bounds = make([]*widget.Entry, len(names))
+	for i, name := range names {
+		e := widget.NewEntry()
+		if i < len(saved) {
+			e.SetText(saved[i])
+		}
+		if i == 2 {
+			e.SetPlaceHolder("空白时取范围的中点")
+		}
+		s.bounds[i] = e
+		form.Add(widget.NewLabel(name))
+		form.Add(e)
+	}
+	s.rootInfo = newSolverInfo()
+
+	buttons := container.NewGridWithColumns(2,
+		widget.NewButton("求根", s.findRoot),
+		widget.NewButton("全部根", s.findAll),
+	)
+	top := container.NewVBox(s.equation, form, buttons)
+	return container.NewBorder(top, nil, nil, nil, container.NewVScroll(s.rootInfo))
+}
+
+// 把方程编译为 左边 - 右边 的函数；没有 = 时为 左边 = 0
+func compileEquation(text string, opts engine.Options) (solve.Func, error) {
+	left, right, found := strings.Cut(text, "=")
+	if strings.Contains(right, "=") {
+		return nil, errors.New("方程中只能有一个 =")
+	}
+	if strings.TrimSpace(left) == "" || found && strings.TrimSpace(right) == "" {
+		return nil, errors.New("请输入方程")
+	}
+	// 两边分别整理，否则 sanitizePaste 会把 = 右边当作结果去掉
+	expr, err := sanitizePaste(left)
+	if err != nil {
+		return nil, err
+	}
+	expr = "(" + checkLastOperator(expr) + ")"
+	if found {
+		r, err := sanitizePaste(right)
+		if err != nil {
+			return nil, err
+		}
+		expr += "-(" + checkLastOperator(r) + ")"
+	}
+	f, err := plot.Compile(expr, opts)
+	if err != nil {
+		return nil, err
+	}
+	return solve.Func(f), nil
+}
+
+// 计算输入框中的算式，结果必须是有限的实数；空白时为 fallback
+// 先按数字解析，1e-12 这样的科学计数法不会被当作 1×e-12
+func (s *solver) number(e *widget.Entry, fallback float64) (float64, error) {
+	text := strings.TrimSpace(e.Text)
+	if text == "" {
+		return fallback, nil
+	}
+	if v, err := strconv.ParseFloat(text, 64); err == nil && !math.IsNaN(v) && !math.IsInf(v, 0) {
+		return v, nil
+	}
+	expr, err := sanitizePaste(e.Text)
+	if err != nil {
+		return 0, err
+	}
+	opts := s.state.engineOptions()
+	opts.Exact, opts.Complex = false, false
+	res, err := engine.Evaluate(checkLastOperator(expr), opts)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(res.Value) || math.IsInf(res.Value, 0) {
+		return 0, errors.New("必须是有限的实数")
+	}
+	return res.Value, nil
+}
+
+// 读取方程页的输入并保存；出错时显示原因并返回 false
+func (s *solver) readEquation() (f solve.Func, lo, hi, guess, tol float64, ok bool) {
+	prefs := fyne.CurrentApp().Preferences()
+	prefs.SetString(prefSolverEquation, s.equation.Text)
+	texts := make([]string, len(s.bounds))
+	for i, e := range s.bounds {
+		texts[i] = e.Text
+	}
+	prefs.SetStringList(prefSolverRange, texts)
+
+	fail := func(msg string) (solve.Func, float64, float64, float64, float64, bool) {
+		s.rootInfo.SetText(msg)
+		return nil, 0, 0, 0, 0, false
+	}
+	f, err := compileEquation(s.equation.Text, s.state.engineOptions())
+	if err != nil {
+		return fail("方程：" + err.Error())
+	}
+	if lo, err = s.number(s.bounds[0], -10); err != nil {
+		return fail("下限：" + err.Error())
+	}
+	if hi, err = s.number(s.bounds[1], 10); err != nil {
+		return fail("上限：" + err.Error())
+	}
+	if !(lo < hi) {
+		return fail(solve.ErrInvalidSpan.Error())
+	}
+	if guess, err = s.number(s.bounds[2], (lo+hi)/2); err != nil {
+		return fail("初值：" + err.Error())
+	}
+	if tol, err = s.number(s.bounds[3], 1e-12); err != nil || tol <= 0 {
+		return fail("精度必须是正数")
+	}
+	return f, lo, hi, guess, tol, true
+}
+
+// 求离初值最近的一个根
+func (s *solver) findRoot() {
+	f, lo, hi, guess, tol, ok := s.readEquation()
+	if !ok {
+		return
+	}
+	x, err := solve.Solve(f, guess, lo, hi, tol, solverSamples)
+	if err != nil {
+		s.rootInfo.SetText(err.Error())
+		return
+	}
+	s.rootInfo.SetText("x = " + solverFormat.Format(x) + residual(f, x))
+}
+
+// 求范围内的全部根
+func (s *solver) findAll() {
+	f, lo, hi, _, tol, ok := s.readEquation()
+	if !ok {
+		return
+	}
+	roots := solve.All(f, lo, hi, tol, solverSamples)
+	if len(roots) == 0 {
+		s.rootInfo.SetText(solve.ErrNoRoot.Error())
+		return
+	}
+	lines := make([]string, len(roots))
+	for i, x := range roots {
+		lines[i] = rootName(i, len(roots)) + " = " + solverFormat.Format(x) + residual(f, x)
+	}
+	s.rootInfo.SetText(strings.Join(lines, "\n"))
+}
+
+// 根代入方程后两边的差明显不为 0 时（如重根附近精度不足）附在根的后面
+func residual(f solve.Func, x float64) string {
+	y, ok := f(x)
+	if !ok || math.Abs(y) < 1e-9 {
+		return ""
+	}
+	return "（误差 " + strconv.FormatFloat(y, 'g', 2, 64) + "）"
+}
+
+// 第 i 个根的名称：只有一个根时为 x，否则为 x1、x2…
+func rootName(i, n int) string {
+	if n == 1 {
+		return "x"
+	}
+	return "x" + strconv.Itoa(i+1)
+}
+
+// 多项式页：选择次数后输入各项系数
+func (s *solver) polynomialView() fyne.CanvasObject {
+	saved := fyne.CurrentApp().Preferences().StringList(prefSolverPolynomial)
+	s.coeffs = make([]*widget.Entry, len(polyTerms))
+	for i := range s.coeffs {
+		s.coeffs[i] = widget.NewEntry()
+		s.coeffs[i].SetPlaceHolder("0")
+	}
+	// 保存的系数从最高次开始，对齐到常数项
+	for i, text := range saved {
+		if j := len(polyTerms) - len(saved) + i; j >= 0 {
+			s.coeffs[j].SetText(text)
+		}
+	}
+	s.formula = widget.NewLabel("")
+	s.polyRows = container.New(layout.NewFormLayout())
+	s.polyInfo = newSolverInfo()
+
+	s.degree = widget.NewSelect([]string{"一次", "二次", "三次", "四次"}, func(string) { s.rebuildCoeffs() })
+	if n := len(saved) - 1; n >= 1 && n <= 4 {
+		s.degree.SetSelectedIndex(n - 1)
+	} else {
+		s.degree.SetSelectedIndex(1)
+	}
+
+	top := container.NewVBox(
+		container.NewBorder(nil, nil, widget.NewLabel("次数"), nil, s.degree),
+		s.formula, s.polyRows,
+		widget.NewButton("求解", s.solvePolynomial),
+	)
+	return container.NewBorder(top, nil, nil, nil, container.NewVScroll(s.polyInfo))
+}
+
+// 当前选择的多项式次数
+func (s *solver) polyDegree() int {
+	return s.degree.SelectedIndex() + 1
+}
+
+// 按次数重建系数输入行，并显示方程的形式，如 ax² + bx + c = 0
+func (s *solver) rebuildCoeffs() {
+	n := s.polyDegree()
+	entries := s.coeffs[len(polyTerms)-n-1:]
+	terms := make([]string, len(entries))
+	s.polyRows.Objects = nil
+	for i, e := range entries {
+		name := string(rune('a' + i))
+		terms[i] = name + polyTerms[len(polyTerms)-n-1+i]
+		s.polyRows.Add(widget.NewLabel(name))
+		s.polyRows.Add(e)
+	}
+	s.formula.SetText(strings.Join(terms, " + ") + " = 0")
+	s.polyInfo.SetText("")
+	s.relayout()
+}
+
+// 输入行数变化后上方的高度随之变化，需要重新布局整个窗口
+func (s *solver) relayout() {
+	if content := s.win.Content(); content != nil {
+		content.Refresh()
+	}
+}
+
+// 精确计算输入框中的算式：有理数保持为分数，无理数（如 √2）按浮点数的值转为分数；空白时为 0
+func (s *solver) rational(e *widget.Entry) (*big.Rat, error) {
+	if strings.TrimSpace(e.Text) == "" {
+		return new(big.Rat), nil
+	}
+	expr, err := sanitizePaste(e.Text)
+	if err != nil {
+		return nil, err
+	}
+	opts := s.state.engineOptions()
+	opts.Exact, opts.Complex = true, false
+	res, err := engine.Evaluate(checkLastOperator(expr), opts)
+	if err != nil {
+		return nil, err
+	}
+	if res.Exact == nil {
+		return new(big.Rat).SetFloat64(res.Value), nil // "0" 不经过精确求值
+	}
+	return res.Exact, nil
+}
+
+// 求解多项式方程，列出全部实根和复数根，有理数根以分数显示
+func (s *solver) solvePolynomial() {
+	n := s.polyDegree()
+	entries := s.coeffs[len(polyTerms)-n-1:]
+	texts := make([]string, len(entries))
+	p := make(solve.Polynomial, len(entries))
+	for i, e := range entries {
+		texts[i] = e.Text
+		r, err := s.rational(e)
+		if err != nil {
+			s.polyInfo.SetText(fmt.Sprintf("%c：%v", 'a'+i, err))
+			return
+		}
+		p[len(entries)-1-i] = r
+	}
+	fyne.CurrentApp().Preferences().SetStringList(prefSolverPolynomial, texts)
+
+	if p.Degree() != n {
+		s.polyInfo.SetText("a 不能为 0")
+		return
+	}
+	roots, err := p.Roots()
+	if err != nil {
+		s.polyInfo.SetText(err.Error())
+		return
+	}
+	lines := make([]string, len(roots))
+	for i, r := range roots {
+		var value string
+		switch {
+		case r.Exact != nil:
+			value = formatRat(r.Exact)
+		case r.IsReal():
+			value = solverFormat.Format(real(r.Value))
+		default:
+			value = solverFormat.FormatComplex(r.Value)
+		}
+		lines[i] = rootName(i, len(roots)) + " = " + value
+	}
+	s.polyInfo.SetText(strings.Join(lines, "\n"))
+}
+
+// 以分数显示有理数，不是整数时附上小数：能精确表示为小数时用 =，否则用 ≈
+func formatRat(r *big.Rat) string {
+	if r.IsInt() {
+		return r.RatString()
+	}
+	dec := engine.FormatExact(r, 12)
+	if d, ok := new(big.Rat).SetString(dec); ok && d.Cmp(r) == 0 {
+		return r.RatString() + " = " + dec
+	}
+	return r.RatString() + " ≈ " + dec
+}
+
+// 方程组页：选择元数后输入增广矩阵
+func (s *solver) systemView() fyne.CanvasObject {
+	saved := fyne.CurrentApp().Preferences().StringList(prefSolverSystem)
+	s.cells = make([][]*widget.Entry, solve.MaxUnknowns)
+	for i := range s.cells {
+		s.cells[i] = make([]*widget.Entry, solve.MaxUnknowns+1)
+		for j := range s.cells[i] {
+			s.cells[i][j] = widget.NewEntry()
+			s.cells[i][j].SetPlaceHolder("0")
+		}
+	}
+	s.grid = container.NewGridWithColumns(1)
+	s.systemInfo = newSolverInfo()
+
+	s.size = widget.NewSelect([]string{"二元", "三元", "四元"}, func(string) { s.rebuildGrid() })
+	// 保存的矩阵有 n×(n+1) 个元素
+	n := 2
+	for k := 2; k <= solve.MaxUnknowns; k++ {
+		if len(saved) == k*(k+1) {
+			n = k
+			for i := range k {
+				for j := range k + 1 {
+					s.cells[i][j].SetText(saved[i*(k+1)+j])
+				}
+			}
+		}
+	}
+	s.size.SetSelectedIndex(n - 2)
+
+	top := container.NewVBox(
+		container.NewBorder(nil, nil, widget.NewLabel("元数"), nil, s.size),
+		s.grid,
+		widget.NewButton("求解", s.solveSystem),
+	)
+	return container.NewBorder(top, nil, nil, nil, container.NewVScroll(s.systemInfo))
+}
+
+// 当前选择的元数
+func (s *solver) systemSize() int {
+	return s.size.SelectedIndex() + 2
+}
+
+// 按元数重建矩阵输入：第一行是各列的名称 x1、x2… 和 =
+func (s *solver) rebuildGrid() {
+	n := s.systemSize()
+	grid := container.NewGridWithColumns(n + 1)
+	for j := range n {
+		grid.Add(widget.NewLabelWithStyle("x"+strconv.Itoa(j+1), fyne.TextAlignCenter, fyne.TextStyle{}))
+	}
+	grid.Add(widget.NewLabelWithStyle("=", fyne.TextAlignCenter, fyne.TextStyle{}))
+	for i := range n {
+		for j := range n + 1 {
+			grid.Add(s.cells[i][j])
+		}
+	}
+	s.grid.Layout = grid.Layout
+	s.grid.Objects = grid.Objects
+	s.systemInfo.SetText("")
+	s.relayout()
+}
+
+// 用分数精确地求解方程组
+func (s *solver) solveSystem() {
+	n := s.systemSize()
+	a := make([][]*big.Rat, n)
+	b := make([]*big.Rat, n)
+	var texts []string
+	for i := range n {
+		a[i] = make([]*big.Rat, n)
+		for j := range n + 1 {
+			e := s.cells[i][j]
+			texts = append(texts, e.Text)
+			r, err := s.rational(e)
+			if err != nil {
+				s.systemInfo.SetText(fmt.Sprintf("第 %d 行第 %d 列：%v", i+1, j+1, err))
+				return
+			}
+			if j < n {
+				a[i][j] = r
+			} else {
+				b[i] = r
+			}
+		}
+	}
+	fyne.CurrentApp().Preferences().SetStringList(prefSolverSystem, texts)
+
+	x, err := solve.Linear(a, b)
+	if err != nil {
+		s.systemInfo.SetText(err.Error())
+		return
+	}
+	lines := make([]string, len(x))
+	for i, v := range x {
+		lines[i] = "x" + strconv.Itoa(i+1) + " = " + formatRat(v)
+	}
+	s.systemInfo.SetText(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/gzjjjfree/MemoryCalculator/calc/history"
+	"github.com/gzjjjfree/MemoryCalculator/calc/split"
+)
+
+// 键盘输入的人数最多几位
+const maxPeopleDigits = 3
+
+// 平摊界面：在结果上按 % 打开，键盘输入人数，可以设置取整方式、小费/税/服务费、每人的名字和份数
+type splitter struct {
+	state  *CalcState
+	result string // 打开时结果行的内容，关闭时恢复
+
+	people       string              // 用键盘输入的人数
+	participants []split.Participant // 已经填写的名字和份数，人数减少时多出的人保留，人数再增加时恢复
+	last         *split.Result       // 最近一次算出的结果，输入有误时为 nil
+	saved        string              // 最近一次存入历史的明细，没有变化时不再重复保存
+
+	amountEntry  *widget.Entry
+	peopleLabel  *widget.Label
+	roundSelect  *widget.Select
+	tipEntry     *widget.Entry
+	taxEntry     *widget.Entry
+	serviceEntry *widget.Entry
+	rows         *fyne.Container // 每人一行：名字和份数
+	breakdown    *widget.Label
+	copyBtn      *widget.Button
+	saveBtn      *widget.Button
+}
+
+// 显示平摊界面，平摊当前的结果
+func (s *CalcState) displaySplit() {
+	result, _ := s.result.Get()
+	amount, err := strconv.ParseFloat(strings.TrimPrefix(result, "= "), 64)
+	if err != nil || amount <= 0 {
+		return
+	}
+	// 整数（如分数）默认按整数平摊，与原来的平摊分数一致
+	rounding := split.RoundCent
+	if amount == float64(int64(amount)) {
+		rounding = split.RoundWhole
+	}
+	newSplitter(s, result, split.Bill{Amount: amount, Rounding: rounding}).show()
+}
+
+// 重新打开历史中的平摊记录，可以调整后另存一条；平摊、换算、统计或程序员界面打开时不响应
+func (s *CalcState) reopenSplit(rec history.Record) {
+	if rec.Split == nil || s.isTakenOver() {
+		return
+	}
+	result, _ := s.result.Get()
+	p := newSplitter(s, result, splitBill(rec.Split))
+	if p.last != nil {
+		p.saved = p.last.Breakdown() // 不做修改时关闭界面不会重复保存
+	}
+	p.show()
+}
+
+// 平摊结果转为历史记录中的明细
+func splitRecord(r split.Result) *history.Split {
+	h := &history.Split{
+		Amount:   r.Bill.Amount,
+		Tip:      r.Bill.Tip,
+		Tax:      r.Bill.Tax,
+		Service:  r.Bill.Service,
+		Rounding: int(r.Bill.Rounding),
+		Total:    r.Total,
+		Shares:   make([]history.SplitShare, len(r.Shares)),
+	}
+	for i, sh := range r.Shares {
+		h.Shares[i] = history.SplitShare{Name: sh.Name, Weight: sh.Weight, Amount: sh.Amount}
+	}
+	return h
+}
+
+// 历史记录中的明细还原为账单，无法识别的取整方式按精确到分处理
+func splitBill(h *history.Split) split.Bill {
+	b := split.Bill{Amount: h.Amount, Tip: h.Tip, Tax: h.Tax, Service: h.Service}
+	if r := split.Rounding(h.Rounding); slices.Contains(split.Roundings, r) {
+		b.Rounding = r
+	}
+	for _, sh := range h.Shares {
+		b.Participants = append(b.Participants, split.Participant{Name: sh.Name, Weight: sh.Weight})
+	}
+	return b
+}
+
+// 创建平摊界面，bill 中已有的人员和设置会填入界面
+func newSplitter(s *CalcState, result string, bill split.Bill) *splitter {
+	p := &splitter{state: s, result: result, participants: slices.Clone(bill.Participants)}
+	if n := len(bill.Participants); n > 0 {
+		p.people = strconv.Itoa(n)
+	}
+
+	p.amountEntry = widget.NewEntry()
+	p.amountEntry.SetText(strconv.FormatFloat(bill.Amount, 'f', -1, 64))
+	p.peopleLabel = widget.NewLabel("")
+	p.peopleLabel.TextStyle = fyne.TextStyle{Bold: true}
+
+	options := make([]string, len(split.Roundings))
+	for i, r := range split.Roundings {
+		options[i] = r.String()
+	}
+	p.roundSelect = widget.NewSelect(options, func(string) { p.recompute() })
+
+	percentEntry := func(placeholder string, v float64) *widget.Entry {
+		e := widget.NewEntry()
+		e.SetPlaceHolder(placeholder)
+		if v != 0 {
+			e.SetText(strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		return e
+	}
+	p.tipEntry = percentEntry("小费 %", bill.Tip)
+	p.taxEntry = percentEntry("税 %", bill.Tax)
+	p.serviceEntry = percentEntry("服务费 %", bill.Service)
+
+	p.rows = container.NewVBox()
+	p.breakdown = widget.NewLabel("")
+	p.breakdown.Wrapping = fyne.TextWrapWord
+
+	p.copyBtn = widget.NewButtonWithIcon("复制", theme.ContentCopyIcon(), func() {
+		if p.last != nil {
+			fyne.CurrentApp().Clipboard().SetContent(p.last.Breakdown())
+		}
+	})
+	p.saveBtn = widget.NewButtonWithIcon("存入历史", theme.DocumentSaveIcon(), p.save)
+
+	p.roundSelect.SetSelected(bill.Rounding.String())
+	for _, e := range []*widget.Entry{p.amountEntry, p.tipEntry, p.taxEntry, p.serviceEntry} {
+		e.OnChanged = func(string) { p.recompute() }
+	}
+	p.rebuildRows()
+	return p
+}
+
+// 解析输入框中的数值，空白时为 fallback
+func parseEntry(e *widget.Entry, fallback float64) (float64, bool) {
+	text := strings.TrimSpace(e.Text)
+	if text == "" {
+		return fallback, true
+	}
+	v, err := strconv.ParseFloat(text, 64)
+	return v, err == nil
+}
+
+// 根据界面上的输入生成账单
+func (p *splitter) bill() (split.Bill, string) {
+	var b split.Bill
+	var ok bool
+	if b.Amount, ok = parseEntry(p.amountEntry, 0); !ok {
+		return b, "金额无效"
+	}
+	if b.Tip, ok = parseEntry(p.tipEntry, 0); !ok {
+		return b, "小费无效"
+	}
+	if b.Tax, ok = parseEntry(p.taxEntry, 0); !ok {
+		return b, "税无效"
+	}
+	if b.Service, ok = parseEntry(p.serviceEntry, 0); !ok {
+		return b, "服务费无效"
+	}
+	for _, r := range split.Roundings {
+		if r.String() == p.roundSelect.Selected {
+			b.Rounding = r
+		}
+	}
+	b.Participants = p.participants[:p.count()]
+	return b, ""
+}
+
+// 当前的人数
+func (p *splitter) count() int {
+	n, _ := strconv.Atoi(p.people)
+	return n
+}
+
+// 人数变化后重建每人一行的名字和份数，保留已经填写的内容
+func (p *splitter) rebuildRows() {
+	n := p.count()
+	for len(p.participants) < n {
+		p.participants = append(p.participants, split.Participant{Weight: 1})
+	}
+
+	p.rows.Objects = nil
+	for i := range p.participants[:n] {
+		name := widget.NewEntry()
+		name.SetPlaceHolder("第 " + strconv.Itoa(i+1) + " 人")
+		name.SetText(p.participants[i].Name)
+		name.OnChanged = func(text string) {
+			p.participants[i].Name = text
+			p.recompute()
+		}
+		weight := widget.NewEntry()
+		weight.SetPlaceHolder("份数")
+		weight.SetText(strconv.FormatFloat(p.participants[i].Weight, 'f', -1, 64))
+		weight.OnChanged = func(text string) {
+			// 空白按 1 份计算，无法解析时按 -1 处理，由 Split 报告份数无效
+			v, ok := parseEntry(weight, 1)
+			if !ok {
+				v = -1
+			}
+			p.participants[i].Weight = v
+			p.recompute()
+		}
+		p.rows.Add(container.NewGridWithColumns(2, name, weight))
+	}
+	p.rows.Refresh()
+
+	if p.people == "" {
+		p.peopleLabel.SetText("用键盘输入人数")
+	} else {
+		p.peopleLabel.SetText(p.people + " 人")
+	}
+	p.recompute()
+}
+
+// 重新计算，明细显示在平摊界面，摘要显示在结果行
+func (p *splitter) recompute() {
+	if p.breakdown == nil || p.saveBtn == nil {
+		return // 还在创建界面
+	}
+	b, msg := p.bill()
+	var r split.Result
+	if msg == "" {
+		var err error
+		if r, err = b.Split(); err != nil {
+			msg = err.Error()
+		}
+	}
+	if msg != "" {
+		p.last = nil
+		p.breakdown.SetText(msg)
+		p.state.result.Set(msg)
+		p.copyBtn.Disable()
+		p.saveBtn.Disable()
+	} else {
+		p.last = &r
+		p.breakdown.SetText(r.Breakdown())
+		p.state.result.Set(r.Summary())
+		p.copyBtn.Enable()
+		if r.Breakdown() == p.saved {
+			p.saveBtn.Disable()
+		} else {
+			p.saveBtn.Enable()
+		}
+	}
+	p.state.isResultMode.Set(false)
+}
+
+// 把平摊结果存入历史，记录中带有完整的明细，可以从历史中重新打开
+func (p *splitter) save() {
+	if p.last == nil || p.last.Breakdown() == p.saved {
+		return // 同一结果只保存一次
+	}
+	r := p.last
+	expression := "平摊 " + r.Bill.Rounding.Format(r.Total) + "（" + strconv.Itoa(len(r.Shares)) + " 人）"
+	rec := history.NewRecord(time.Now(), expression, r.ShareText(), "", history.TagSplit)
+	rec.Split = splitRecord(*r)
+	p.state.appendRecord(rec)
+	text, _ := updateFontSizeBasedOnWidth(rec.String(), nil) // 更新字体大小和换行状态
+	p.state.addSessionEntry(rec, text)
+	p.saved = r.Breakdown()
+	p.saveBtn.Disable()
+}
+
+// 处理键盘输入，作为 CalcState.onScoreInput 使用：数字输入人数，⌫ 删除，C 关闭
+func (p *splitter) onInput(char string) {
+	switch {
+	case len(char) == 1 && char >= "0" && char <= "9":
+		if len(p.people) < maxPeopleDigits && (p.people != "" || char != "0") {
+			p.people += char
+			p.rebuildRows()
+		}
+	case char == "⌫":
+		if p.people != "" {
+			p.people = p.people[:len(p.people)-1]
+			p.rebuildRows()
+		}
+	case char == "C":
+		p.close()
+	}
+}
+
+// 重置人数和附加费用，金额和取整方式保持不变
+func (p *splitter) reset() {
+	p.people = ""
+	p.participants = nil
+	for _, e := range []*widget.Entry{p.tipEntry, p.taxEntry, p.serviceEntry} {
+		e.SetText("")
+	}
+	p.rebuildRows()
+}
+
+// 关闭平摊界面，恢复原来的结果；算好但还没保存的平摊自动存入历史
+func (p *splitter) close() {
+	p.save()
+	s := p.state
+	s.isInterceptingForScore = false
+	s.onScoreInput = nil
+	if s.scoreOverlay != nil {
+		s.scoreOverlay.Hide()
+	}
+	s.result.Set(p.result)
+	s.isResultMode.Set(true)
+}
+
+// 显示平摊界面并接管键盘输入，界面盖住上方的显示区，下方的键盘仍然可以输入人数
+func (p *splitter) show() {
+	s := p.state
+	s.isInterceptingForScore = true
+	s.onScoreInput = p.onInput
+	p.recompute()
+	if s.scoreOverlay == nil {
+		return
+	}
+
+	title := widget.NewLabel("平摊")
+	title.Alignment = fyne.TextAlignCenter
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	form := container.New(layout.NewFormLayout(),
+		widget.NewLabel("金额"), p.amountEntry,
+		widget.NewLabel("人数"), p.peopleLabel,
+		widget.NewLabel("取整"), p.roundSelect,
+	)
+	buttons := container.NewGridWithColumns(4,
+		widget.NewButton("返回", p.close),
+		widget.NewButton("重置", p.reset),
+		p.copyBtn,
+		p.saveBtn,
+	)
+	body := container.NewVBox(
+		form,
+		container.NewGridWithColumns(3, p.tipEntry, p.taxEntry, p.serviceEntry),
+		p.rows,
+		p.breakdown,
+	)
+
+	cardBackground := canvas.NewRectangle(theme.BackgroundColor())
+	card := container.NewStack(cardBackground,
+		container.NewBorder(title, buttons, nil, nil, container.NewVScroll(body)))
+
+	// 与主界面相同的比例：上方放平摊界面，下方留给键盘
+	s.scoreOverlay.Objects = []fyne.CanvasObject{
+		container.New(&ratioLayout{ratio: 0.47}, card, layout.NewSpacer()),
+	}
+	s.scoreOverlay.Refresh()
+	s.scoreOverlay.Show()
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/gzjjjfree/MemoryCalculator/calc/engine"
+	"github.com/gzjjjfree/MemoryCalculator/calc/stats"
+)
+
+// 保存统计数据时使用的 Preferences 键名
+const (
+	prefStatsX      = "stats.x"      // []float64，数据（成对数据的 x）
+	prefStatsY      = "stats.y"      // []float64，成对数据的 y，与 x 一一对应
+	prefStatsPaired = "stats.paired" // bool，是否为成对数据
+)
+
+// 统计结果的显示格式：保留 12 位有效数字，消除求和带来的二进制误差
+var statsFormat = engine.Options{Precision: 12}
+
+// 统计界面：键盘输入数值（可以是算式），按 = 加入列表；点击列表中的一项可以修改或删除
+// 成对数据时先输入 x，按 = 后输入 y，再按 = 加入一组
+type statsPanel struct {
+	state   *CalcState
+	xs, ys  []float64 // 数据，单变量时 ys 不使用
+	paired  bool      // 是否为成对数据
+	inputs  [2]string // 正在输入的 x 和 y
+	active  int       // 当前输入的一侧：0 为 x，1 为 y
+	editing int       // 正在修改的数据下标，-1 表示新增
+
+	pairedCheck *widget.Check
+	inputBtn    [2]*widget.Button
+	list        *widget.List
+	countLabel  *widget.Label
+	deleteBtn   *widget.Button
+	clearBtn    *widget.Button
+	resultLabel *widget.Label
+	msgLabel    *widget.Label
+}
+
+// 创建统计界面，恢复上次的数据
+func newStatsPanel(state *CalcState) *statsPanel {
+	p := &statsPanel{state: state, editing: -1}
+	if app := fyne.CurrentApp(); app != nil {
+		prefs := app.Preferences()
+		p.xs = prefs.FloatList(prefStatsX)
+		p.ys = prefs.FloatList(prefStatsY)
+		p.paired = prefs.BoolWithFallback(prefStatsPaired, false)
+	}
+	if len(p.ys) != len(p.xs) {
+		p.ys = make([]float64, len(p.xs)) // 列表不完整时 y 按 0 处理
+	}
+
+	p.pairedCheck = widget.NewCheck("成对数据 (x, y)", func(on bool) {
+		if on == p.paired {
+			return
+		}
+		p.paired = on
+		p.active = 0
+		p.save()
+		p.list.Refresh()
+		p.refresh()
+	})
+	p.pairedCheck.SetChecked(p.paired)
+
+	for i := range 2 {
+		p.inputBtn[i] = widget.NewButton("", func() {
+			p.active = i
+			p.refresh()
+		})
+		p.inputBtn[i].Alignment = widget.ButtonAlignTrailing
+	}
+
+	p.list = widget.NewList(
+		func() int { return len(p.xs) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.SizeName = SmallFont
+			return label
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(p.itemText(id))
+		},
+	)
+	p.list.OnSelected = p.edit
+	p.list.OnUnselected = func(id widget.ListItemID) {
+		if id == p.editing {
+			p.cancelEdit()
+		}
+	}
+
+	p.countLabel = widget.NewLabel("")
+	p.countLabel.SizeName = SmallFont
+	p.deleteBtn = widget.NewButtonWithIcon("", theme.DeleteIcon(), p.deleteEditing)
+	p.deleteBtn.Importance = widget.LowImportance
+	p.clearBtn = widget.NewButtonWithIcon("", theme.ContentClearIcon(), p.confirmClear)
+	p.clearBtn.Importance = widget.LowImportance
+
+	p.resultLabel = widget.NewLabel("")
+	p.resultLabel.SizeName = SmallFont
+	p.resultLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	p.msgLabel = widget.NewLabel("")
+	p.msgLabel.Alignment = fyne.TextAlignTrailing
+	p.msgLabel.Importance = widget.DangerImportance
+	p.msgLabel.SizeName = SmallFont
+
+	p.refresh()
+	return p
+}
+
+// 保存全部数据
+func (p *statsPanel) save() {
+	if app := fyne.CurrentApp(); app != nil {
+		prefs := app.Preferences()
+		prefs.SetFloatList(prefStatsX, p.xs)
+		prefs.SetFloatList(prefStatsY, p.ys)
+		prefs.SetBool(prefStatsPaired, p.paired)
+	}
+}
+
+// 列表中一项的文字，如 "3.  12.5" 或 "3.  (1, 2.5)"
+func (p *statsPanel) itemText(i int) string {
+	text := strconv.Itoa(i+1) + ".  "
+	if p.paired {
+		return text + "(" + statsFormat.Format(p.xs[i]) + ", " + statsFormat.Format(p.ys[i]) + ")"
+	}
+	return text + statsFormat.Format(p.xs[i])
+}
+
+// 处理键盘输入，作为 CalcState.onStatsInput 使用
+// = 确认当前的数值：成对数据先输入 x 再输入 y；C 清除输入，输入为空时取消修改
+func (p *statsPanel) onInput(char string) {
+	current := p.inputs[p.active]
+	switch char {
+	case "C":
+		if current == "" && p.editing >= 0 {
+			p.list.UnselectAll()
+			return
+		}
+		current = ""
+	case "⌫":
+		if runes := []rune(current); len(runes) > 0 {
+			current = string(runes[:len(runes)-1])
+		}
+	case "=":
+		p.commit()
+		return
+	default:
+		current += char
+	}
+	p.inputs[p.active] = current
+	p.msgLabel.SetText("")
+	p.refresh()
+}
+
+// 计算输入的算式，结果必须是实数
+func (p *statsPanel) evaluate(input string) (float64, string) {
+	if strings.TrimSpace(input) == "" {
+		return 0, "请输入数值"
+	}
+	res, err := engine.Evaluate(checkLastOperator(input), p.state.engineOptions())
+	if err != nil {
+		return 0, err.Error()
+	}
+	if !res.IsReal() || math.IsNaN(res.Value) || math.IsInf(res.Value, 0) {
+		return 0, "数据必须是有限的实数"
+	}
+	return res.Value, ""
+}
+
+// 确认当前一侧的输入：x 确认后转到 y，全部确认后加入列表或替换正在修改的一项
+func (p *statsPanel) commit() {
+	x, msg := p.evaluate(p.inputs[0])
+	if msg == "" && p.paired && p.active == 0 {
+		p.inputs[0] = statsFormat.Format(x)
+		p.active = 1
+		p.refresh()
+		return
+	}
+	var y float64
+	if msg == "" && p.paired {
+		y, msg = p.evaluate(p.inputs[1])
+	}
+	if msg != "" {
+		p.msgLabel.SetText(msg)
+		return
+	}
+
+	if p.editing >= 0 {
+		p.xs[p.editing], p.ys[p.editing] = x, y
+	} else {
+		p.xs = append(p.xs, x)
+		p.ys = append(p.ys, y)
+	}
+	p.save()
+	p.list.UnselectAll()
+	p.inputs = [2]string{}
+	p.active = 0
+	p.editing = -1
+	p.msgLabel.SetText("")
+	p.list.Refresh()
+	p.list.ScrollToBottom()
+	p.refresh()
+}
+
+// 选中列表中的一项：把它的值放入输入区，按 = 后替换
+func (p *statsPanel) edit(id widget.ListItemID) {
+	p.editing = id
+	p.inputs = [2]string{statsFormat.Format(p.xs[id]), statsFormat.Format(p.ys[id])}
+	p.active = 0
+	p.msgLabel.SetText("")
+	p.refresh()
+}
+
+// 取消修改，清空输入区
+func (p *statsPanel) cancelEdit() {
+	p.editing = -1
+	p.inputs = [2]string{}
+	p.active = 0
+	p.refresh()
+}
+
+// 删除正在修改的一项
+func (p *statsPanel) deleteEditing() {
+	i := p.editing
+	if i < 0 || i >= len(p.xs) {
+		return
+	}
+	p.xs = append(p.xs[:i], p.xs[i+1:]...)
+	p.ys = append(p.ys[:i], p.ys[i+1:]...)
+	p.save()
+	p.editing = -1 // 先清除，取消选中时不再回调 cancelEdit
+	p.list.UnselectAll()
+	p.cancelEdit()
+	p.list.Refresh()
+}
+
+// 确认后清空全部数据
+func (p *statsPanel) confirmClear() {
+	if len(p.xs) == 0 {
+		return
+	}
+	dialog.ShowConfirm("确认", "确定清空全部数据吗？", func(ok bool) {
+		if ok {
+			p.clear()
+		}
+	}, p.state.win)
+}
+
+// 清空全部数据
+func (p *statsPanel) clear() {
+	p.xs, p.ys = nil, nil
+	p.save()
+	p.editing = -1
+	p.list.UnselectAll()
+	p.cancelEdit()
+	p.list.Refresh()
+}
+
+// 刷新输入区和统计结果
+func (p *statsPanel) refresh() {
+	names := [2]string{"x", "y"}
+	if !p.paired {
+		names[0] = "数值"
+	}
+	for i := range 2 {
+		text := p.inputs[i]
+		if text == "" {
+			text = "0"
+		}
+		p.inputBtn[i].SetText(names[i] + " = " + text)
+		if i == p.active {
+			p.inputBtn[i].Importance = widget.HighImportance
+		} else {
+			p.inputBtn[i].Importance = widget.LowImportance
+		}
+		p.inputBtn[i].Refresh()
+	}
+	if p.paired {
+		p.inputBtn[1].Show()
+	} else {
+		p.inputBtn[1].Hide()
+	}
+
+	if p.editing >= 0 {
+		p.countLabel.SetText("修改第 " + strconv.Itoa(p.editing+1) + " 项")
+		p.deleteBtn.Enable()
+	} else {
+		p.countLabel.SetText(strconv.Itoa(len(p.xs)) + " 项")
+		p.deleteBtn.Disable()
+	}
+	if len(p.xs) > 0 {
+		p.clearBtn.Enable()
+	} else {
+		p.clearBtn.Disable()
+	}
+	p.resultLabel.SetText(p.report())
+}
+
+// 统计结果的文字：x 的描述统计量，成对数据时再加上 y 的平均数和样本标准差、回归直线和相关系数
+func (p *statsPanel) report() string {
+	sum, err := stats.Describe(p.xs)
+	if err != nil {
+		return err.Error()
+	}
+	f := func(v float64) string {
+		if math.IsNaN(v) {
+			return "—"
+		}
+		return statsFormat.Format(v)
+	}
+	mode := "—"
+	if len(sum.Modes) > 0 {
+		parts := make([]string, len(sum.Modes))
+		for i, m := range sum.Modes {
+			parts[i] = f(m)
+		}
+		mode = strings.Join(parts, ", ")
+	}
+
+	lines := []string{
+		"n = " + strconv.Itoa(sum.Count),
+		"Σ = " + f(sum.Sum),
+		"平均数 = " + f(sum.Mean),
+		"中位数 = " + f(sum.Median),
+		"众数 = " + mode,
+		"最小 = " + f(sum.Min) + "  最大 = " + f(sum.Max),
+		"极差 = " + f(sum.Range),
+		"Q1 = " + f(sum.Q1) + "  Q3 = " + f(sum.Q3),
+		"σ² = " + f(sum.PopVariance) + "  σ = " + f(sum.PopStdDev),
+		"s² = " + f(sum.SampleVariance) + "  s = " + f(sum.SampleStdDev),
+	}
+	if p.paired {
+		if y, err := stats.Describe(p.ys); err == nil {
+			lines = append(lines, "y 平均数 = "+f(y.Mean)+"  s = "+f(y.SampleStdDev))
+		}
+		if r, err := stats.Fit(p.xs, p.ys); err == nil {
+			sign := " + "
+			if r.Slope < 0 {
+				sign = " - "
+			}
+			lines = append(lines,
+				"y = "+f(r.Intercept)+sign+f(math.Abs(r.Slope))+"x",
+				"r = "+f(r.R)+"  r² = "+f(r.R2),
+			)
+		} else {
+			lines = append(lines, "回归："+err.Error())
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// 统计界面的布局：上方是输入区，下方左侧是数据列表，右侧是统计结果
+func (p *statsPanel) view() fyne.CanvasObject {
+	copyBtn := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+		fyne.CurrentApp().Clipboard().SetContent(p.resultLabel.Text)
+	})
+	copyBtn.Importance = widget.LowImportance
+
+	top := container.NewVBox(
+		container.NewBorder(nil, nil, p.pairedCheck, copyBtn),
+		p.inputBtn[0],
+		p.inputBtn[1],
+		p.msgLabel,
+	)
+	listHeader := container.NewBorder(nil, nil, p.countLabel, container.NewHBox(p.deleteBtn, p.clearBtn))
+	return container.NewBorder(top, nil, nil, nil,
+		container.NewGridWithColumns(2,
+			container.NewBorder(listHeader, nil, nil, nil, p.list),
+			container.NewVScroll(p.resultLabel),
+		),
+	)
+}
+
+// 切换到统计界面时接管键盘，离开时交还
+func (p *statsPanel) setActive(on bool) {
+	p.state.isStatistics = on
+	if on {
+		p.state.onStatsInput = p.onInput
+	} else {
+		p.state.onStatsInput = nil
+	}
+}
@@ -2,114 +2,52 @@ package main
 
 import (
 	"image/color"
-	"strings"
+	"slices"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/data/binding"
-	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+
+	"github.com/gzjjjfree/MemoryCalculator/calc/engine"
 )
 
 // --- UI 构建 ---
 func CreateUI(state *CalcState) fyne.CanvasObject {
 	// --- 顶部 Tab 居中布局 ---
 	calcLabel := widget.NewButton("计算", func() {})
-	calcLabel.Importance = widget.LowImportance
+	calcLabel.Importance = widget.MediumImportance // 默认显示计算界面
 
 	convertLabel := widget.NewButton("换算", func() {})
 	convertLabel.Importance = widget.LowImportance
 
-	// --- 定义全屏历史查看函数 ---
-	showFullHistory := func() {
-		historyWin := fyne.CurrentApp().NewWindow("全部历史记录")
-		historyWin.Resize(fyne.NewSize(360, 640))
-
-		// 将 Builder 内容转为切片，过滤掉可能的空行
-		rawLines := strings.Split(state.allHistoryBuilder.String(), "\n")
-		var data []string
-		for _, line := range rawLines {
-			trimmed := strings.TrimSpace(line) // 去除行首尾空白
-			if trimmed == "" {
-				continue
-			}
-			data = append(data, trimmed)
-		}
-
-		// 创建 List 组件
-		list := widget.NewList(
-			// 返回数据总量
-			func() int {
-				return len(data)
-			},
-			// 创建单元格外观（模板）
-			func() fyne.CanvasObject {
-				label := widget.NewLabel("")
-				label.Alignment = fyne.TextAlignTrailing
-				//label.Wrapping = fyne.TextWrapBreak               // 允许在长等式处自动换行
-				//label.TextStyle = fyne.TextStyle{Monospace: true} // 等宽字体更整齐
-				labelFontSize = 18
-				label.SizeName = LabelFont
-
-				return container.NewPadded(label)
-			},
-			// 绑定数据到单元格（滚动时会被频繁调用）
-			func(id widget.ListItemID, item fyne.CanvasObject) {
-				text := data[id]
-				label := item.(*fyne.Container).Objects[0].(*widget.Label)
-
-				// 如果是日期标题，可以做特殊样式处理
-				if strings.HasPrefix(text, "---") {
-					label.Alignment = fyne.TextAlignCenter
-					label.TextStyle = fyne.TextStyle{Bold: true}
-				} else {
-					label.Alignment = fyne.TextAlignTrailing
-					label.TextStyle = fyne.TextStyle{}
-				}
-
-				label.SetText(text)
-			},
-		)
-
-		// 清除逻辑
-		clearBtn := widget.NewButtonWithIcon("清除全部", theme.DeleteIcon(), func() {
-			dialog.ShowConfirm("确认", "确定删除吗？", func(ok bool) {
-				if ok {
-					state.ClearAllHistoryLocal()
-					data = []string{} // 清空本地索引
-					list.Refresh()    // 刷新列表
-				}
-			}, historyWin)
-		})
+	statsLabel := widget.NewButton("统计", func() {})
+	statsLabel.Importance = widget.LowImportance
 
-		// 布局
-		historyWin.SetContent(container.NewBorder(nil, clearBtn, nil, nil, list))
-		historyWin.Show()
-
-		// 自动滚动到底部（在列表渲染完成后执行）
-		if len(data) > 0 {
-			list.ScrollToBottom()
-		}
-	}
-
-	// 定义历史显示, 使用 RichText 获得更好的排版支持
-	richHistory := newAllHistoryClickable()
-	richHistory.Wrapping = fyne.TextWrapBreak
+	// 定义历史显示：每条记录一个可点击的 RichText，点击后可以重用算式或结果
+	sessionBox := container.New(layout.NewCustomPaddedVBoxLayout(0))
 
 	// 修改默认主题，去除上下边框阴影
 	colorNameShadow := color.Transparent // color.Transparent
 	customTheme := &myTheme{Theme: theme.DefaultTheme(), colorNameShadow: colorNameShadow}
-	container.NewThemeOverride(richHistory, customTheme)
+	sessionHistory := container.NewThemeOverride(sessionBox, customTheme)
 
 	// 定义输入框
-	richInput := widget.NewRichText()
-	// 设置对齐方式（通过段落样式）
-	richInput.ExtendBaseWidget(richInput)
+	richInput := newCaretInput()
 	richInput.Wrapping = fyne.TextWrapWord // 改为按单词换行
+	richInput.onTapped = state.MoveCaretTo // 点击算式定位光标
+	// 在输入框上向左滑动撤销，向右滑动重做
+	richInput.onSwiped = func(dx float32) {
+		if dx < 0 {
+			state.Undo()
+		} else {
+			state.Redo()
+		}
+	}
 
 	// 定义结果显示
 	lblResult := widget.NewLabelWithData(state.result)
@@ -118,7 +56,7 @@ func CreateUI(state *CalcState) fyne.CanvasObject {
 	// 即时历史显示框, 冒泡显示
 	historyContainer := container.NewBorder(
 		nil,                // Top
-		richHistory,        // Bottom (强制文字靠底)
+		sessionHistory,     // Bottom (强制文字靠底)
 		nil,                // Left
 		nil,                // Right
 		layout.NewSpacer(), // Center (占据剩余所有空间)
@@ -137,7 +75,7 @@ func CreateUI(state *CalcState) fyne.CanvasObject {
 
 		// 根据当前输入框宽度和文本内容动态调整字体大小
 		actualW = richInput.Size().Width - theme.Padding()*4 // 留出一些内边距空间
-		changeText, isFinal := updateFontSizeBasedOnWidth(text, richInput)
+		changeText, isFinal := updateFontSizeBasedOnWidth(text, &richInput.RichText)
 
 		// 根据是否是结果模式调整字体大小和样式
 		if isBold {
@@ -163,18 +101,18 @@ func CreateUI(state *CalcState) fyne.CanvasObject {
 		}
 		stateMutex.Unlock()
 
-		// 更新 RichText 内容
-		richInput.Segments = []widget.RichTextSegment{
-			&widget.TextSegment{
-				Text: changeText,
-				Style: widget.RichTextStyle{
-					TextStyle: fyne.TextStyle{Bold: !isBold}, // 非结果模式时加粗
-					// 使用 Fyne 定义的 SizeName
-					SizeName: RichInputFont,
-					// 设置向右对齐
-					Alignment: fyne.TextAlignTrailing,
-				},
-			},
+		// 更新 RichText 内容，出错的部分标红；光标移到中间时显示光标
+		errSpan, _ := state.errorSpan.Get()
+		caret := -1
+		if tail := state.caretTail(); tail > 0 && !isBold {
+			caret = caretIndex(changeText, tail)
+		}
+		richInput.text = changeText
+		richInput.Segments = inputSegments(changeText, errSpan, !isBold, caret)
+		if errSpan != (engine.Span{}) {
+			lblResult.Importance = widget.DangerImportance
+		} else {
+			lblResult.Importance = widget.MediumImportance
 		}
 
 		richInput.Refresh()
@@ -183,6 +121,8 @@ func CreateUI(state *CalcState) fyne.CanvasObject {
 
 	// --- 创建不同的按键布局 ---
 	calcGrid := createCalculatorGrid(state) // 开始时的计算器 4x5 布局
+	programmer := newProgrammer(state)      // 程序员界面，显示区和键盘一起切换
+	programmerGrid := programmer.keypad()   // 程序员键盘 5x7 布局
 	var calcBigGrid fyne.CanvasObject
 
 	// --- 定义动态按键容器 (Stack) --- Stack 容器会自动填满可用空间，并显示最上层的对象
@@ -191,21 +131,29 @@ func CreateUI(state *CalcState) fyne.CanvasObject {
 	go func() { // 后台启动监听
 		// 每次更新历史时，自动滚动到底部
 		state.history.AddListener(binding.NewDataListener(func() {
-			hText, _ := state.history.Get()
-
-			richHistory.Segments = []widget.RichTextSegment{
-				&widget.TextSegment{
-					Text: hText,
-					Style: widget.RichTextStyle{
-						// 设置为禁用色（灰色）
-						ColorName: theme.ColorNameDisabled,
-						SizeName:  SmallFont,
-						Alignment: fyne.TextAlignTrailing,
+			entries := state.sessionEntries()
+			sessionBox.Objects = make([]fyne.CanvasObject, len(entries))
+			for i, entry := range entries {
+				item := newAllHistoryClickable()
+				item.Wrapping = fyne.TextWrapBreak
+				item.Segments = []widget.RichTextSegment{
+					&widget.TextSegment{
+						Text: entry.text,
+						Style: widget.RichTextStyle{
+							// 设置为禁用色（灰色）
+							ColorName: theme.ColorNameDisabled,
+							SizeName:  SmallFont,
+							Alignment: fyne.TextAlignTrailing,
+						},
 					},
-				},
+				}
+				item.onTapped = func(ev *fyne.PointEvent) {
+					showRecallMenu(state, entry.record, state.win.Canvas(), ev.AbsolutePosition, nil)
+				}
+				sessionBox.Objects[i] = item
 			}
 
-			richHistory.Refresh()
+			sessionBox.Refresh()
 			time.AfterFunc(time.Millisecond*50, func() {
 				fyne.Do(func() { scrollSession.ScrollToBottom() })
 			})
@@ -217,46 +165,158 @@ func CreateUI(state *CalcState) fyne.CanvasObject {
 			}
 		}))
 
+		// --- 监听光标位置变化 ---
+		state.caret.AddListener(binding.NewDataListener(func() {
+			refreshRichInput()
+		}))
+
+		// --- 监听错误位置变化，标出算式中出错的部分 ---
+		state.errorSpan.AddListener(binding.NewDataListener(func() {
+			refreshRichInput()
+		}))
+
 		// --- 监听模式变化 (OnEqual 动作会触发这里) ---
 		state.isResultMode.AddListener(binding.NewDataListener(func() {
 			refreshRichInput() // 按下等号时也要刷新一次颜色和粗细
 		}))
 
 		calcBigGrid = createConverterGrid(state) // 新的布局 5x7 布局
-		state.isCalcBig.AddListener(binding.NewDataListener(func() {
-			if ok, _ := state.isCalcBig.Get(); ok {
+		updateKeypad := func() {
+			isBig, _ := state.isCalcBig.Get()
+			isProgrammer, _ := state.isProgrammer.Get()
+			switch {
+			case isProgrammer:
+				keypadContainer.Objects = []fyne.CanvasObject{programmerGrid}
+			case isBig:
 				keypadContainer.Objects = []fyne.CanvasObject{calcBigGrid}
-				keypadContainer.Refresh()
-			} else {
+			default:
 				keypadContainer.Objects = []fyne.CanvasObject{calcGrid}
-				keypadContainer.Refresh()
 			}
-		}))
+			keypadContainer.Refresh()
+		}
+		state.isCalcBig.AddListener(binding.NewDataListener(updateKeypad))
+		state.isProgrammer.AddListener(binding.NewDataListener(updateKeypad))
 	}()
 
 	// 设置显示全部历史
-	historyIcon := widget.NewButtonWithIcon("", theme.HistoryIcon(), showFullHistory)
+	historyIcon := widget.NewButtonWithIcon("", theme.HistoryIcon(), func() { showFullHistory(state) })
 	historyIcon.Importance = widget.LowImportance
 
+	// 复制当前结果或算式、从剪贴板粘贴算式
+	copyIcon := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), state.OnCopy)
+	copyIcon.Importance = widget.LowImportance
+	pasteIcon := widget.NewButtonWithIcon("", theme.ContentPasteIcon(), state.OnPaste)
+	pasteIcon.Importance = widget.LowImportance
+
+	// 更多菜单：变量面板、函数面板、绘图、解方程和设置
+	var moreIcon *widget.Button
+	moreIcon = widget.NewButtonWithIcon("", theme.MoreVerticalIcon(), func() {
+		menu := fyne.NewMenu("",
+			fyne.NewMenuItem("变量", func() { showVariables(state) }),
+			fyne.NewMenuItem("函数", func() { showFunctions(state) }),
+			fyne.NewMenuItem("绘图", func() { showPlot(state) }),
+			fyne.NewMenuItem("解方程", func() { showSolver(state) }),
+			fyne.NewMenuItem("设置", func() { showSettings(state) }),
+		)
+		widget.ShowPopUpMenuAtRelativePosition(menu, state.win.Canvas(), fyne.NewPos(0, moreIcon.Size().Height), moreIcon)
+	})
+	moreIcon.Importance = widget.LowImportance
+
+	// 撤销和重做，放在结果行左侧的空白处
+	undoBtn := widget.NewButtonWithIcon("", theme.ContentUndoIcon(), state.Undo)
+	undoBtn.Importance = widget.LowImportance
+	redoBtn := widget.NewButtonWithIcon("", theme.ContentRedoIcon(), state.Redo)
+	redoBtn.Importance = widget.LowImportance
+
 	// 下方输入区容器
 	inputArea := container.NewVBox(
 		richInput,
-		lblResult,
+		container.NewBorder(nil, nil, container.NewHBox(undoBtn, redoBtn), nil, lblResult),
 	)
 
-	// 最终的 topBar：两侧是 Spacer，中间是 Tabs，最右边是历史按钮
-	topBar := container.NewBorder(nil, nil, nil, historyIcon,
-		container.NewHBox(layout.NewSpacer(), calcLabel, convertLabel, layout.NewSpacer()),
+	// 内存指示：显示当前寄存器的值以及其他非空寄存器
+	memoryLabel := widget.NewLabelWithData(state.memory.indicator)
+	memoryLabel.SizeName = SmallFont
+	memoryLabel.Importance = widget.LowImportance
+
+	// 最终的 topBar：左侧是内存指示，中间是 Tabs，最右边是复制、粘贴、更多菜单和历史按钮
+	topBar := container.NewBorder(nil, nil, memoryLabel, container.NewHBox(copyIcon, pasteIcon, moreIcon, historyIcon),
+		container.NewHBox(layout.NewSpacer(), calcLabel, convertLabel, statsLabel, layout.NewSpacer()),
 	)
 
-	displayArea := container.NewBorder(
-		topBar,        // Top
+	// 计算界面：上方历史，下方输入和结果
+	calcDisplay := container.NewBorder(
+		nil,           // Top
 		inputArea,     // Bottom
 		nil,           // Left
 		nil,           // Right
 		scrollSession, // Center (自动填充)
 	)
 
+	// 换算和统计界面，与计算界面共用下方的键盘
+	converter := newUnitConverter(state)
+	convertDisplay := converter.view()
+	statistics := newStatsPanel(state)
+	statsDisplay := statistics.view()
+	programmerDisplay := programmer.view()
+	displayBody := container.NewStack(calcDisplay)
+
+	// 按当前 Tab 和键盘布局切换显示区：换算、统计界面优先，程序员键盘属于计算 Tab
+	tab := calcLabel
+	refreshBody := func() {
+		isProgrammer, _ := state.isProgrammer.Get()
+		converter.setActive(tab == convertLabel)
+		statistics.setActive(tab == statsLabel)
+		programmer.setActive(isProgrammer && tab == calcLabel)
+		switch {
+		case tab == convertLabel:
+			displayBody.Objects = []fyne.CanvasObject{convertDisplay}
+		case tab == statsLabel:
+			displayBody.Objects = []fyne.CanvasObject{statsDisplay}
+		case isProgrammer:
+			displayBody.Objects = []fyne.CanvasObject{programmerDisplay}
+		default:
+			displayBody.Objects = []fyne.CanvasObject{calcDisplay}
+		}
+		for _, label := range []*widget.Button{calcLabel, convertLabel, statsLabel} {
+			if label == tab {
+				label.Importance = widget.MediumImportance
+			} else {
+				label.Importance = widget.LowImportance
+			}
+			label.Refresh()
+		}
+		displayBody.Refresh()
+	}
+
+	// 切换顶部 Tab：当前 Tab 的文字突出显示；换算和统计使用十进制键盘，离开程序员键盘
+	switchTab := func(label *widget.Button) {
+		tab = label
+		if tab != calcLabel {
+			state.isProgrammer.Set(false)
+		}
+		refreshBody()
+	}
+	calcLabel.OnTapped = func() { switchTab(calcLabel) }
+	convertLabel.OnTapped = func() { switchTab(convertLabel) }
+	statsLabel.OnTapped = func() { switchTab(statsLabel) }
+
+	// 切换到程序员键盘时回到计算 Tab
+	state.isProgrammer.AddListener(binding.NewDataListener(func() {
+		if isProgrammer, _ := state.isProgrammer.Get(); isProgrammer {
+			tab = calcLabel
+		}
+		refreshBody()
+	}))
+
+	displayArea := container.NewBorder(
+		topBar,      // Top
+		nil,         // Bottom
+		nil,         // Left
+		nil,         // Right
+		displayBody, // Center (自动填充)
+	)
+
 	content := container.New(&ratioLayout{ratio: 0.47}, displayArea, keypadContainer)
 
 	// 创建一个透明的矩形作为底部的“垫片”，高度设置为 20
@@ -267,6 +327,57 @@ func CreateUI(state *CalcState) fyne.CanvasObject {
 	return container.NewBorder(nil, bottomSpacer, nil, nil, content)
 }
 
+// 把输入内容拆分为若干文本段，span 标出的出错部分使用错误色
+func inputSegments(text string, span engine.Span, bold bool, caret int) []widget.RichTextSegment {
+	style := widget.RichTextStyle{
+		TextStyle: fyne.TextStyle{Bold: bold}, // 非结果模式时加粗
+		// 使用 Fyne 定义的 SizeName
+		SizeName: RichInputFont,
+		// 设置向右对齐
+		Alignment: fyne.TextAlignTrailing,
+	}
+
+	runes := []rune(text)
+	start, end := min(span.Start, len(runes)), min(span.End, len(runes))
+	if start >= end && caret < 0 {
+		return []widget.RichTextSegment{&widget.TextSegment{Text: text, Style: style}}
+	}
+
+	// 同一行内的多个文本段需要设置 Inline，只有最后一段结束本段落
+	inline := style
+	inline.Inline = true
+	errStyle := inline
+	errStyle.ColorName = theme.ColorNameError
+	caretStyle := inline
+	caretStyle.ColorName = theme.ColorNameHyperlink
+	caretStyle.TextStyle = fyne.TextStyle{}
+
+	// 按出错区间和光标把算式切成若干段
+	cuts := []int{0, start, end, len(runes)}
+	if caret >= 0 {
+		cuts = append(cuts, min(caret, len(runes)))
+	}
+	slices.Sort(cuts)
+
+	var segments []widget.RichTextSegment
+	for i := 0; i+1 < len(cuts); i++ {
+		if caret == cuts[i] && (i == 0 || cuts[i-1] != caret) {
+			segments = append(segments, &widget.TextSegment{Text: "|", Style: caretStyle})
+		}
+		a, b := cuts[i], cuts[i+1]
+		if a == b {
+			continue
+		}
+		seg := inline
+		if a >= start && b <= end && start < end {
+			seg = errStyle
+		}
+		segments = append(segments, &widget.TextSegment{Text: string(runes[a:b]), Style: seg})
+	}
+	segments = append(segments, &widget.TextSegment{Text: "", Style: style}) // 结束本段落
+	return segments
+}
+
 // style: 0-普通，1-强调，2-警告，3-成功
 // 根据文本内容自动设置字体颜色和背景颜色，并创建按钮
 func makeBtn(text string, icon fyne.Resource, style int, action func()) fyne.CanvasObject {
@@ -312,15 +423,35 @@ func createConverterGrid(state *CalcState) fyne.CanvasObject {
 	var degBtn *widget.Button
 
 	colorBackground := color.NRGBA{R: 220, G: 235, B: 255, A: 255} // 淡蓝色背景
-	customTheme := &myTheme{Theme: theme.DefaultTheme(), textSize: 30, colorBackground: colorBackground}
+	// 两个按钮上下叠放，字号小一些
+	customTheme := &myTheme{Theme: theme.DefaultTheme(), textSize: 18, colorBackground: colorBackground}
 
 	// 使用一个特殊的构造逻辑或直接创建，以便拿到指针
 	// 我们直接写一个闭包来生成这个特定按钮
 	degBtn = widget.NewButton("DEG", state.OnDegToRad)
 	degBtn.Importance = widget.HighImportance
 	container.NewThemeOverride(degBtn, customTheme)
+	// 复数模式按钮与 DEG/RAD 上下叠放，循环切换 ℝ（实数）、a+bi（直角坐标）、r∠θ（极坐标）
+	complexBtn := widget.NewButton("ℝ", state.OnComplexMode)
+	complexBtn.Importance = widget.HighImportance
+	container.NewThemeOverride(complexBtn, customTheme)
 	// 将其包装进你想要的容器格式
-	degBtnObj := container.NewStack(degBtn)
+	degBtnObj := container.NewGridWithRows(2, degBtn, complexBtn)
+
+	updateComplexBtn := func() {
+		isComplex, _ := state.isComplex.Get()
+		isPolar, _ := state.isPolar.Get()
+		switch {
+		case !isComplex:
+			complexBtn.SetText("ℝ")
+		case isPolar:
+			complexBtn.SetText("r∠θ")
+		default:
+			complexBtn.SetText("a+bi")
+		}
+	}
+	state.isComplex.AddListener(binding.NewDataListener(updateComplexBtn))
+	state.isPolar.AddListener(binding.NewDataListener(updateComplexBtn))
 
 	// 为 IsRadian 增加监听器，实现 UI 自动同步
 	state.isRadian.AddListener(binding.NewDataListener(func() {
@@ -362,6 +493,10 @@ func createConverterGrid(state *CalcState) fyne.CanvasObject {
 			"lg":  {"lg", "10ˣ"},
 			"ln":  {"ln", "eˣ"},
 			"√x":  {"√x", "x²"},
+			"e":   {"e", "ans"},
+			"π":   {"π", "f(x)"},
+			"x!":  {"x!", "i"},
+			")":   {")", ","},
 		}
 
 		for id, btn := range toggleButtons {
@@ -375,6 +510,26 @@ func createConverterGrid(state *CalcState) fyne.CanvasObject {
 		}
 	}))
 
+	// π 键在 2nd 模式下弹出自定义函数列表
+	piBtn := makeToggleBtn("π", 1)
+	toggleButtons["π"].OnTapped = func() {
+		if is2nd, _ := state.is2ndMode.Get(); is2nd {
+			showFunctionPicker(state, piBtn)
+			return
+		}
+		state.OnAdvancedTap("π")
+	}
+
+	// ) 键在 2nd 模式下输入函数参数之间的逗号
+	closeBtn := makeToggleBtn(")", 1)
+	toggleButtons[")"].OnTapped = func() {
+		if is2nd, _ := state.is2ndMode.Get(); is2nd {
+			state.OnTap(",")
+			return
+		}
+		state.OnTap(")")
+	}
+
 	grid := container.NewGridWithColumns(5,
 		makeBtn("2nd", nil, 1, state.OnToggle2nd),
 		degBtnObj,
@@ -386,7 +541,7 @@ func createConverterGrid(state *CalcState) fyne.CanvasObject {
 		makeToggleBtn("lg", 1),
 		makeToggleBtn("ln", 1),
 		makeBtn("(", nil, 1, func() { state.OnTap("(") }),
-		makeBtn(")", nil, 1, func() { state.OnTap(")") }),
+		closeBtn, // 2nd 模式下输入逗号
 
 		makeToggleBtn("√x", 1),
 		makeBtn("C", nil, 2, state.OnClear),
@@ -394,7 +549,7 @@ func createConverterGrid(state *CalcState) fyne.CanvasObject {
 		makeBtn("%", nil, 0, func() { state.OnTap("%") }),
 		makeBtn("÷", nil, 1, func() { state.OnTap("÷") }),
 
-		makeBtn("x!", nil, 1, func() { state.OnAdvancedTap("x!") }),
+		makeToggleBtn("x!", 1), // 2nd 模式下输入虚数单位 i
 		makeBtn("7", nil, 0, func() { state.OnTap("7") }),
 		makeBtn("8", nil, 0, func() { state.OnTap("8") }),
 		makeBtn("9", nil, 0, func() { state.OnTap("9") }),
@@ -406,20 +561,21 @@ func createConverterGrid(state *CalcState) fyne.CanvasObject {
 		makeBtn("6", nil, 0, func() { state.OnTap("6") }),
 		makeBtn("-", nil, 1, func() { state.OnTap("-") }),
 
-		makeBtn("π", nil, 1, func() { state.OnAdvancedTap("π") }),
+		piBtn, // 2nd 模式下选择自定义函数
 		makeBtn("1", nil, 0, func() { state.OnTap("1") }),
 		makeBtn("2", nil, 0, func() { state.OnTap("2") }),
 		makeBtn("3", nil, 0, func() { state.OnTap("3") }),
 		makeBtn("+", nil, 1, func() { state.OnTap("+") }),
 
 		makeBtn("", theme.GridIcon(), 0, state.OnGoBigGrid),
-		makeBtn("e", nil, 1, func() { state.OnAdvancedTap("e") }),
+		makeToggleBtn("e", 1), // 2nd 模式下输入 ans
 		makeBtn("0", nil, 0, func() { state.OnTap("0") }),
 		makeBtn(".", nil, 0, func() { state.OnTap(".") }),
 		makeBtn("=", nil, 2, state.OnEqual),
 	)
 
-	return grid
+	// 顶部加一行内存键
+	return container.NewBorder(createMemoryRow(state), nil, nil, nil, grid)
 }
 
 // 创建一个新的按键布局，包含基本的计算功能（4x5 布局）
@@ -451,36 +607,26 @@ func createCalculatorGrid(state *CalcState) fyne.CanvasObject {
 		makeBtn("=", nil, 2, state.OnEqual),
 	)
 
-	return grid
+	// 顶部加一行内存键
+	return container.NewBorder(createMemoryRow(state), nil, nil, nil, grid)
 }
 
-// 定义一个新的 RichText 组件，重写 Tapped 方法实现点击切换颜色的功能
+// 定义一个新的 RichText 组件，点击或长按（右键）时调用 onTapped，用于历史记录条目
 type allHistoryClickable struct {
 	widget.RichText
+	onTapped func(ev *fyne.PointEvent)
 }
 
 // 处理点击事件
-func (t *allHistoryClickable) Tapped(_ *fyne.PointEvent) {
-	if len(t.Segments) == 0 {
-		return
-	}
-
-	// 先根据第一个片段判断当前状态（是灰还是亮）
-	firstSeg := t.Segments[0].(*widget.TextSegment)
-	targetColor := theme.ColorNameForeground
-	if firstSeg.Style.ColorName == theme.ColorNameForeground {
-		targetColor = theme.ColorNameDisabled
-	}
-
-	// 遍历所有片段，统一修改颜色
-	for _, seg := range t.Segments {
-		if txtSeg, ok := seg.(*widget.TextSegment); ok {
-			txtSeg.Style.ColorName = targetColor
-		}
+func (t *allHistoryClickable) Tapped(ev *fyne.PointEvent) {
+	if t.onTapped != nil {
+		t.onTapped(ev)
 	}
+}
 
-	// 刷新 UI
-	t.Refresh()
+// 长按（移动端）和右键（桌面端）与点击相同
+func (t *allHistoryClickable) TappedSecondary(ev *fyne.PointEvent) {
+	t.Tapped(ev)
 }
 
 // 创建一个新的 allHistoryClickable 实例，并进行必要的初始化
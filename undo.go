@@ -0,0 +1,90 @@
+package main
+
+import (
+	"github.com/gzjjjfree/MemoryCalculator/calc/engine"
+)
+
+// 撤销栈最多保存的步数，超出时丢弃最早的
+const undoLimit = 100
+
+// 输入区的一次状态：算式、结果行、光标以及输入模式
+type editSnapshot struct {
+	display      string
+	result       string
+	caret        int
+	errorSpan    engine.Span
+	isNewNumber  bool
+	isResultMode bool
+}
+
+// 撤销和重做栈，栈顶在切片末尾
+type undoStack struct {
+	undo  []editSnapshot
+	redo  []editSnapshot
+	depth int // 正在进行的编辑层数，嵌套调用时只记录最外层
+}
+
+// 当前的输入区状态
+func (s *CalcState) snapshot() editSnapshot {
+	snap := editSnapshot{isNewNumber: s.isNewNumber}
+	snap.display, _ = s.display.Get()
+	snap.result, _ = s.result.Get()
+	snap.caret, _ = s.caret.Get()
+	snap.errorSpan, _ = s.errorSpan.Get()
+	snap.isResultMode, _ = s.isResultMode.Get()
+	return snap
+}
+
+// 恢复输入区状态
+func (s *CalcState) restore(snap editSnapshot) {
+	s.isNewNumber = snap.isNewNumber
+	isChangeRow = false
+	s.caret.Set(snap.caret)
+	s.errorSpan.Set(snap.errorSpan)
+	s.result.Set(snap.result)
+	s.isResultMode.Set(snap.isResultMode)
+	s.display.Set(snap.display)
+}
+
+// 开始一次编辑：在修改前调用，返回的函数在修改后调用（通常写作 defer s.beginEdit()()）
+// 状态有变化时把修改前的状态压入撤销栈，并清空重做栈
+func (s *CalcState) beginEdit() func() {
+	u := &s.undo
+	u.depth++
+	before := s.snapshot()
+	return func() {
+		u.depth--
+		if u.depth > 0 || s.snapshot() == before {
+			return
+		}
+		u.undo = append(u.undo, before)
+		if len(u.undo) > undoLimit {
+			u.undo = u.undo[len(u.undo)-undoLimit:]
+		}
+		u.redo = nil
+	}
+}
+
+// 撤销上一次编辑，包括按 C 清除的算式
+func (s *CalcState) Undo() {
+	if s.isTakenOver() || len(s.undo.undo) == 0 {
+		return
+	}
+	u := &s.undo
+	snap := u.undo[len(u.undo)-1]
+	u.undo = u.undo[:len(u.undo)-1]
+	u.redo = append(u.redo, s.snapshot())
+	s.restore(snap)
+}
+
+// 重做刚才撤销的编辑
+func (s *CalcState) Redo() {
+	if s.isTakenOver() || len(s.undo.redo) == 0 {
+		return
+	}
+	u := &s.undo
+	snap := u.redo[len(u.redo)-1]
+	u.redo = u.redo[:len(u.redo)-1]
+	u.undo = append(u.undo, s.snapshot())
+	s.restore(snap)
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"io"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/gzjjjfree/MemoryCalculator/calc/engine"
+	"github.com/gzjjjfree/MemoryCalculator/calc/units"
+)
+
+// 用户自定义单位文件名，放在 App 沙盒根目录下，格式与内置的 units.json 相同
+const userUnitsFileName = "units.json"
+
+// 保存换算界面选择时使用的 Preferences 键名
+const (
+	prefConvertCategory = "convert.category"
+	prefConvertFrom     = "convert.from"
+	prefConvertTo       = "convert.to"
+)
+
+// 单位换算界面：上下两行各有一个单位和一个数值，点击数值行选中输入的一侧，键盘输入实时换算到另一侧
+type unitConverter struct {
+	state    *CalcState
+	catalog  *units.Catalog
+	category *units.Category
+	unitIDs  [2]string // 上下两行选中的单位 ID
+	inputs   [2]string // 上下两行的内容，输入侧可以是算式
+	active   int       // 当前输入的一侧：0 为上方，1 为下方
+
+	catSelect  *widget.Select
+	unitSelect [2]*widget.Select
+	valueBtn   [2]*widget.Button
+}
+
+// 加载单位定义：内置定义 + 沙盒中的用户自定义文件
+func loadUnitCatalog() *units.Catalog {
+	catalog := units.Default()
+
+	rootURI := fyne.CurrentApp().Storage().RootURI()
+	if rootURI == nil {
+		return catalog
+	}
+	fileURI, err := storage.Child(rootURI, userUnitsFileName)
+	if err != nil {
+		return catalog
+	}
+	reader, err := storage.Reader(fileURI)
+	if err != nil {
+		return catalog // 没有自定义文件是正常的
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return catalog
+	}
+	extra, err := units.Parse(data)
+	if err == nil {
+		catalog.Merge(extra)
+		err = catalog.Validate()
+	}
+	if err != nil {
+		fyne.LogError("invalid "+userUnitsFileName, err)
+		return units.Default() // 合并后的定义无效时只用内置定义
+	}
+	return catalog
+}
+
+// 创建单位换算界面
+func newUnitConverter(state *CalcState) *unitConverter {
+	c := &unitConverter{state: state, catalog: loadUnitCatalog()}
+
+	names := make([]string, len(c.catalog.Categories))
+	for i, cat := range c.catalog.Categories {
+		names[i] = cat.Name
+	}
+	c.catSelect = widget.NewSelect(names, func(name string) {
+		for i := range c.catalog.Categories {
+			if c.catalog.Categories[i].Name == name {
+				c.setCategory(&c.catalog.Categories[i], "", "")
+				return
+			}
+		}
+	})
+
+	for i := range 2 {
+		c.unitSelect[i] = widget.NewSelect(nil, func(label string) {
+			if u := c.unitByLabel(label); u != nil && c.unitIDs[i] != u.ID {
+				c.unitIDs[i] = u.ID
+				c.save()
+				c.recompute()
+			}
+		})
+		c.valueBtn[i] = widget.NewButton("", func() {
+			c.active = i
+			c.recompute()
+		})
+		c.valueBtn[i].Alignment = widget.ButtonAlignTrailing
+	}
+
+	// 恢复上次的选择
+	prefs := fyne.CurrentApp().Preferences()
+	cat := c.catalog.Category(prefs.String(prefConvertCategory))
+	if cat == nil && len(c.catalog.Categories) > 0 {
+		cat = &c.catalog.Categories[0]
+	}
+	if cat != nil {
+		c.setCategory(cat, prefs.String(prefConvertFrom), prefs.String(prefConvertTo))
+	}
+	return c
+}
+
+// 切换类别，from/to 为空或不存在时默认选前两个单位
+func (c *unitConverter) setCategory(cat *units.Category, from, to string) {
+	if c.category == cat {
+		return
+	}
+	c.category = cat
+	if cat.Unit(from) == nil {
+		from = cat.Units[0].ID
+	}
+	if cat.Unit(to) == nil {
+		to = cat.Units[min(1, len(cat.Units)-1)].ID
+	}
+	c.unitIDs = [2]string{from, to}
+
+	labels := make([]string, len(cat.Units))
+	for i, u := range cat.Units {
+		labels[i] = u.Label()
+	}
+	c.catSelect.SetSelected(cat.Name)
+	for i := range 2 {
+		c.unitSelect[i].SetOptions(labels)
+		c.unitSelect[i].SetSelected(cat.Unit(c.unitIDs[i]).Label())
+	}
+	c.save()
+	c.recompute()
+}
+
+func (c *unitConverter) unitByLabel(label string) *units.Unit {
+	for i := range c.category.Units {
+		if c.category.Units[i].Label() == label {
+			return &c.category.Units[i]
+		}
+	}
+	return nil
+}
+
+// 保存当前选择
+func (c *unitConverter) save() {
+	prefs := fyne.CurrentApp().Preferences()
+	prefs.SetString(prefConvertCategory, c.category.ID)
+	prefs.SetString(prefConvertFrom, c.unitIDs[0])
+	prefs.SetString(prefConvertTo, c.unitIDs[1])
+}
+
+// 处理键盘输入，作为 CalcState.onConvertInput 使用
+func (c *unitConverter) onInput(char string) {
+	current := c.inputs[c.active]
+	switch char {
+	case "C":
+		current = ""
+	case "⌫":
+		if runes := []rune(current); len(runes) > 0 {
+			current = string(runes[:len(runes)-1])
+		}
+	case "=":
+		// 把输入侧的算式替换为计算结果
+		if res, err := engine.Evaluate(checkLastOperator(current), c.state.engineOptions()); err == nil {
+			current = res.Text
+		}
+	default:
+		current += char
+	}
+	c.inputs[c.active] = current
+	c.recompute()
+}
+
+// 根据输入侧重新计算另一侧，并刷新界面
+func (c *unitConverter) recompute() {
+	other := 1 - c.active
+	from, to := c.category.Unit(c.unitIDs[c.active]), c.category.Unit(c.unitIDs[other])
+
+	input := c.inputs[c.active]
+	switch res, err := engine.Evaluate(checkLastOperator(input), c.state.engineOptions()); {
+	case input == "":
+		c.inputs[other] = ""
+	case err == nil && from != nil && to != nil:
+		// 保留 12 位有效数字，消除换算系数带来的二进制误差
+		c.inputs[other] = engine.Options{Precision: 12}.Format(units.Convert(res.Value, *from, *to))
+	}
+
+	for i := range 2 {
+		text := c.inputs[i]
+		if text == "" {
+			text = "0"
+		}
+		c.valueBtn[i].SetText(text)
+		if i == c.active {
+			c.valueBtn[i].Importance = widget.HighImportance
+		} else {
+			c.valueBtn[i].Importance = widget.LowImportance
+		}
+		c.valueBtn[i].Refresh()
+	}
+}
+
+// 交换上下两行的单位，数值随之交换
+func (c *unitConverter) swap() {
+	c.unitIDs[0], c.unitIDs[1] = c.unitIDs[1], c.unitIDs[0]
+	c.inputs[0], c.inputs[1] = c.inputs[1], c.inputs[0]
+	c.active = 1 - c.active
+	for i := range 2 {
+		c.unitSelect[i].SetSelected(c.category.Unit(c.unitIDs[i]).Label())
+	}
+	c.save()
+	c.recompute()
+}
+
+// 换算界面的布局
+func (c *unitConverter) view() fyne.CanvasObject {
+	swapBtn := widget.NewButtonWithIcon("", theme.MoveDownIcon(), c.swap)
+	swapBtn.Importance = widget.LowImportance
+
+	row := func(i int) fyne.CanvasObject {
+		return container.NewGridWithColumns(2, c.unitSelect[i], c.valueBtn[i])
+	}
+
+	return container.NewVBox(
+		c.catSelect,
+		row(0),
+		container.NewCenter(swapBtn),
+		row(1),
+	)
+}
+
+// 切换到换算界面时接管键盘，返回计算界面时交还
+func (c *unitConverter) setActive(on bool) {
+	c.state.isConverting = on
+	if on {
+		c.state.onConvertInput = c.onInput
+	} else {
+		c.state.onConvertInput = nil
+	}
+}
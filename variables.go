@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/gzjjjfree/MemoryCalculator/calc/engine"
+)
+
+// 保存变量时使用的 Preferences 键名，两个列表按下标一一对应
+const (
+	prefVariableNames  = "variables.names"  // []string，变量名
+	prefVariableValues = "variables.values" // []float64，变量的值
+)
+
+// 从 Preferences 恢复上次保存的变量
+func (s *CalcState) loadVariables() {
+	s.variables = map[string]float64{}
+	if app := fyne.CurrentApp(); app != nil {
+		prefs := app.Preferences()
+		names := prefs.StringList(prefVariableNames)
+		values := prefs.FloatList(prefVariableValues)
+		for i := 0; i < len(names) && i < len(values); i++ {
+			s.variables[names[i]] = values[i]
+		}
+	}
+}
+
+// 保存全部变量
+func (s *CalcState) saveVariables() {
+	names := s.variableNames()
+	values := make([]float64, len(names))
+	for i, name := range names {
+		values[i] = s.variables[name]
+	}
+	if app := fyne.CurrentApp(); app != nil {
+		prefs := app.Preferences()
+		prefs.SetStringList(prefVariableNames, names)
+		prefs.SetFloatList(prefVariableValues, values)
+	}
+}
+
+// 按名称排序的变量名
+func (s *CalcState) variableNames() []string {
+	names := make([]string, 0, len(s.variables))
+	for name := range s.variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 定义或修改一个变量
+func (s *CalcState) SetVariable(name string, value float64) {
+	if _, ok := s.variables[name]; !ok {
+		s.evalCache.Reset() // 新的名称会影响连写名称的拆分
+	}
+	s.variables[name] = value
+	s.saveVariables()
+}
+
+// 删除一个变量
+func (s *CalcState) DeleteVariable(name string) {
+	delete(s.variables, name)
+	s.evalCache.Reset()
+	s.saveVariables()
+}
+
+// 执行一条赋值语句 "x = 3.5" 或函数定义 "f(x) = x^2"，返回计算结果
+func (s *CalcState) Assign(statement string) (engine.Result, error) {
+	res, a, err := s.evaluate(statement)
+	if err != nil {
+		return engine.Result{}, err
+	}
+	if a == nil {
+		return engine.Result{}, fmt.Errorf("不是赋值语句")
+	}
+	if err := s.apply(a, res); err != nil {
+		return engine.Result{}, err
+	}
+	return res, nil
+}
+
+// 保存赋值语句的结果：变量记下值，函数定义编译后加入函数表
+func (s *CalcState) apply(a *engine.Assignment, res engine.Result) error {
+	if !a.IsFunction() {
+		s.SetVariable(a.Name, res.Value)
+		return nil
+	}
+	fn, err := engine.Define(a, s.engineOptions())
+	if err != nil {
+		return err
+	}
+	s.SetFunction(a.Name, fn)
+	return nil
+}
+
+// ans、ans1、ans2… 指向历史结果，返回对应的序号（ans 即 ans1）
+func answerIndex(name string) (int, bool) {
+	if name == "ans" {
+		return 1, true
+	}
+	rest, ok := strings.CutPrefix(name, "ans")
+	if !ok {
+		return 0, false
+	}
+	if n, _ := strconv.Atoi(rest); n > 0 && strconv.Itoa(n) == rest {
+		return n, true
+	}
+	return 0, false
+}
+
+// 算式只有一个可以赋值的名称（如 "x" 或 "f(x)"），按 = 时应当开始写赋值语句而不是求值
+func (s *CalcState) isAssignable(expr string) bool {
+	if strings.Contains(expr, "=") {
+		return false
+	}
+	a, err := engine.ParseAssignment(expr+"=0", s.engineOptions())
+	if err != nil || a == nil {
+		return false
+	}
+	// 已经定义的函数，f(2) 按 = 是调用而不是重新定义
+	if _, defined := s.userFunctions[a.Name]; defined && a.IsFunction() {
+		return false
+	}
+	_, isAnswer := answerIndex(a.Name)
+	return !isAnswer
+}
+
+// 显示变量面板：列出全部变量，点击插入算式，可以新建、修改和删除
+func showVariables(state *CalcState) {
+	p := &definitionPanel{
+		title: "变量",
+		hint:  "还没有变量，可以输入 x=3.5 后按 = 定义",
+		names: state.variableNames,
+		label: func(name string) string {
+			return name + " = " + engine.Options{}.Format(state.variables[name])
+		},
+		insert: func(name string) string { return name },
+		remove: state.DeleteVariable,
+	}
+	// 新建或修改变量，name 为空时新建
+	p.edit = func(name string) {
+		nameEntry := widget.NewEntry()
+		nameEntry.SetPlaceHolder("如 x、rate")
+		valueEntry := widget.NewEntry()
+		valueEntry.SetPlaceHolder("数值或算式")
+		if name != "" {
+			nameEntry.SetText(name)
+			nameEntry.Disable()
+			valueEntry.SetText(engine.Options{}.Format(state.variables[name]))
+		}
+		items := []*widget.FormItem{
+			widget.NewFormItem("名称", nameEntry),
+			widget.NewFormItem("值", valueEntry),
+		}
+		dialog.ShowForm("变量", "确定", "取消", items, func(ok bool) {
+			if !ok {
+				return
+			}
+			value, err := sanitizePaste(valueEntry.Text)
+			if err == nil {
+				_, err = state.Assign(strings.TrimSpace(nameEntry.Text) + "=" + value)
+			}
+			if err != nil {
+				dialog.ShowError(err, p.win)
+				return
+			}
+			p.reload()
+		}, p.win)
+	}
+	p.show(state)
+}
+
+// 变量面板和函数面板共用的列表窗口
+type definitionPanel struct {
+	title  string                   // 窗口标题，也用于提示文字，如 "变量"
+	hint   string                   // 列表为空时的提示
+	names  func() []string          // 全部名称，按显示顺序排列
+	label  func(name string) string // 列表中一行的文字
+	insert func(name string) string // 点击一行时插入算式的内容
+	edit   func(name string)        // 新建（name 为空）或修改
+	remove func(name string)        // 删除
+
+	win  fyne.Window
+	list *widget.List
+	rows []string
+	tip  *widget.Label
+}
+
+// 重新读取名称并刷新列表
+func (p *definitionPanel) reload() {
+	p.rows = p.names()
+	p.tip.Hidden = len(p.rows) > 0
+	p.tip.Refresh()
+	p.list.Refresh()
+}
+
+// 打开窗口
+func (p *definitionPanel) show(state *CalcState) {
+	p.win = fyne.CurrentApp().NewWindow(p.title)
+	p.win.Resize(fyne.NewSize(360, 480))
+
+	p.tip = widget.NewLabel(p.hint)
+	p.tip.Wrapping = fyne.TextWrapWord
+	p.tip.Importance = widget.LowImportance
+
+	p.list = widget.NewList(
+		func() int {
+			return len(p.rows)
+		},
+		func() fyne.CanvasObject {
+			label := widget.NewButton("", nil)
+			label.Alignment = widget.ButtonAlignLeading
+			label.Importance = widget.LowImportance
+			editBtn := widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), nil)
+			editBtn.Importance = widget.LowImportance
+			deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), nil)
+			deleteBtn.Importance = widget.LowImportance
+			return container.NewBorder(nil, nil, nil, container.NewHBox(editBtn, deleteBtn), label)
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			name := p.rows[id]
+			row := item.(*fyne.Container)
+			label := row.Objects[0].(*widget.Button)
+			buttons := row.Objects[1].(*fyne.Container).Objects
+			label.SetText(p.label(name))
+			// 点击一行，把名称插入算式
+			label.OnTapped = func() {
+				if !state.isTakenOver() {
+					state.insertValue(p.insert(name))
+				}
+				p.win.Close()
+			}
+			buttons[0].(*widget.Button).OnTapped = func() { p.edit(name) }
+			buttons[1].(*widget.Button).OnTapped = func() {
+				dialog.ShowConfirm("确认", fmt.Sprintf("确定删除%s %s 吗？", p.title, name), func(ok bool) {
+					if ok {
+						p.remove(name)
+						p.reload()
+					}
+				}, p.win)
+			}
+		},
+	)
+
+	addBtn := widget.NewButtonWithIcon("新建"+p.title, theme.ContentAddIcon(), func() { p.edit("") })
+	p.win.SetContent(container.NewBorder(
+		container.NewHBox(layout.NewSpacer(), addBtn),
+		nil, nil, nil,
+		container.NewStack(p.list, container.NewPadded(p.tip)),
+	))
+	p.reload()
+	p.win.Show()
+}